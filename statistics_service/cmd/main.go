@@ -1,21 +1,47 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log"
+	"log/slog"
+	"os"
 
 	"statistics/src/broker"
 	"statistics/src/database"
+	"statistics/src/migrate"
 	"statistics/src/server"
 )
 
 func main() {
 	port := flag.Int("port", 8082, "Port of statistics service server.")
+	autoMigrate := flag.Bool("auto-migrate", true, "Apply pending database migrations on start.")
 	flag.Parse()
 
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
 	db := database.New()
+	migrator, err := db.SchemaMigrator()
+	if err != nil {
+		log.Fatalf("Can not load migrations: %v", err)
+	}
+	if flag.Arg(0) == "migrate" {
+		if err := migrate.Run(context.Background(), migrator, flag.Args()[1:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if *autoMigrate {
+		if err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+	}
 
-	b, close := broker.New()
+	b, close, err := broker.FromEnv()
+	if err != nil {
+		panic(err)
+	}
 	defer close()
 	go b.Consume(db)
 
@@ -2,7 +2,10 @@ module statistics
 
 go 1.22.0
 
-require github.com/IBM/sarama v1.43.2
+require (
+	github.com/IBM/sarama v1.43.2
+	google.golang.org/protobuf v1.33.0
+)
 
 require (
 	github.com/jackc/pgpassfile v1.0.0 // indirect
@@ -13,7 +16,6 @@ require (
 	golang.org/x/sys v0.19.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
-	google.golang.org/protobuf v1.33.0 // indirect
 )
 
 require (
@@ -19,9 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion8
 
 const (
-	StatisticsService_GetTaskStats_FullMethodName = "/stat_grpc.StatisticsService/GetTaskStats"
-	StatisticsService_GetTopTasks_FullMethodName  = "/stat_grpc.StatisticsService/GetTopTasks"
-	StatisticsService_GetTopUsers_FullMethodName  = "/stat_grpc.StatisticsService/GetTopUsers"
+	StatisticsService_GetTaskStats_FullMethodName     = "/stat_grpc.StatisticsService/GetTaskStats"
+	StatisticsService_GetTopTasks_FullMethodName      = "/stat_grpc.StatisticsService/GetTopTasks"
+	StatisticsService_GetTopUsers_FullMethodName      = "/stat_grpc.StatisticsService/GetTopUsers"
+	StatisticsService_GetAllStats_FullMethodName      = "/stat_grpc.StatisticsService/GetAllStats"
+	StatisticsService_GetStatsForTasks_FullMethodName = "/stat_grpc.StatisticsService/GetStatsForTasks"
+	StatisticsService_MoveTaskStats_FullMethodName    = "/stat_grpc.StatisticsService/MoveTaskStats"
+	StatisticsService_GetUserDeletion_FullMethodName  = "/stat_grpc.StatisticsService/GetUserDeletion"
+	StatisticsService_GetUserActivity_FullMethodName  = "/stat_grpc.StatisticsService/GetUserActivity"
 )
 
 // StatisticsServiceClient is the client API for StatisticsService service.
@@ -31,6 +36,14 @@ type StatisticsServiceClient interface {
 	GetTaskStats(ctx context.Context, in *GetTaskStatsRequest, opts ...grpc.CallOption) (*GetTaskStatsResponse, error)
 	GetTopTasks(ctx context.Context, in *GetTopTasksRequest, opts ...grpc.CallOption) (*GetTopTasksResponse, error)
 	GetTopUsers(ctx context.Context, in *GetTopUsersRequest, opts ...grpc.CallOption) (*GetTopUsersResponse, error)
+	GetAllStats(ctx context.Context, in *GetAllStatsRequest, opts ...grpc.CallOption) (*GetAllStatsResponse, error)
+	// Likes and views of the given tasks only.
+	GetStatsForTasks(ctx context.Context, in *GetStatsForTasksRequest, opts ...grpc.CallOption) (*GetAllStatsResponse, error)
+	MoveTaskStats(ctx context.Context, in *MoveTaskStatsRequest, opts ...grpc.CallOption) (*MoveTaskStatsResponse, error)
+	// Reports whether a DeleteUser message from the Stat topic was handled.
+	GetUserDeletion(ctx context.Context, in *GetUserDeletionRequest, opts ...grpc.CallOption) (*GetUserDeletionResponse, error)
+	// Likes and views the user gave and got, for the personal data export.
+	GetUserActivity(ctx context.Context, in *GetUserActivityRequest, opts ...grpc.CallOption) (*GetUserActivityResponse, error)
 }
 
 type statisticsServiceClient struct {
@@ -71,6 +84,56 @@ func (c *statisticsServiceClient) GetTopUsers(ctx context.Context, in *GetTopUse
 	return out, nil
 }
 
+func (c *statisticsServiceClient) GetAllStats(ctx context.Context, in *GetAllStatsRequest, opts ...grpc.CallOption) (*GetAllStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAllStatsResponse)
+	err := c.cc.Invoke(ctx, StatisticsService_GetAllStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statisticsServiceClient) GetStatsForTasks(ctx context.Context, in *GetStatsForTasksRequest, opts ...grpc.CallOption) (*GetAllStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAllStatsResponse)
+	err := c.cc.Invoke(ctx, StatisticsService_GetStatsForTasks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statisticsServiceClient) MoveTaskStats(ctx context.Context, in *MoveTaskStatsRequest, opts ...grpc.CallOption) (*MoveTaskStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MoveTaskStatsResponse)
+	err := c.cc.Invoke(ctx, StatisticsService_MoveTaskStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statisticsServiceClient) GetUserDeletion(ctx context.Context, in *GetUserDeletionRequest, opts ...grpc.CallOption) (*GetUserDeletionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUserDeletionResponse)
+	err := c.cc.Invoke(ctx, StatisticsService_GetUserDeletion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statisticsServiceClient) GetUserActivity(ctx context.Context, in *GetUserActivityRequest, opts ...grpc.CallOption) (*GetUserActivityResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUserActivityResponse)
+	err := c.cc.Invoke(ctx, StatisticsService_GetUserActivity_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StatisticsServiceServer is the server API for StatisticsService service.
 // All implementations must embed UnimplementedStatisticsServiceServer
 // for forward compatibility
@@ -78,6 +141,14 @@ type StatisticsServiceServer interface {
 	GetTaskStats(context.Context, *GetTaskStatsRequest) (*GetTaskStatsResponse, error)
 	GetTopTasks(context.Context, *GetTopTasksRequest) (*GetTopTasksResponse, error)
 	GetTopUsers(context.Context, *GetTopUsersRequest) (*GetTopUsersResponse, error)
+	GetAllStats(context.Context, *GetAllStatsRequest) (*GetAllStatsResponse, error)
+	// Likes and views of the given tasks only.
+	GetStatsForTasks(context.Context, *GetStatsForTasksRequest) (*GetAllStatsResponse, error)
+	MoveTaskStats(context.Context, *MoveTaskStatsRequest) (*MoveTaskStatsResponse, error)
+	// Reports whether a DeleteUser message from the Stat topic was handled.
+	GetUserDeletion(context.Context, *GetUserDeletionRequest) (*GetUserDeletionResponse, error)
+	// Likes and views the user gave and got, for the personal data export.
+	GetUserActivity(context.Context, *GetUserActivityRequest) (*GetUserActivityResponse, error)
 	mustEmbedUnimplementedStatisticsServiceServer()
 }
 
@@ -94,6 +165,21 @@ func (UnimplementedStatisticsServiceServer) GetTopTasks(context.Context, *GetTop
 func (UnimplementedStatisticsServiceServer) GetTopUsers(context.Context, *GetTopUsersRequest) (*GetTopUsersResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetTopUsers not implemented")
 }
+func (UnimplementedStatisticsServiceServer) GetAllStats(context.Context, *GetAllStatsRequest) (*GetAllStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAllStats not implemented")
+}
+func (UnimplementedStatisticsServiceServer) GetStatsForTasks(context.Context, *GetStatsForTasksRequest) (*GetAllStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatsForTasks not implemented")
+}
+func (UnimplementedStatisticsServiceServer) MoveTaskStats(context.Context, *MoveTaskStatsRequest) (*MoveTaskStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveTaskStats not implemented")
+}
+func (UnimplementedStatisticsServiceServer) GetUserDeletion(context.Context, *GetUserDeletionRequest) (*GetUserDeletionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserDeletion not implemented")
+}
+func (UnimplementedStatisticsServiceServer) GetUserActivity(context.Context, *GetUserActivityRequest) (*GetUserActivityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserActivity not implemented")
+}
 func (UnimplementedStatisticsServiceServer) mustEmbedUnimplementedStatisticsServiceServer() {}
 
 // UnsafeStatisticsServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -161,6 +247,96 @@ func _StatisticsService_GetTopUsers_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StatisticsService_GetAllStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatisticsServiceServer).GetAllStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatisticsService_GetAllStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatisticsServiceServer).GetAllStats(ctx, req.(*GetAllStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatisticsService_GetStatsForTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsForTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatisticsServiceServer).GetStatsForTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatisticsService_GetStatsForTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatisticsServiceServer).GetStatsForTasks(ctx, req.(*GetStatsForTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatisticsService_MoveTaskStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveTaskStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatisticsServiceServer).MoveTaskStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatisticsService_MoveTaskStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatisticsServiceServer).MoveTaskStats(ctx, req.(*MoveTaskStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatisticsService_GetUserDeletion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserDeletionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatisticsServiceServer).GetUserDeletion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatisticsService_GetUserDeletion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatisticsServiceServer).GetUserDeletion(ctx, req.(*GetUserDeletionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatisticsService_GetUserActivity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserActivityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatisticsServiceServer).GetUserActivity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatisticsService_GetUserActivity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatisticsServiceServer).GetUserActivity(ctx, req.(*GetUserActivityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // StatisticsService_ServiceDesc is the grpc.ServiceDesc for StatisticsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -180,6 +356,26 @@ var StatisticsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetTopUsers",
 			Handler:    _StatisticsService_GetTopUsers_Handler,
 		},
+		{
+			MethodName: "GetAllStats",
+			Handler:    _StatisticsService_GetAllStats_Handler,
+		},
+		{
+			MethodName: "GetStatsForTasks",
+			Handler:    _StatisticsService_GetStatsForTasks_Handler,
+		},
+		{
+			MethodName: "MoveTaskStats",
+			Handler:    _StatisticsService_MoveTaskStats_Handler,
+		},
+		{
+			MethodName: "GetUserDeletion",
+			Handler:    _StatisticsService_GetUserDeletion_Handler,
+		},
+		{
+			MethodName: "GetUserActivity",
+			Handler:    _StatisticsService_GetUserActivity_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "statistics_service.proto",
@@ -2,73 +2,133 @@ package broker
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"statistics/src/database"
-	"time"
-
-	"github.com/IBM/sarama"
+	"strings"
 )
 
+const statTopic = "Stat"
+
+var ErrClosed = errors.New("broker is closed")
+
+type Message struct {
+	Topic string
+	// Key is the login for messages of user service; it keeps the messages
+	// of one user in one partition.
+	Key string
+	// Type tells how to read Value. Messages sent before it was added
+	// carry the type in Key instead.
+	Type  string
+	Value []byte
+}
+
+func (m Message) kind() string {
+	if m.Type == "" {
+		return m.Key
+	}
+	return m.Type
+}
+
+type Subscriber interface {
+	Messages() <-chan Message
+	Close() error
+}
+
+type Store interface {
+	EnsureLike(stat database.Statistic) error
+	EnsureView(stat database.Statistic) error
+	DeleteUserStats(id, login string, taskIDs []uint) error
+}
+
 type Broker struct {
-	Consumer sarama.PartitionConsumer
+	subscriber Subscriber
 }
 
-func New() (*Broker, func()) {
-	config := sarama.NewConfig()
-	config.Consumer.Return.Errors = true
+func New(subscriber Subscriber) *Broker {
+	return &Broker{subscriber: subscriber}
+}
 
-	// TODO: use env
-	brokers := []string{"kafka:29092"}
-	var master sarama.Consumer
-	var err error
-	for {
-		master, err = sarama.NewConsumer(brokers, config)
+// FromEnv picks the subscriber by BROKER ("kafka" by default or "memory").
+// The in-memory subscriber never receives anything unless fed by the caller,
+// so the service can run without Kafka.
+func FromEnv() (*Broker, func(), error) {
+	var subscriber Subscriber
+	switch kind := os.Getenv("BROKER"); kind {
+	case "", "kafka":
+		brokers := []string{"kafka:29092"}
+		if addrs := os.Getenv("KAFKA_BROKERS"); addrs != "" {
+			brokers = strings.Split(addrs, ",")
+		}
+		kafka, err := NewKafkaSubscriber(brokers, statTopic, kafkaConnectAttempts)
 		if err != nil {
-			fmt.Println("Wait for Kafka")
-			time.Sleep(5 * time.Second) // TODO: decrease?
-			continue
+			return nil, nil, err
 		}
-		fmt.Println("Kafka is ready")
-		break
+		subscriber = kafka
+	case "memory":
+		subscriber = NewChannelSubscriber(100)
+	default:
+		return nil, nil, fmt.Errorf("unknown broker %q", kind)
 	}
+
 	close := func() {
-		master.Close()
+		subscriber.Close()
 	}
+	return New(subscriber), close, nil
+}
 
-	consumer, err := master.ConsumePartition("Stat", 0, sarama.OffsetNewest)
-	if err != nil {
-		log.Fatalln("error creating broker", err)
+func (b *Broker) Consume(store Store) {
+	for msg := range b.subscriber.Messages() {
+		if err := handle(msg, store); err != nil {
+			slog.Error("Can not handle message", "type", msg.kind(), "error", err)
+		}
 	}
-	// TODO: close
-
-	return &Broker{
-		Consumer: consumer,
-	}, close
 }
 
-func (b *Broker) Consume(db *database.DataBase) {
-	for msg := range b.Consumer.Messages() {
-		var stat Statistic
-		json.Unmarshal(msg.Value, &stat)
-		key := string(msg.Key)
-		fmt.Printf("Got: %s %#v\n", string(msg.Key), stat)
-
-		if key == "Like" {
-			db.EnsureLike(database.Statistic{
-				Login:  stat.Login,
-				TaskID: stat.TaskID,
-			})
-		} else if key == "View" {
-			db.EnsureView(database.Statistic{
-				Login:  stat.Login,
-				TaskID: stat.TaskID,
-			})
+func handle(msg Message, store Store) error {
+	if msg.kind() == "DeleteUser" {
+		var deletion UserDeletion
+		if err := json.Unmarshal(msg.Value, &deletion); err != nil {
+			return err
+		}
+		if deletion.ID == "" || deletion.Login == "" {
+			return fmt.Errorf("deletion id and login are required")
 		}
+		return store.DeleteUserStats(deletion.ID, deletion.Login, deletion.TaskIDs)
 	}
+
+	var stat Statistic
+	if err := json.Unmarshal(msg.Value, &stat); err != nil {
+		return err
+	}
+
+	switch msg.kind() {
+	case "Like":
+		return store.EnsureLike(database.Statistic{
+			Login:  stat.Login,
+			TaskID: stat.TaskID,
+		})
+	case "View":
+		return store.EnsureView(database.Statistic{
+			Login:  stat.Login,
+			TaskID: stat.TaskID,
+		})
+	}
+	return fmt.Errorf("unknown type %q", msg.kind())
 }
 
 type Statistic struct {
 	Login  string `json:"login"`
 	TaskID uint   `json:"task_id"`
 }
+
+// UserDeletion asks to forget a deleted account. It goes through the same
+// topic and partition as likes and views of the user, so everything the
+// user sent before is handled first.
+type UserDeletion struct {
+	ID      string `json:"id"`
+	Login   string `json:"login"`
+	TaskIDs []uint `json:"task_ids"`
+}
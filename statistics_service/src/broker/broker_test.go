@@ -0,0 +1,121 @@
+package broker
+
+import (
+	"encoding/json"
+	"statistics/src/database"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryStore struct {
+	mu      sync.Mutex
+	likes   []database.Statistic
+	views   []database.Statistic
+	deleted []UserDeletion
+}
+
+func (s *memoryStore) EnsureLike(stat database.Statistic) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.likes = append(s.likes, stat)
+	return nil
+}
+
+func (s *memoryStore) EnsureView(stat database.Statistic) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.views = append(s.views, stat)
+	return nil
+}
+
+func (s *memoryStore) DeleteUserStats(id, login string, taskIDs []uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, UserDeletion{ID: id, Login: login, TaskIDs: taskIDs})
+	return nil
+}
+
+func statMessage(t *testing.T, key, login string, taskID uint) Message {
+	value, err := json.Marshal(Statistic{Login: login, TaskID: taskID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Message{Topic: statTopic, Key: key, Value: value}
+}
+
+func TestConsume(t *testing.T) {
+	sub := NewChannelSubscriber(10)
+	store := &memoryStore{}
+
+	sub.Publish(statMessage(t, "Like", "kek", 1))
+	sub.Publish(statMessage(t, "View", "kek", 1))
+	sub.Publish(statMessage(t, "View", "lol", 2))
+	sub.Publish(Message{Topic: statTopic, Key: "Like", Value: []byte("not json")})
+	sub.Publish(statMessage(t, "Unknown", "kek", 3))
+	sub.Publish(Message{Topic: statTopic, Key: "DeleteUser", Value: []byte(`{"id":"d1","login":"kek","task_ids":[1,5]}`)})
+	sub.Publish(Message{Topic: statTopic, Key: "DeleteUser", Value: []byte(`{"login":"kek"}`)})
+	sub.Close()
+
+	New(sub).Consume(store)
+
+	if len(store.likes) != 1 || store.likes[0] != (database.Statistic{Login: "kek", TaskID: 1}) {
+		t.Errorf("unexpected likes: %#v", store.likes)
+	}
+	if len(store.views) != 2 || store.views[1] != (database.Statistic{Login: "lol", TaskID: 2}) {
+		t.Errorf("unexpected views: %#v", store.views)
+	}
+	if len(store.deleted) != 1 || store.deleted[0].ID != "d1" || len(store.deleted[0].TaskIDs) != 2 {
+		t.Errorf("unexpected deletions: %#v", store.deleted)
+	}
+}
+
+func TestConsumeKeyedByLogin(t *testing.T) {
+	sub := NewChannelSubscriber(10)
+	store := &memoryStore{}
+
+	like := statMessage(t, "kek", "kek", 1)
+	like.Type = "Like"
+	sub.Publish(like)
+	sub.Publish(Message{Topic: statTopic, Key: "kek", Type: "DeleteUser", Value: []byte(`{"id":"d1","login":"kek"}`)})
+	sub.Close()
+
+	New(sub).Consume(store)
+
+	if len(store.likes) != 1 || len(store.deleted) != 1 {
+		t.Errorf("expected a like and a deletion, got %#v and %#v", store.likes, store.deleted)
+	}
+}
+
+func TestPublishAfterClose(t *testing.T) {
+	sub := NewChannelSubscriber(1)
+	sub.Close()
+	if err := sub.Publish(statMessage(t, "Like", "kek", 1)); err != ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestCloseWhilePublishing(t *testing.T) {
+	sub := NewChannelSubscriber(1)
+	sub.Publish(statMessage(t, "Like", "kek", 1))
+
+	published := make(chan error)
+	go func() {
+		published <- sub.Publish(statMessage(t, "Like", "kek", 2))
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		sub.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("expected Close not to wait for a blocked Publish")
+	}
+	if err := <-published; err != ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
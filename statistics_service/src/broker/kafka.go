@@ -0,0 +1,111 @@
+package broker
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	kafkaConnectAttempts = 12
+	kafkaConnectDelay    = 5 * time.Second
+)
+
+// KafkaSubscriber reads every partition of the topic. Messages of one
+// partition come in order; partitions are interleaved.
+type KafkaSubscriber struct {
+	master    sarama.Consumer
+	consumers []sarama.PartitionConsumer
+	messages  chan Message
+}
+
+func NewKafkaSubscriber(brokers []string, topic string, attempts int) (*KafkaSubscriber, error) {
+	config := sarama.NewConfig()
+	config.Consumer.Return.Errors = true
+
+	var master sarama.Consumer
+	var err error
+	for i := 0; i < attempts; i++ {
+		master, err = sarama.NewConsumer(brokers, config)
+		if err == nil {
+			break
+		}
+		slog.Info("Waiting for Kafka", "error", err)
+		time.Sleep(kafkaConnectDelay)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not connect to kafka: %w", err)
+	}
+	slog.Info("Kafka is ready")
+	return newKafkaSubscriber(master, topic)
+}
+
+func newKafkaSubscriber(master sarama.Consumer, topic string) (*KafkaSubscriber, error) {
+	partitions, err := master.Partitions(topic)
+	if err != nil {
+		master.Close()
+		return nil, fmt.Errorf("can not get partitions of %s: %w", topic, err)
+	}
+	s := &KafkaSubscriber{
+		master:   master,
+		messages: make(chan Message),
+	}
+	for _, partition := range partitions {
+		consumer, err := master.ConsumePartition(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("can not consume %s/%d: %w", topic, partition, err)
+		}
+		s.consumers = append(s.consumers, consumer)
+	}
+
+	var wg sync.WaitGroup
+	for _, consumer := range s.consumers {
+		wg.Add(1)
+		go func(consumer sarama.PartitionConsumer) {
+			defer wg.Done()
+			s.forward(consumer)
+		}(consumer)
+	}
+	go func() {
+		wg.Wait()
+		close(s.messages)
+	}()
+	return s, nil
+}
+
+func (s *KafkaSubscriber) forward(consumer sarama.PartitionConsumer) {
+	for msg := range consumer.Messages() {
+		s.messages <- Message{
+			Topic: msg.Topic,
+			Key:   string(msg.Key),
+			Type:  header(msg, "type"),
+			Value: msg.Value,
+		}
+	}
+}
+
+func header(msg *sarama.ConsumerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (s *KafkaSubscriber) Messages() <-chan Message {
+	return s.messages
+}
+
+func (s *KafkaSubscriber) Close() error {
+	for _, consumer := range s.consumers {
+		if err := consumer.Close(); err != nil {
+			return err
+		}
+	}
+	return s.master.Close()
+}
@@ -0,0 +1,38 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+func TestKafkaSubscriberReadsAllPartitions(t *testing.T) {
+	master := mocks.NewConsumer(t, nil)
+	master.SetTopicMetadata(map[string][]int32{statTopic: {0, 1}})
+	master.ExpectConsumePartition(statTopic, 0, sarama.OffsetNewest).
+		YieldMessage(&sarama.ConsumerMessage{
+			Topic:   statTopic,
+			Key:     []byte("kek"),
+			Headers: []*sarama.RecordHeader{{Key: []byte("type"), Value: []byte("Like")}},
+		})
+	master.ExpectConsumePartition(statTopic, 1, sarama.OffsetNewest).
+		YieldMessage(&sarama.ConsumerMessage{Topic: statTopic, Key: []byte("View")})
+
+	s, err := newKafkaSubscriber(master, statTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for i := 0; i < 2; i++ {
+		msg := <-s.Messages()
+		got[msg.Key] = msg.kind()
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got["kek"] != "Like" || got["View"] != "View" {
+		t.Errorf("expected messages of both partitions, got %v", got)
+	}
+}
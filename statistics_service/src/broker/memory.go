@@ -0,0 +1,54 @@
+package broker
+
+import (
+	"sync"
+)
+
+// ChannelSubscriber is an in-process stand-in for Kafka. Messages passed to
+// Publish are delivered to Messages() in order.
+type ChannelSubscriber struct {
+	mu       sync.RWMutex
+	once     sync.Once
+	done     chan struct{}
+	messages chan Message
+}
+
+func NewChannelSubscriber(size int) *ChannelSubscriber {
+	return &ChannelSubscriber{
+		done:     make(chan struct{}),
+		messages: make(chan Message, size),
+	}
+}
+
+// Publish waits for room in the buffer, like a consumer lagging behind
+// Kafka. Close wakes it up, so a full buffer can not block closing.
+func (s *ChannelSubscriber) Publish(msg Message) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	select {
+	case <-s.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case s.messages <- msg:
+		return nil
+	case <-s.done:
+		return ErrClosed
+	}
+}
+
+func (s *ChannelSubscriber) Messages() <-chan Message {
+	return s.messages
+}
+
+func (s *ChannelSubscriber) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		close(s.messages)
+	})
+	return nil
+}
@@ -1,10 +1,23 @@
 package database
 
 import (
+	"context"
+	"embed"
+	"io/fs"
+	"statistics/src/migrate"
+	"time"
+
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// Advisory lock key ("stat" in ASCII) taken while migrating.
+const migrationLockID = 0x73746174
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
 type DataBase struct {
 	*gorm.DB
 }
@@ -21,6 +34,13 @@ type viewStat struct {
 	TaskID    uint
 }
 
+// mergedTask redirects statistics of a duplicate task to its canonical task.
+type mergedTask struct {
+	TaskID     uint `gorm:"primaryKey"`
+	IntoTaskID uint
+	CreatedAt  time.Time
+}
+
 type Statistic struct {
 	Login  string
 	TaskID uint
@@ -34,13 +54,34 @@ func New() *DataBase {
 		panic("failed to connect tasks database: " + err.Error())
 	}
 
-	db.AutoMigrate(&likeStat{})
-	db.AutoMigrate(&viewStat{})
-
 	return &DataBase{db}
 }
 
+// WithContext binds queries to ctx, so they stop when the call is
+// cancelled or its deadline passes.
+func (db *DataBase) WithContext(ctx context.Context) *DataBase {
+	return &DataBase{db.DB.WithContext(ctx)}
+}
+
+func (db *DataBase) SchemaMigrator() (*migrate.Migrator, error) {
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, fsys, migrationLockID)
+}
+
 func (db *DataBase) EnsureLike(stat Statistic) error {
+	taskID, err := db.canonicalTask(stat.TaskID)
+	if err != nil {
+		return err
+	}
+	stat.TaskID = taskID
+
 	var info likeStat
 	result := db.First(&info, "user_login = ? AND task_id = ?", stat.Login, stat.TaskID)
 	if result.Error == nil {
@@ -55,6 +96,12 @@ func (db *DataBase) EnsureLike(stat Statistic) error {
 }
 
 func (db *DataBase) EnsureView(stat Statistic) error {
+	taskID, err := db.canonicalTask(stat.TaskID)
+	if err != nil {
+		return err
+	}
+	stat.TaskID = taskID
+
 	var info viewStat
 	result := db.First(&info, "user_login = ? AND task_id = ?", stat.Login, stat.TaskID)
 	if result.Error == nil {
@@ -115,3 +162,58 @@ func (db *DataBase) GroupedLikes() ([]TaskIDCount, error) {
 		Scan(&tasks)
 	return tasks, result.Error
 }
+
+func (db *DataBase) GroupedViews() ([]TaskIDCount, error) {
+	var tasks []TaskIDCount
+	result := db.Model(&viewStat{}).
+		Group("task_id").
+		Select("task_id, COUNT(*) AS count").
+		Scan(&tasks)
+	return tasks, result.Error
+}
+
+// canonicalTask returns the task that statistics of taskID are counted on.
+// It differs from taskID only for merged duplicates.
+func (db *DataBase) canonicalTask(taskID uint) (uint, error) {
+	var merged mergedTask
+	result := db.Limit(1).Find(&merged, "task_id = ?", taskID)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return taskID, result.Error
+	}
+	return merged.IntoTaskID, nil
+}
+
+// MoveTaskStats moves likes and views of a duplicate task onto the canonical
+// task. A user who reacted to both tasks is counted once. Later likes and
+// views of the duplicate are counted on the canonical task too, so calling it
+// again is safe.
+func (db *DataBase) MoveTaskStats(from, to uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		redirect := mergedTask{TaskID: from, IntoTaskID: to}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "task_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"into_task_id"}),
+		}).Create(&redirect).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&mergedTask{}).Where("into_task_id = ?", from).Update("into_task_id", to).Error
+		if err != nil {
+			return err
+		}
+
+		for _, model := range []any{&likeStat{}, &viewStat{}} {
+			reacted := tx.Model(model).Select("user_login").Where("task_id = ?", to)
+			err := tx.Model(model).
+				Where("task_id = ? AND user_login NOT IN (?)", from, reacted).
+				Update("task_id", to).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Where("task_id = ?", from).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
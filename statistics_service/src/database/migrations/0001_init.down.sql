@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS view_stats;
+DROP TABLE IF EXISTS like_stats;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS like_stats (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    user_login TEXT,
+    task_id    BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_like_stats_deleted_at ON like_stats (deleted_at);
+
+CREATE TABLE IF NOT EXISTS view_stats (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    user_login TEXT,
+    task_id    BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_view_stats_deleted_at ON view_stats (deleted_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_view_stats_task_id;
+DROP INDEX IF EXISTS idx_like_stats_task_id;
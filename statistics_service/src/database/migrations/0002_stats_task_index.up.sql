@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_like_stats_task_id ON like_stats (task_id);
+CREATE INDEX IF NOT EXISTS idx_view_stats_task_id ON view_stats (task_id);
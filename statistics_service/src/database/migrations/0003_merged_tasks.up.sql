@@ -0,0 +1,5 @@
+CREATE TABLE merged_tasks (
+    task_id      BIGINT PRIMARY KEY,
+    into_task_id BIGINT NOT NULL,
+    created_at   TIMESTAMPTZ
+);
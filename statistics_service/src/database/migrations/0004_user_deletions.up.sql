@@ -0,0 +1,4 @@
+CREATE TABLE user_deletions (
+    id           TEXT PRIMARY KEY,
+    completed_at TIMESTAMPTZ NOT NULL
+);
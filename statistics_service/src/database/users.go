@@ -0,0 +1,84 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// userDeletion records a handled account deletion, so the user service can
+// tell it is done. It keeps no login.
+type userDeletion struct {
+	ID          string `gorm:"primaryKey"`
+	CompletedAt time.Time
+}
+
+// DeleteUserStats drops likes and views the user left and all statistics of
+// the user's tasks for good, then records deletion id as done. Calling it
+// again is safe.
+func (db *DataBase) DeleteUserStats(id, login string, taskIDs []uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&likeStat{}, &viewStat{}} {
+			query := tx.Unscoped().Where("user_login = ?", login)
+			if len(taskIDs) > 0 {
+				query = query.Or("task_id IN ?", taskIDs)
+			}
+			if err := query.Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if len(taskIDs) > 0 {
+			err := tx.Where("task_id IN ? OR into_task_id IN ?", taskIDs, taskIDs).Delete(&mergedTask{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&userDeletion{
+			ID:          id,
+			CompletedAt: time.Now(),
+		}).Error
+	})
+}
+
+func (db *DataBase) UserDeletionDone(id string) (bool, error) {
+	var count int64
+	result := db.Model(&userDeletion{}).Where("id = ?", id).Count(&count)
+	return count > 0, result.Error
+}
+
+type Reaction struct {
+	TaskID    uint
+	CreatedAt time.Time
+}
+
+// UserReactions returns likes and views the user left, oldest first.
+func (db *DataBase) UserReactions(login string) (likes, views []Reaction, err error) {
+	result := db.Model(&likeStat{}).Where("user_login = ?", login).Order("created_at").Find(&likes)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	result = db.Model(&viewStat{}).Where("user_login = ?", login).Order("created_at").Find(&views)
+	return likes, views, result.Error
+}
+
+// CountsForTasks counts likes and views of the tasks.
+func (db *DataBase) CountsForTasks(taskIDs []uint) (likes, views []TaskIDCount, err error) {
+	if len(taskIDs) == 0 {
+		return nil, nil, nil
+	}
+	result := db.Model(&likeStat{}).
+		Where("task_id IN ?", taskIDs).
+		Group("task_id").
+		Select("task_id, COUNT(*) AS count").
+		Scan(&likes)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	result = db.Model(&viewStat{}).
+		Where("task_id IN ?", taskIDs).
+		Group("task_id").
+		Select("task_id, COUNT(*) AS count").
+		Scan(&views)
+	return likes, views, result.Error
+}
@@ -0,0 +1,145 @@
+package migrate
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":   {Data: []byte("CREATE INDEX")},
+		"0002_add_index.down.sql": {Data: []byte("DROP INDEX")},
+		"0001_init.up.sql":        {Data: []byte("CREATE TABLE")},
+		"0001_init.down.sql":      {Data: []byte("DROP TABLE")},
+		"README.md":               {Data: []byte("ignored")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	target := []Migration{
+		{Version: 1, Name: "init", Up: "CREATE TABLE", Down: "DROP TABLE"},
+		{Version: 2, Name: "add_index", Up: "CREATE INDEX", Down: "DROP INDEX"},
+	}
+	if len(migrations) != len(target) {
+		t.Fatalf("expected %d migrations, got %d", len(target), len(migrations))
+	}
+	for i, migration := range migrations {
+		if migration != target[i] {
+			t.Errorf("expected: %#v; got: %#v", target[i], migration)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Run("Missing down", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{
+			"0001_init.up.sql": {Data: []byte("CREATE TABLE")},
+		})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("Conflicting names", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{
+			"0001_init.up.sql":    {Data: []byte("CREATE TABLE")},
+			"0001_other.down.sql": {Data: []byte("DROP TABLE")},
+		})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+var testMigrations = fstest.MapFS{
+	"0001_init.up.sql":         {Data: []byte("CREATE TABLE")},
+	"0001_init.down.sql":       {Data: []byte("DROP TABLE")},
+	"0002_add_index.up.sql":    {Data: []byte("CREATE INDEX")},
+	"0002_add_index.down.sql":  {Data: []byte("DROP INDEX")},
+	"0003_add_column.up.sql":   {Data: []byte("ALTER TABLE ADD")},
+	"0003_add_column.down.sql": {Data: []byte("ALTER TABLE DROP")},
+}
+
+func appliedVersions(db *fakeDB) []int64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var versions []int64
+	for version := range db.applied {
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	return versions
+}
+
+func TestUpDown(t *testing.T) {
+	db := newFakeDB()
+	m, err := New(db.open(t.Name()), testMigrations, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	ctx := context.Background()
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("up: expected no error, got %v", err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("second up: expected no error, got %v", err)
+	}
+	if target := []string{"CREATE TABLE", "CREATE INDEX", "ALTER TABLE ADD"}; !slices.Equal(db.scripts, target) {
+		t.Errorf("up: expected scripts %q once in order, got %q", target, db.scripts)
+	}
+	if versions := appliedVersions(db); !slices.Equal(versions, []int64{1, 2, 3}) {
+		t.Errorf("up: expected all applied, got %v", versions)
+	}
+
+	db.scripts = nil
+	if err := m.Down(ctx, 2); err != nil {
+		t.Fatalf("down: expected no error, got %v", err)
+	}
+	if target := []string{"ALTER TABLE DROP", "DROP INDEX"}; !slices.Equal(db.scripts, target) {
+		t.Errorf("down: expected scripts %q, newest first, got %q", target, db.scripts)
+	}
+	if versions := appliedVersions(db); !slices.Equal(versions, []int64{1}) {
+		t.Errorf("down: expected only 1 applied, got %v", versions)
+	}
+
+	// Steps count applied migrations only.
+	db.scripts = nil
+	if err := m.Down(ctx, 5); err != nil {
+		t.Fatalf("down: expected no error, got %v", err)
+	}
+	if target := []string{"DROP TABLE"}; !slices.Equal(db.scripts, target) {
+		t.Errorf("down: expected scripts %q, got %q", target, db.scripts)
+	}
+	if versions := appliedVersions(db); len(versions) != 0 {
+		t.Errorf("down: expected nothing applied, got %v", versions)
+	}
+}
+
+func TestUpRollsBackFailedMigration(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for name, file := range testMigrations {
+		fsys[name] = file
+	}
+	fsys["0002_add_index.up.sql"] = &fstest.MapFile{Data: []byte("CREATE INDEX; FAIL")}
+
+	db := newFakeDB()
+	m, err := New(db.open(t.Name()), fsys, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := m.Up(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+	if target := []string{"CREATE TABLE"}; !slices.Equal(db.scripts, target) {
+		t.Errorf("expected only %q run, got %q", target, db.scripts)
+	}
+	if versions := appliedVersions(db); !slices.Equal(versions, []int64{1}) {
+		t.Errorf("expected the failed migration not recorded, got %v applied", versions)
+	}
+}
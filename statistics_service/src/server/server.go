@@ -2,15 +2,19 @@ package server
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log"
+	"log/slog"
 	"net"
 	"sort"
 	pb "statistics/proto/statistic"
 	"statistics/src/database"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type Server struct {
@@ -25,26 +29,38 @@ func New(db *database.DataBase) *Server {
 }
 
 func (s *Server) GetTaskStats(ctx context.Context, req *pb.GetTaskStatsRequest) (*pb.GetTaskStatsResponse, error) {
-	likes, _ := s.db.CountLikes(uint(req.Id))
-	views, err := s.db.CountViews(uint(req.Id))
+	if req.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	likes, err := s.db.WithContext(ctx).CountLikes(uint(req.Id))
+	if err != nil {
+		return nil, internal(err)
+	}
+	views, err := s.db.WithContext(ctx).CountViews(uint(req.Id))
+	if err != nil {
+		return nil, internal(err)
+	}
 
 	return &pb.GetTaskStatsResponse{
 		Likes: int32(likes),
 		Views: int32(views),
-	}, err
+	}, nil
 }
 
 func (s *Server) GetTopTasks(ctx context.Context, req *pb.GetTopTasksRequest) (*pb.GetTopTasksResponse, error) {
 	var err error
 	var topTasks []database.TaskIDCount
 
-	if req.Sort == pb.SortBy_likes {
-		topTasks, err = s.db.TopByLikes(5)
-	} else {
-		topTasks, err = s.db.TopByViews(5)
+	switch req.Sort {
+	case pb.SortBy_likes:
+		topTasks, err = s.db.WithContext(ctx).TopByLikes(5)
+	case pb.SortBy_views:
+		topTasks, err = s.db.WithContext(ctx).TopByViews(5)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown sort %v", req.Sort)
 	}
 	if err != nil {
-		return nil, err
+		return nil, internal(err)
 	}
 
 	result := extractTopTasks(topTasks, req.Authors)
@@ -68,9 +84,9 @@ func extractTopTasks(topTasks []database.TaskIDCount, authors map[uint32]string)
 }
 
 func (s *Server) GetTopUsers(ctx context.Context, req *pb.GetTopUsersRequest) (*pb.GetTopUsersResponse, error) {
-	likes, err := s.db.GroupedLikes()
+	likes, err := s.db.WithContext(ctx).GroupedLikes()
 	if err != nil {
-		return nil, err
+		return nil, internal(err)
 	}
 	authorsLikes := make(map[string]int64)
 	for _, like := range likes {
@@ -101,18 +117,131 @@ func extractTopAuthors(authorsLikes map[string]int64) []*pb.Author {
 	return authors
 }
 
+func (s *Server) GetAllStats(ctx context.Context, req *pb.GetAllStatsRequest) (*pb.GetAllStatsResponse, error) {
+	likes, err := s.db.WithContext(ctx).GroupedLikes()
+	if err != nil {
+		return nil, internal(err)
+	}
+	views, err := s.db.WithContext(ctx).GroupedViews()
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	return &pb.GetAllStatsResponse{
+		Likes: countsByTask(likes),
+		Views: countsByTask(views),
+	}, nil
+}
+
+func (s *Server) GetStatsForTasks(ctx context.Context, req *pb.GetStatsForTasksRequest) (*pb.GetAllStatsResponse, error) {
+	taskIDs := make([]uint, 0, len(req.TaskIds))
+	for _, id := range req.TaskIds {
+		taskIDs = append(taskIDs, uint(id))
+	}
+	likes, views, err := s.db.WithContext(ctx).CountsForTasks(taskIDs)
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	return &pb.GetAllStatsResponse{
+		Likes: countsByTask(likes),
+		Views: countsByTask(views),
+	}, nil
+}
+
+func countsByTask(counts []database.TaskIDCount) map[uint32]int32 {
+	result := make(map[uint32]int32, len(counts))
+	for _, count := range counts {
+		result[uint32(count.TaskID)] = int32(count.Count)
+	}
+	return result
+}
+
+func (s *Server) MoveTaskStats(ctx context.Context, req *pb.MoveTaskStatsRequest) (*pb.MoveTaskStatsResponse, error) {
+	if req.FromId == 0 || req.ToId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "from_id and to_id are required")
+	}
+	if req.FromId == req.ToId {
+		return nil, status.Error(codes.InvalidArgument, "can not move stats of a task onto itself")
+	}
+	if err := s.db.WithContext(ctx).MoveTaskStats(uint(req.FromId), uint(req.ToId)); err != nil {
+		return nil, internal(err)
+	}
+	return &pb.MoveTaskStatsResponse{}, nil
+}
+
+func (s *Server) GetUserDeletion(ctx context.Context, req *pb.GetUserDeletionRequest) (*pb.GetUserDeletionResponse, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	done, err := s.db.WithContext(ctx).UserDeletionDone(req.Id)
+	if err != nil {
+		return nil, internal(err)
+	}
+	return &pb.GetUserDeletionResponse{Done: done}, nil
+}
+
+func (s *Server) GetUserActivity(ctx context.Context, req *pb.GetUserActivityRequest) (*pb.GetUserActivityResponse, error) {
+	if req.Login == "" {
+		return nil, status.Error(codes.InvalidArgument, "login is required")
+	}
+	likes, views, err := s.db.WithContext(ctx).UserReactions(req.Login)
+	if err != nil {
+		return nil, internal(err)
+	}
+	taskIDs := make([]uint, 0, len(req.TaskIds))
+	for _, id := range req.TaskIds {
+		taskIDs = append(taskIDs, uint(id))
+	}
+	likesReceived, viewsReceived, err := s.db.WithContext(ctx).CountsForTasks(taskIDs)
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	return &pb.GetUserActivityResponse{
+		Likes:         reactionsToProto(likes),
+		Views:         reactionsToProto(views),
+		LikesReceived: countsByTask(likesReceived),
+		ViewsReceived: countsByTask(viewsReceived),
+	}, nil
+}
+
+func reactionsToProto(reactions []database.Reaction) []*pb.Reaction {
+	result := make([]*pb.Reaction, 0, len(reactions))
+	for _, reaction := range reactions {
+		result = append(result, &pb.Reaction{
+			TaskId: uint32(reaction.TaskID),
+			Time:   timestamppb.New(reaction.CreatedAt),
+		})
+	}
+	return result
+}
+
+// internal reports a database error. Queries stopped by the caller's
+// deadline or cancellation keep their code, so user service can tell them
+// from failures.
+func internal(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
 func (s *Server) RegisterAndListen(addr string) {
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(logCalls(slog.Default())))
 	reflection.Register(grpcServer)
 
 	pb.RegisterStatisticsServiceServer(grpcServer, s)
 
-	fmt.Println("Statistics service server started.")
+	slog.Info("Statistics service server started.", slog.String("addr", addr))
 	err = grpcServer.Serve(lis)
 	if err != nil {
 		log.Fatalf("Server stopped: %v", err)
@@ -1,9 +1,16 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	pb "statistics/proto/statistic"
 	"statistics/src/database"
 	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestExtractTopAuthors(t *testing.T) {
@@ -53,3 +60,58 @@ func TestExtractTopTasks(t *testing.T) {
 		}
 	}
 }
+
+func TestCountsByTask(t *testing.T) {
+	in := []database.TaskIDCount{
+		{TaskID: 1, Count: 3},
+		{TaskID: 7, Count: 1},
+	}
+
+	out := countsByTask(in)
+	if len(out) != 2 || out[1] != 3 || out[7] != 1 {
+		t.Errorf("expected: map[1:3 7:1]; got: %v", out)
+	}
+}
+
+func TestMoveTaskStatsValidation(t *testing.T) {
+	s := &Server{}
+	for _, req := range []*pb.MoveTaskStatsRequest{
+		{FromId: 0, ToId: 2},
+		{FromId: 3, ToId: 3},
+	} {
+		_, err := s.MoveTaskStats(context.Background(), req)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%v: expected InvalidArgument, got %v", req, err)
+		}
+	}
+}
+
+func TestReactionsToProto(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	out := reactionsToProto([]database.Reaction{{TaskID: 4, CreatedAt: at}})
+	if len(out) != 1 || out[0].TaskId != 4 || !out[0].Time.AsTime().Equal(at) {
+		t.Errorf("unexpected reactions: %v", out)
+	}
+
+	s := &Server{}
+	if _, err := s.GetUserActivity(context.Background(), &pb.GetUserActivityRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without login, got %v", err)
+	}
+}
+
+func TestInternal(t *testing.T) {
+	tests := []struct {
+		in   error
+		code codes.Code
+	}{
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+		{context.Canceled, codes.Canceled},
+		{errors.New("boom"), codes.Internal},
+	}
+
+	for _, test := range tests {
+		if code := status.Code(internal(test.in)); code != test.code {
+			t.Errorf("%v: expected: %v; got: %v", test.in, test.code, code)
+		}
+	}
+}
@@ -1,18 +1,42 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log"
+	"log/slog"
+	"os"
 
-	"tasksmanager/src/server"
 	"tasksmanager/src/database"
+	"tasksmanager/src/migrate"
+	"tasksmanager/src/server"
 )
 
 func main() {
 	port := flag.Int("port", 8081, "Port of tasks manager server.")
+	autoMigrate := flag.Bool("auto-migrate", true, "Apply pending database migrations on start.")
 	flag.Parse()
 
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
 	db := database.New()
+	migrator, err := db.SchemaMigrator()
+	if err != nil {
+		log.Fatalf("Can not load migrations: %v", err)
+	}
+	if flag.Arg(0) == "migrate" {
+		if err := migrate.Run(context.Background(), migrator, flag.Args()[1:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if *autoMigrate {
+		if err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+	}
+
 	server := server.New(db)
 
 	addr := fmt.Sprintf("0.0.0.0:%d", *port)
@@ -20,11 +20,34 @@ import (
 const _ = grpc.SupportPackageIsVersion8
 
 const (
-	TaskService_CreateTask_FullMethodName = "/mes_grpc.TaskService/CreateTask"
-	TaskService_UpdateTask_FullMethodName = "/mes_grpc.TaskService/UpdateTask"
-	TaskService_DeleteTask_FullMethodName = "/mes_grpc.TaskService/DeleteTask"
-	TaskService_GetTask_FullMethodName    = "/mes_grpc.TaskService/GetTask"
-	TaskService_GetTasks_FullMethodName   = "/mes_grpc.TaskService/GetTasks"
+	TaskService_CreateTask_FullMethodName            = "/mes_grpc.TaskService/CreateTask"
+	TaskService_UpdateTask_FullMethodName            = "/mes_grpc.TaskService/UpdateTask"
+	TaskService_DeleteTask_FullMethodName            = "/mes_grpc.TaskService/DeleteTask"
+	TaskService_GetTask_FullMethodName               = "/mes_grpc.TaskService/GetTask"
+	TaskService_GetTasks_FullMethodName              = "/mes_grpc.TaskService/GetTasks"
+	TaskService_CountTasks_FullMethodName            = "/mes_grpc.TaskService/CountTasks"
+	TaskService_GetCountCandidates_FullMethodName    = "/mes_grpc.TaskService/GetCountCandidates"
+	TaskService_CountQueries_FullMethodName          = "/mes_grpc.TaskService/CountQueries"
+	TaskService_CreateFieldDefinition_FullMethodName = "/mes_grpc.TaskService/CreateFieldDefinition"
+	TaskService_GetFieldDefinitions_FullMethodName   = "/mes_grpc.TaskService/GetFieldDefinitions"
+	TaskService_DeleteFieldDefinition_FullMethodName = "/mes_grpc.TaskService/DeleteFieldDefinition"
+	TaskService_AddChecklistItem_FullMethodName      = "/mes_grpc.TaskService/AddChecklistItem"
+	TaskService_CheckChecklistItem_FullMethodName    = "/mes_grpc.TaskService/CheckChecklistItem"
+	TaskService_MoveChecklistItem_FullMethodName     = "/mes_grpc.TaskService/MoveChecklistItem"
+	TaskService_DeleteChecklistItem_FullMethodName   = "/mes_grpc.TaskService/DeleteChecklistItem"
+	TaskService_LinkTasks_FullMethodName             = "/mes_grpc.TaskService/LinkTasks"
+	TaskService_UnlinkTasks_FullMethodName           = "/mes_grpc.TaskService/UnlinkTasks"
+	TaskService_MergeTasks_FullMethodName            = "/mes_grpc.TaskService/MergeTasks"
+	TaskService_CreateSprint_FullMethodName          = "/mes_grpc.TaskService/CreateSprint"
+	TaskService_GetSprints_FullMethodName            = "/mes_grpc.TaskService/GetSprints"
+	TaskService_StartSprint_FullMethodName           = "/mes_grpc.TaskService/StartSprint"
+	TaskService_CloseSprint_FullMethodName           = "/mes_grpc.TaskService/CloseSprint"
+	TaskService_PlanTask_FullMethodName              = "/mes_grpc.TaskService/PlanTask"
+	TaskService_GetVelocity_FullMethodName           = "/mes_grpc.TaskService/GetVelocity"
+	TaskService_GetUserTaskIds_FullMethodName        = "/mes_grpc.TaskService/GetUserTaskIds"
+	TaskService_DeleteUserData_FullMethodName        = "/mes_grpc.TaskService/DeleteUserData"
+	TaskService_AdminDeleteTask_FullMethodName       = "/mes_grpc.TaskService/AdminDeleteTask"
+	TaskService_AdminRestoreTask_FullMethodName      = "/mes_grpc.TaskService/AdminRestoreTask"
 )
 
 // TaskServiceClient is the client API for TaskService service.
@@ -36,6 +59,44 @@ type TaskServiceClient interface {
 	DeleteTask(ctx context.Context, in *DeleteTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*Task, error)
 	GetTasks(ctx context.Context, in *GetTasksRequest, opts ...grpc.CallOption) (*GetTasksReponse, error)
+	CountTasks(ctx context.Context, in *GetTasksRequest, opts ...grpc.CallOption) (*CountTasksResponse, error)
+	// Tells whether the query filters or sorts by likes or views, and if
+	// so which tasks match the rest of the request. Likes and views of
+	// only these tasks have to be passed to GetTasks and CountTasks.
+	GetCountCandidates(ctx context.Context, in *GetTasksRequest, opts ...grpc.CallOption) (*GetCountCandidatesResponse, error)
+	// Counts tasks matching each query, like CountTasks. Without
+	// with_counts a query that filters by likes or views is not counted:
+	// it gets needs_counts and the tasks whose likes and views it needs.
+	CountQueries(ctx context.Context, in *CountQueriesRequest, opts ...grpc.CallOption) (*CountQueriesResponse, error)
+	CreateFieldDefinition(ctx context.Context, in *CreateFieldDefinitionRequest, opts ...grpc.CallOption) (*FieldDefinition, error)
+	GetFieldDefinitions(ctx context.Context, in *GetFieldDefinitionsRequest, opts ...grpc.CallOption) (*GetFieldDefinitionsResponse, error)
+	DeleteFieldDefinition(ctx context.Context, in *DeleteFieldDefinitionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	AddChecklistItem(ctx context.Context, in *AddChecklistItemRequest, opts ...grpc.CallOption) (*ChecklistItem, error)
+	CheckChecklistItem(ctx context.Context, in *CheckChecklistItemRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	MoveChecklistItem(ctx context.Context, in *MoveChecklistItemRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DeleteChecklistItem(ctx context.Context, in *DeleteChecklistItemRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	LinkTasks(ctx context.Context, in *LinkTasksRequest, opts ...grpc.CallOption) (*TaskLink, error)
+	UnlinkTasks(ctx context.Context, in *UnlinkTasksRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Closes the duplicate and links it to the canonical task. Statistics
+	// are moved by the caller through statistics service.
+	MergeTasks(ctx context.Context, in *MergeTasksRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	CreateSprint(ctx context.Context, in *CreateSprintRequest, opts ...grpc.CallOption) (*Sprint, error)
+	GetSprints(ctx context.Context, in *GetSprintsRequest, opts ...grpc.CallOption) (*GetSprintsResponse, error)
+	StartSprint(ctx context.Context, in *StartSprintRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Unfinished tasks roll over to next_sprint_id or to the backlog.
+	CloseSprint(ctx context.Context, in *CloseSprintRequest, opts ...grpc.CallOption) (*CloseSprintResponse, error)
+	PlanTask(ctx context.Context, in *PlanTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetVelocity(ctx context.Context, in *GetVelocityRequest, opts ...grpc.CallOption) (*GetVelocityResponse, error)
+	// IDs of all tasks of the user, deleted ones included. Used by account
+	// deletion to find statistics to drop.
+	GetUserTaskIds(ctx context.Context, in *UserDataRequest, opts ...grpc.CallOption) (*GetUserTaskIdsResponse, error)
+	// Deletes tasks of the user and removes the login from shared data,
+	// like sprints and field definitions. Calling it again is safe.
+	DeleteUserData(ctx context.Context, in *UserDataRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Privileged calls for administrators: no ownership checks. Restore
+	// brings back a deleted task.
+	AdminDeleteTask(ctx context.Context, in *AdminTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	AdminRestoreTask(ctx context.Context, in *AdminTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type taskServiceClient struct {
@@ -96,6 +157,236 @@ func (c *taskServiceClient) GetTasks(ctx context.Context, in *GetTasksRequest, o
 	return out, nil
 }
 
+func (c *taskServiceClient) CountTasks(ctx context.Context, in *GetTasksRequest, opts ...grpc.CallOption) (*CountTasksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CountTasksResponse)
+	err := c.cc.Invoke(ctx, TaskService_CountTasks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) GetCountCandidates(ctx context.Context, in *GetTasksRequest, opts ...grpc.CallOption) (*GetCountCandidatesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCountCandidatesResponse)
+	err := c.cc.Invoke(ctx, TaskService_GetCountCandidates_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) CountQueries(ctx context.Context, in *CountQueriesRequest, opts ...grpc.CallOption) (*CountQueriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CountQueriesResponse)
+	err := c.cc.Invoke(ctx, TaskService_CountQueries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) CreateFieldDefinition(ctx context.Context, in *CreateFieldDefinitionRequest, opts ...grpc.CallOption) (*FieldDefinition, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FieldDefinition)
+	err := c.cc.Invoke(ctx, TaskService_CreateFieldDefinition_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) GetFieldDefinitions(ctx context.Context, in *GetFieldDefinitionsRequest, opts ...grpc.CallOption) (*GetFieldDefinitionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetFieldDefinitionsResponse)
+	err := c.cc.Invoke(ctx, TaskService_GetFieldDefinitions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) DeleteFieldDefinition(ctx context.Context, in *DeleteFieldDefinitionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TaskService_DeleteFieldDefinition_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) AddChecklistItem(ctx context.Context, in *AddChecklistItemRequest, opts ...grpc.CallOption) (*ChecklistItem, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChecklistItem)
+	err := c.cc.Invoke(ctx, TaskService_AddChecklistItem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) CheckChecklistItem(ctx context.Context, in *CheckChecklistItemRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TaskService_CheckChecklistItem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) MoveChecklistItem(ctx context.Context, in *MoveChecklistItemRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TaskService_MoveChecklistItem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) DeleteChecklistItem(ctx context.Context, in *DeleteChecklistItemRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TaskService_DeleteChecklistItem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) LinkTasks(ctx context.Context, in *LinkTasksRequest, opts ...grpc.CallOption) (*TaskLink, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TaskLink)
+	err := c.cc.Invoke(ctx, TaskService_LinkTasks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) UnlinkTasks(ctx context.Context, in *UnlinkTasksRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TaskService_UnlinkTasks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) MergeTasks(ctx context.Context, in *MergeTasksRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TaskService_MergeTasks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) CreateSprint(ctx context.Context, in *CreateSprintRequest, opts ...grpc.CallOption) (*Sprint, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Sprint)
+	err := c.cc.Invoke(ctx, TaskService_CreateSprint_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) GetSprints(ctx context.Context, in *GetSprintsRequest, opts ...grpc.CallOption) (*GetSprintsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetSprintsResponse)
+	err := c.cc.Invoke(ctx, TaskService_GetSprints_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) StartSprint(ctx context.Context, in *StartSprintRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TaskService_StartSprint_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) CloseSprint(ctx context.Context, in *CloseSprintRequest, opts ...grpc.CallOption) (*CloseSprintResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CloseSprintResponse)
+	err := c.cc.Invoke(ctx, TaskService_CloseSprint_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) PlanTask(ctx context.Context, in *PlanTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TaskService_PlanTask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) GetVelocity(ctx context.Context, in *GetVelocityRequest, opts ...grpc.CallOption) (*GetVelocityResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetVelocityResponse)
+	err := c.cc.Invoke(ctx, TaskService_GetVelocity_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) GetUserTaskIds(ctx context.Context, in *UserDataRequest, opts ...grpc.CallOption) (*GetUserTaskIdsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUserTaskIdsResponse)
+	err := c.cc.Invoke(ctx, TaskService_GetUserTaskIds_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) DeleteUserData(ctx context.Context, in *UserDataRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TaskService_DeleteUserData_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) AdminDeleteTask(ctx context.Context, in *AdminTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TaskService_AdminDeleteTask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) AdminRestoreTask(ctx context.Context, in *AdminTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TaskService_AdminRestoreTask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TaskServiceServer is the server API for TaskService service.
 // All implementations must embed UnimplementedTaskServiceServer
 // for forward compatibility
@@ -105,6 +396,44 @@ type TaskServiceServer interface {
 	DeleteTask(context.Context, *DeleteTaskRequest) (*emptypb.Empty, error)
 	GetTask(context.Context, *GetTaskRequest) (*Task, error)
 	GetTasks(context.Context, *GetTasksRequest) (*GetTasksReponse, error)
+	CountTasks(context.Context, *GetTasksRequest) (*CountTasksResponse, error)
+	// Tells whether the query filters or sorts by likes or views, and if
+	// so which tasks match the rest of the request. Likes and views of
+	// only these tasks have to be passed to GetTasks and CountTasks.
+	GetCountCandidates(context.Context, *GetTasksRequest) (*GetCountCandidatesResponse, error)
+	// Counts tasks matching each query, like CountTasks. Without
+	// with_counts a query that filters by likes or views is not counted:
+	// it gets needs_counts and the tasks whose likes and views it needs.
+	CountQueries(context.Context, *CountQueriesRequest) (*CountQueriesResponse, error)
+	CreateFieldDefinition(context.Context, *CreateFieldDefinitionRequest) (*FieldDefinition, error)
+	GetFieldDefinitions(context.Context, *GetFieldDefinitionsRequest) (*GetFieldDefinitionsResponse, error)
+	DeleteFieldDefinition(context.Context, *DeleteFieldDefinitionRequest) (*emptypb.Empty, error)
+	AddChecklistItem(context.Context, *AddChecklistItemRequest) (*ChecklistItem, error)
+	CheckChecklistItem(context.Context, *CheckChecklistItemRequest) (*emptypb.Empty, error)
+	MoveChecklistItem(context.Context, *MoveChecklistItemRequest) (*emptypb.Empty, error)
+	DeleteChecklistItem(context.Context, *DeleteChecklistItemRequest) (*emptypb.Empty, error)
+	LinkTasks(context.Context, *LinkTasksRequest) (*TaskLink, error)
+	UnlinkTasks(context.Context, *UnlinkTasksRequest) (*emptypb.Empty, error)
+	// Closes the duplicate and links it to the canonical task. Statistics
+	// are moved by the caller through statistics service.
+	MergeTasks(context.Context, *MergeTasksRequest) (*emptypb.Empty, error)
+	CreateSprint(context.Context, *CreateSprintRequest) (*Sprint, error)
+	GetSprints(context.Context, *GetSprintsRequest) (*GetSprintsResponse, error)
+	StartSprint(context.Context, *StartSprintRequest) (*emptypb.Empty, error)
+	// Unfinished tasks roll over to next_sprint_id or to the backlog.
+	CloseSprint(context.Context, *CloseSprintRequest) (*CloseSprintResponse, error)
+	PlanTask(context.Context, *PlanTaskRequest) (*emptypb.Empty, error)
+	GetVelocity(context.Context, *GetVelocityRequest) (*GetVelocityResponse, error)
+	// IDs of all tasks of the user, deleted ones included. Used by account
+	// deletion to find statistics to drop.
+	GetUserTaskIds(context.Context, *UserDataRequest) (*GetUserTaskIdsResponse, error)
+	// Deletes tasks of the user and removes the login from shared data,
+	// like sprints and field definitions. Calling it again is safe.
+	DeleteUserData(context.Context, *UserDataRequest) (*emptypb.Empty, error)
+	// Privileged calls for administrators: no ownership checks. Restore
+	// brings back a deleted task.
+	AdminDeleteTask(context.Context, *AdminTaskRequest) (*emptypb.Empty, error)
+	AdminRestoreTask(context.Context, *AdminTaskRequest) (*emptypb.Empty, error)
 	mustEmbedUnimplementedTaskServiceServer()
 }
 
@@ -127,6 +456,75 @@ func (UnimplementedTaskServiceServer) GetTask(context.Context, *GetTaskRequest)
 func (UnimplementedTaskServiceServer) GetTasks(context.Context, *GetTasksRequest) (*GetTasksReponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetTasks not implemented")
 }
+func (UnimplementedTaskServiceServer) CountTasks(context.Context, *GetTasksRequest) (*CountTasksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) GetCountCandidates(context.Context, *GetTasksRequest) (*GetCountCandidatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCountCandidates not implemented")
+}
+func (UnimplementedTaskServiceServer) CountQueries(context.Context, *CountQueriesRequest) (*CountQueriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountQueries not implemented")
+}
+func (UnimplementedTaskServiceServer) CreateFieldDefinition(context.Context, *CreateFieldDefinitionRequest) (*FieldDefinition, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateFieldDefinition not implemented")
+}
+func (UnimplementedTaskServiceServer) GetFieldDefinitions(context.Context, *GetFieldDefinitionsRequest) (*GetFieldDefinitionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFieldDefinitions not implemented")
+}
+func (UnimplementedTaskServiceServer) DeleteFieldDefinition(context.Context, *DeleteFieldDefinitionRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteFieldDefinition not implemented")
+}
+func (UnimplementedTaskServiceServer) AddChecklistItem(context.Context, *AddChecklistItemRequest) (*ChecklistItem, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddChecklistItem not implemented")
+}
+func (UnimplementedTaskServiceServer) CheckChecklistItem(context.Context, *CheckChecklistItemRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckChecklistItem not implemented")
+}
+func (UnimplementedTaskServiceServer) MoveChecklistItem(context.Context, *MoveChecklistItemRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveChecklistItem not implemented")
+}
+func (UnimplementedTaskServiceServer) DeleteChecklistItem(context.Context, *DeleteChecklistItemRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteChecklistItem not implemented")
+}
+func (UnimplementedTaskServiceServer) LinkTasks(context.Context, *LinkTasksRequest) (*TaskLink, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LinkTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) UnlinkTasks(context.Context, *UnlinkTasksRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlinkTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) MergeTasks(context.Context, *MergeTasksRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MergeTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) CreateSprint(context.Context, *CreateSprintRequest) (*Sprint, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSprint not implemented")
+}
+func (UnimplementedTaskServiceServer) GetSprints(context.Context, *GetSprintsRequest) (*GetSprintsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSprints not implemented")
+}
+func (UnimplementedTaskServiceServer) StartSprint(context.Context, *StartSprintRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartSprint not implemented")
+}
+func (UnimplementedTaskServiceServer) CloseSprint(context.Context, *CloseSprintRequest) (*CloseSprintResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseSprint not implemented")
+}
+func (UnimplementedTaskServiceServer) PlanTask(context.Context, *PlanTaskRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PlanTask not implemented")
+}
+func (UnimplementedTaskServiceServer) GetVelocity(context.Context, *GetVelocityRequest) (*GetVelocityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVelocity not implemented")
+}
+func (UnimplementedTaskServiceServer) GetUserTaskIds(context.Context, *UserDataRequest) (*GetUserTaskIdsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserTaskIds not implemented")
+}
+func (UnimplementedTaskServiceServer) DeleteUserData(context.Context, *UserDataRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserData not implemented")
+}
+func (UnimplementedTaskServiceServer) AdminDeleteTask(context.Context, *AdminTaskRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdminDeleteTask not implemented")
+}
+func (UnimplementedTaskServiceServer) AdminRestoreTask(context.Context, *AdminTaskRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdminRestoreTask not implemented")
+}
 func (UnimplementedTaskServiceServer) mustEmbedUnimplementedTaskServiceServer() {}
 
 // UnsafeTaskServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -230,32 +628,538 @@ func _TaskService_GetTasks_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
-// TaskService_ServiceDesc is the grpc.ServiceDesc for TaskService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var TaskService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "mes_grpc.TaskService",
-	HandlerType: (*TaskServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "CreateTask",
-			Handler:    _TaskService_CreateTask_Handler,
-		},
-		{
-			MethodName: "UpdateTask",
-			Handler:    _TaskService_UpdateTask_Handler,
-		},
-		{
-			MethodName: "DeleteTask",
-			Handler:    _TaskService_DeleteTask_Handler,
-		},
-		{
-			MethodName: "GetTask",
-			Handler:    _TaskService_GetTask_Handler,
-		},
-		{
-			MethodName: "GetTasks",
-			Handler:    _TaskService_GetTasks_Handler,
+func _TaskService_CountTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).CountTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_CountTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).CountTasks(ctx, req.(*GetTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_GetCountCandidates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).GetCountCandidates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_GetCountCandidates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).GetCountCandidates(ctx, req.(*GetTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_CountQueries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountQueriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).CountQueries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_CountQueries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).CountQueries(ctx, req.(*CountQueriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_CreateFieldDefinition_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateFieldDefinitionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).CreateFieldDefinition(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_CreateFieldDefinition_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).CreateFieldDefinition(ctx, req.(*CreateFieldDefinitionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_GetFieldDefinitions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFieldDefinitionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).GetFieldDefinitions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_GetFieldDefinitions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).GetFieldDefinitions(ctx, req.(*GetFieldDefinitionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_DeleteFieldDefinition_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteFieldDefinitionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).DeleteFieldDefinition(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_DeleteFieldDefinition_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).DeleteFieldDefinition(ctx, req.(*DeleteFieldDefinitionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_AddChecklistItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddChecklistItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).AddChecklistItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_AddChecklistItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).AddChecklistItem(ctx, req.(*AddChecklistItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_CheckChecklistItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckChecklistItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).CheckChecklistItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_CheckChecklistItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).CheckChecklistItem(ctx, req.(*CheckChecklistItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_MoveChecklistItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveChecklistItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).MoveChecklistItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_MoveChecklistItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).MoveChecklistItem(ctx, req.(*MoveChecklistItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_DeleteChecklistItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteChecklistItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).DeleteChecklistItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_DeleteChecklistItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).DeleteChecklistItem(ctx, req.(*DeleteChecklistItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_LinkTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LinkTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).LinkTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_LinkTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).LinkTasks(ctx, req.(*LinkTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_UnlinkTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlinkTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).UnlinkTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_UnlinkTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).UnlinkTasks(ctx, req.(*UnlinkTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_MergeTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MergeTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).MergeTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_MergeTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).MergeTasks(ctx, req.(*MergeTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_CreateSprint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSprintRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).CreateSprint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_CreateSprint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).CreateSprint(ctx, req.(*CreateSprintRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_GetSprints_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSprintsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).GetSprints(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_GetSprints_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).GetSprints(ctx, req.(*GetSprintsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_StartSprint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartSprintRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).StartSprint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_StartSprint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).StartSprint(ctx, req.(*StartSprintRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_CloseSprint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseSprintRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).CloseSprint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_CloseSprint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).CloseSprint(ctx, req.(*CloseSprintRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_PlanTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlanTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).PlanTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_PlanTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).PlanTask(ctx, req.(*PlanTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_GetVelocity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVelocityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).GetVelocity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_GetVelocity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).GetVelocity(ctx, req.(*GetVelocityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_GetUserTaskIds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).GetUserTaskIds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_GetUserTaskIds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).GetUserTaskIds(ctx, req.(*UserDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_DeleteUserData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).DeleteUserData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_DeleteUserData_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).DeleteUserData(ctx, req.(*UserDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_AdminDeleteTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).AdminDeleteTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_AdminDeleteTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).AdminDeleteTask(ctx, req.(*AdminTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_AdminRestoreTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).AdminRestoreTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_AdminRestoreTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).AdminRestoreTask(ctx, req.(*AdminTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TaskService_ServiceDesc is the grpc.ServiceDesc for TaskService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TaskService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mes_grpc.TaskService",
+	HandlerType: (*TaskServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateTask",
+			Handler:    _TaskService_CreateTask_Handler,
+		},
+		{
+			MethodName: "UpdateTask",
+			Handler:    _TaskService_UpdateTask_Handler,
+		},
+		{
+			MethodName: "DeleteTask",
+			Handler:    _TaskService_DeleteTask_Handler,
+		},
+		{
+			MethodName: "GetTask",
+			Handler:    _TaskService_GetTask_Handler,
+		},
+		{
+			MethodName: "GetTasks",
+			Handler:    _TaskService_GetTasks_Handler,
+		},
+		{
+			MethodName: "CountTasks",
+			Handler:    _TaskService_CountTasks_Handler,
+		},
+		{
+			MethodName: "GetCountCandidates",
+			Handler:    _TaskService_GetCountCandidates_Handler,
+		},
+		{
+			MethodName: "CountQueries",
+			Handler:    _TaskService_CountQueries_Handler,
+		},
+		{
+			MethodName: "CreateFieldDefinition",
+			Handler:    _TaskService_CreateFieldDefinition_Handler,
+		},
+		{
+			MethodName: "GetFieldDefinitions",
+			Handler:    _TaskService_GetFieldDefinitions_Handler,
+		},
+		{
+			MethodName: "DeleteFieldDefinition",
+			Handler:    _TaskService_DeleteFieldDefinition_Handler,
+		},
+		{
+			MethodName: "AddChecklistItem",
+			Handler:    _TaskService_AddChecklistItem_Handler,
+		},
+		{
+			MethodName: "CheckChecklistItem",
+			Handler:    _TaskService_CheckChecklistItem_Handler,
+		},
+		{
+			MethodName: "MoveChecklistItem",
+			Handler:    _TaskService_MoveChecklistItem_Handler,
+		},
+		{
+			MethodName: "DeleteChecklistItem",
+			Handler:    _TaskService_DeleteChecklistItem_Handler,
+		},
+		{
+			MethodName: "LinkTasks",
+			Handler:    _TaskService_LinkTasks_Handler,
+		},
+		{
+			MethodName: "UnlinkTasks",
+			Handler:    _TaskService_UnlinkTasks_Handler,
+		},
+		{
+			MethodName: "MergeTasks",
+			Handler:    _TaskService_MergeTasks_Handler,
+		},
+		{
+			MethodName: "CreateSprint",
+			Handler:    _TaskService_CreateSprint_Handler,
+		},
+		{
+			MethodName: "GetSprints",
+			Handler:    _TaskService_GetSprints_Handler,
+		},
+		{
+			MethodName: "StartSprint",
+			Handler:    _TaskService_StartSprint_Handler,
+		},
+		{
+			MethodName: "CloseSprint",
+			Handler:    _TaskService_CloseSprint_Handler,
+		},
+		{
+			MethodName: "PlanTask",
+			Handler:    _TaskService_PlanTask_Handler,
+		},
+		{
+			MethodName: "GetVelocity",
+			Handler:    _TaskService_GetVelocity_Handler,
+		},
+		{
+			MethodName: "GetUserTaskIds",
+			Handler:    _TaskService_GetUserTaskIds_Handler,
+		},
+		{
+			MethodName: "DeleteUserData",
+			Handler:    _TaskService_DeleteUserData_Handler,
+		},
+		{
+			MethodName: "AdminDeleteTask",
+			Handler:    _TaskService_AdminDeleteTask_Handler,
+		},
+		{
+			MethodName: "AdminRestoreTask",
+			Handler:    _TaskService_AdminRestoreTask_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrChecklistItemNotFound = errors.New("checklist item not found")
+
+type checklistItem struct {
+	ID        uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	TaskID    uint
+	Position  int
+	Text      string
+	Done      bool
+}
+
+type ChecklistItem struct {
+	ID       uint
+	Position int
+	Text     string
+	Done     bool
+}
+
+// ChecklistProgress is the number of checked items out of all items.
+type ChecklistProgress struct {
+	Done  int
+	Total int
+}
+
+// AddChecklistItem inserts an item at position, shifting the following
+// items down. A negative or too large position appends the item.
+func (db *DataBase) AddChecklistItem(taskID uint, author, text string, position int) (*ChecklistItem, error) {
+	var added checklistItem
+	err := db.changeChecklist(taskID, author, func(tx *gorm.DB, items []checklistItem) error {
+		if position < 0 || position > len(items) {
+			position = len(items)
+		}
+		added = checklistItem{TaskID: taskID, Position: position, Text: text}
+		if err := shiftPositions(tx, taskID, position, len(items), 1); err != nil {
+			return err
+		}
+		return tx.Create(&added).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added.toChecklistItem(), nil
+}
+
+func (db *DataBase) SetChecklistItemDone(taskID, id uint, author string, done bool) error {
+	return db.changeChecklist(taskID, author, func(tx *gorm.DB, items []checklistItem) error {
+		if _, err := findItem(items, id); err != nil {
+			return err
+		}
+		return tx.Model(&checklistItem{ID: id}).Update("done", done).Error
+	})
+}
+
+// MoveChecklistItem moves an item to position, keeping positions of all
+// items of the task contiguous.
+func (db *DataBase) MoveChecklistItem(taskID, id uint, author string, position int) error {
+	return db.changeChecklist(taskID, author, func(tx *gorm.DB, items []checklistItem) error {
+		from, err := findItem(items, id)
+		if err != nil {
+			return err
+		}
+		if position < 0 || position >= len(items) {
+			position = len(items) - 1
+		}
+		switch {
+		case position < from:
+			err = shiftPositions(tx, taskID, position, from, 1)
+		case position > from:
+			err = shiftPositions(tx, taskID, from+1, position+1, -1)
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&checklistItem{ID: id}).Update("position", position).Error
+	})
+}
+
+func (db *DataBase) DeleteChecklistItem(taskID, id uint, author string) error {
+	return db.changeChecklist(taskID, author, func(tx *gorm.DB, items []checklistItem) error {
+		position, err := findItem(items, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&checklistItem{ID: id}).Error; err != nil {
+			return err
+		}
+		return shiftPositions(tx, taskID, position+1, len(items), -1)
+	})
+}
+
+func (db *DataBase) GetChecklist(taskID uint) ([]ChecklistItem, error) {
+	var items []checklistItem
+	if err := db.Where("task_id = ?", taskID).Order("position").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	checklist := make([]ChecklistItem, 0, len(items))
+	for _, item := range items {
+		checklist = append(checklist, *item.toChecklistItem())
+	}
+	return checklist, nil
+}
+
+func (db *DataBase) checklistProgress(taskIDs []uint) (map[uint]ChecklistProgress, error) {
+	result := make(map[uint]ChecklistProgress)
+	if len(taskIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		TaskID uint
+		ChecklistProgress
+	}
+	err := db.Model(&checklistItem{}).
+		Select("task_id, COUNT(*) FILTER (WHERE done) AS done, COUNT(*) AS total").
+		Where("task_id IN ?", taskIDs).
+		Group("task_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result[row.TaskID] = row.ChecklistProgress
+	}
+	return result, nil
+}
+
+// changeChecklist runs change in a transaction with the task row locked, so
+// concurrent changes see consistent positions. The task update time is
+// bumped because a checklist change is a task modification.
+func (db *DataBase) changeChecklist(taskID uint, author string, change func(tx *gorm.DB, items []checklistItem) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var info taskInfo
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&info, taskID)
+		if result.Error == gorm.ErrRecordNotFound {
+			return ErrNotFound
+		} else if result.Error != nil {
+			return result.Error
+		}
+		if info.Author != author {
+			return ErrPermissionDenied
+		}
+
+		var items []checklistItem
+		if err := tx.Where("task_id = ?", taskID).Order("position").Find(&items).Error; err != nil {
+			return err
+		}
+		if err := change(tx, items); err != nil {
+			return err
+		}
+		return tx.Model(&info).Update("updated_at", time.Now()).Error
+	})
+}
+
+// shiftPositions adds delta to positions in [from, to) of the task items.
+func shiftPositions(tx *gorm.DB, taskID uint, from, to, delta int) error {
+	if from >= to {
+		return nil
+	}
+	return tx.Model(&checklistItem{}).
+		Where("task_id = ? AND position >= ? AND position < ?", taskID, from, to).
+		Update("position", gorm.Expr("position + ?", delta)).Error
+}
+
+func findItem(items []checklistItem, id uint) (int, error) {
+	for i, item := range items {
+		if item.ID == id {
+			return i, nil
+		}
+	}
+	return 0, ErrChecklistItemNotFound
+}
+
+func (item checklistItem) toChecklistItem() *ChecklistItem {
+	return &ChecklistItem{
+		ID:       item.ID,
+		Position: item.Position,
+		Text:     item.Text,
+		Done:     item.Done,
+	}
+}
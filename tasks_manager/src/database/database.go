@@ -1,14 +1,37 @@
 package database
 
 import (
+	"context"
+	"embed"
 	"errors"
+	"io/fs"
+	"tasksmanager/src/migrate"
 	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-var ErrPermissionDenied = errors.New("permission denied")
+var (
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrNotFound         = errors.New("task not found")
+	ErrNotDeleted       = errors.New("task is not deleted")
+)
+
+const (
+	StatusOpen       = "open"
+	StatusInProgress = "in_progress"
+	StatusClosed     = "closed"
+)
+
+var TaskStatuses = []string{StatusOpen, StatusInProgress, StatusClosed}
+
+// Advisory lock key ("task" in ASCII) taken while migrating.
+const migrationLockID = 0x7461736b
+
+//go:embed migrations/*.sql
+var migrations embed.FS
 
 type DataBase struct {
 	*gorm.DB
@@ -16,9 +39,15 @@ type DataBase struct {
 
 type taskInfo struct {
 	gorm.Model
-	Author  string
-	Title   string
-	Content string
+	Author       string
+	Title        string
+	Content      string
+	Project      string
+	Status       string
+	MergedInto   *uint
+	Estimate     *float64
+	EstimateUnit string
+	SprintID     *uint
 }
 
 type UserData struct {
@@ -34,19 +63,60 @@ type TaskData struct {
 	Author       string
 	Title        string
 	Content      string
+	Project      string
+	Status       string
 	CreationTime time.Time
+	UpdateTime   time.Time
+	Fields       map[string]string
+	Checklist    []ChecklistItem
+	Progress     ChecklistProgress
+	Links        []TaskLink
+	MergedInto   uint
+	// Estimate is nil if the task is not estimated. On update nil keeps
+	// the estimate and zero clears it.
+	Estimate     *float64
+	EstimateUnit string
+	SprintID     uint
 }
 
 func (ti taskInfo) toTaskData() TaskData {
+	var mergedInto, sprintID uint
+	if ti.MergedInto != nil {
+		mergedInto = *ti.MergedInto
+	}
+	if ti.SprintID != nil {
+		sprintID = *ti.SprintID
+	}
 	return TaskData{
 		ID:           ti.ID,
 		Author:       ti.Author,
 		Title:        ti.Title,
 		Content:      ti.Content,
+		Project:      ti.Project,
+		Status:       ti.Status,
 		CreationTime: ti.Model.CreatedAt,
+		UpdateTime:   ti.Model.UpdatedAt,
+		MergedInto:   mergedInto,
+		Estimate:     ti.Estimate,
+		EstimateUnit: ti.EstimateUnit,
+		SprintID:     sprintID,
 	}
 }
 
+// Clause is a raw SQL fragment with its arguments used to filter or order
+// tasks. Column names refer to the task_infos table.
+type Clause struct {
+	SQL  string
+	Args []any
+}
+
+type TaskFilter struct {
+	Author  string
+	Project string
+	Where   []Clause
+	Order   []Clause
+}
+
 func New() *DataBase {
 	dsn := "host=task_db dbname=task_db sslmode=disable user=user password=password"
 
@@ -55,21 +125,57 @@ func New() *DataBase {
 		panic("failed to connect tasks database: " + err.Error())
 	}
 
-	db.AutoMigrate(&taskInfo{})
-
 	return &DataBase{db}
 }
 
-func (db *DataBase) CreateTask(data *TaskData) (uint32, error) {
-	info := &taskInfo{Author: data.Author, Title: data.Title, Content: data.Content}
-	result := db.Create(info)
-	return uint32(info.ID), result.Error
+// WithContext binds queries to ctx, so they stop when the call is
+// cancelled or its deadline passes.
+func (db *DataBase) WithContext(ctx context.Context) *DataBase {
+	return &DataBase{db.DB.WithContext(ctx)}
+}
+
+func (db *DataBase) SchemaMigrator() (*migrate.Migrator, error) {
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, fsys, migrationLockID)
+}
+
+func (db *DataBase) CreateTask(data *TaskData, values []FieldValue) (uint32, error) {
+	info := &taskInfo{
+		Author:       data.Author,
+		Title:        data.Title,
+		Content:      data.Content,
+		Project:      data.Project,
+		Status:       StatusOpen,
+		EstimateUnit: data.EstimateUnit,
+	}
+	if data.Estimate != nil && *data.Estimate != 0 {
+		info.Estimate = data.Estimate
+	}
+	if info.EstimateUnit == "" {
+		info.EstimateUnit = EstimatePoints
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(info).Error; err != nil {
+			return err
+		}
+		return setFieldValues(tx, info.ID, values, nil)
+	})
+	return uint32(info.ID), err
 }
 
 func (db *DataBase) CheckTaskPermission(id uint, author string) error {
 	info := &taskInfo{Model: gorm.Model{ID: id}}
 	result := db.First(&info, "ID = ?", id)
-	if result.Error != nil {
+	if result.Error == gorm.ErrRecordNotFound {
+		return ErrNotFound
+	} else if result.Error != nil {
 		return result.Error
 	}
 	if info.Author != author {
@@ -81,28 +187,60 @@ func (db *DataBase) CheckTaskPermission(id uint, author string) error {
 func (db *DataBase) GetTaskData(id uint, author string) (*TaskData, error) {
 	info := &taskInfo{Model: gorm.Model{ID: id}}
 	result := db.First(&info, "ID = ?", id)
-	if result.Error != nil {
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, ErrNotFound
+	} else if result.Error != nil {
 		return nil, result.Error
 	}
 	data := info.toTaskData()
+	fields, err := db.fieldValues([]uint{id})
+	if err != nil {
+		return nil, err
+	}
+	data.Fields = fields[id]
+	data.Checklist, err = db.GetChecklist(id)
+	if err != nil {
+		return nil, err
+	}
+	data.Links, err = db.GetTaskLinks(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range data.Checklist {
+		data.Progress.Total++
+		if item.Done {
+			data.Progress.Done++
+		}
+	}
 	return &data, nil
 }
 
-func (db *DataBase) UpdateTaskData(data *TaskData) error {
+func (db *DataBase) UpdateTaskData(data *TaskData, values []FieldValue, cleared []uint) error {
 	if err := db.CheckTaskPermission(data.ID, data.Author); err != nil {
 		return err
 	}
-	result := db.Model(&taskInfo{}).Where("ID = ?", data.ID).Updates(taskInfo{
-		Title:   data.Title,
-		Content: data.Content,
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&taskInfo{}).Where("ID = ?", data.ID).Updates(taskInfo{
+			Title:        data.Title,
+			Content:      data.Content,
+			Status:       data.Status,
+			EstimateUnit: data.EstimateUnit,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if data.Estimate != nil {
+			var estimate any
+			if *data.Estimate != 0 {
+				estimate = *data.Estimate
+			}
+			result = tx.Model(&taskInfo{}).Where("ID = ?", data.ID).Update("estimate", estimate)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return setFieldValues(tx, data.ID, values, cleared)
 	})
-	if result.Error != nil {
-		return result.Error
-	}
-	return db.Model(&taskInfo{}).Where("ID = ?", data.ID).Updates(taskInfo{
-		Title:   data.Title,
-		Content: data.Content,
-	}).Error
 }
 
 func (db *DataBase) DeleteTask(id uint, author string) error {
@@ -112,12 +250,98 @@ func (db *DataBase) DeleteTask(id uint, author string) error {
 	return db.Delete(&taskInfo{}, id).Error
 }
 
-func (db *DataBase) GetTasks(offset, batchSize int) ([]TaskData, error) {
+// AdminDeleteTask deletes a task of any author.
+func (db *DataBase) AdminDeleteTask(id uint) error {
+	result := db.Delete(&taskInfo{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RestoreTask brings back a deleted task with its fields, checklist and
+// links, which are kept while the task is deleted.
+func (db *DataBase) RestoreTask(id uint) error {
+	result := db.Unscoped().Model(&taskInfo{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	if err := db.taskExists(id); err != nil {
+		return err
+	}
+	return ErrNotDeleted
+}
+
+func (db *DataBase) filterTasks(filter TaskFilter) *gorm.DB {
+	query := db.Model(&taskInfo{})
+	if filter.Author != "" {
+		query = query.Where("author = ?", filter.Author)
+	}
+	if filter.Project != "" {
+		query = query.Where("project = ?", filter.Project)
+	}
+	for _, where := range filter.Where {
+		query = query.Where(where.SQL, where.Args...)
+	}
+	return query
+}
+
+func (db *DataBase) CountTasks(filter TaskFilter) (int64, error) {
+	var count int64
+	result := db.filterTasks(filter).Count(&count)
+	return count, result.Error
+}
+
+// TaskIDs returns IDs of all tasks matching the filter. The order is
+// ignored.
+func (db *DataBase) TaskIDs(filter TaskFilter) ([]uint32, error) {
+	var ids []uint32
+	result := db.filterTasks(filter).Pluck("id", &ids)
+	return ids, result.Error
+}
+
+func (db *DataBase) GetTasks(offset, batchSize int, filter TaskFilter) ([]TaskData, error) {
+	query := db.filterTasks(filter)
+	order := clause.Expr{WithoutParentheses: true}
+	for _, by := range filter.Order {
+		order.SQL += by.SQL + ", "
+		order.Vars = append(order.Vars, by.Args...)
+	}
+	order.SQL += "id"
+
 	var tasks []taskInfo
-	result := db.Limit(batchSize).Offset(offset).Find(&tasks)
+	result := query.Clauses(clause.OrderBy{Expression: order}).Limit(batchSize).Offset(offset).Find(&tasks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	ids := make([]uint, 0, len(tasks))
+	for _, info := range tasks {
+		ids = append(ids, info.ID)
+	}
+	fields, err := db.fieldValues(ids)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := db.checklistProgress(ids)
+	if err != nil {
+		return nil, err
+	}
+
 	data := make([]TaskData, 0, len(tasks))
 	for _, info := range tasks {
-		data = append(data, info.toTaskData())
+		task := info.toTaskData()
+		task.Fields = fields[info.ID]
+		task.Progress = progress[info.ID]
+		data = append(data, task)
 	}
-	return data, result.Error
+	return data, nil
 }
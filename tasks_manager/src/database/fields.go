@@ -0,0 +1,254 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	FieldText   = "text"
+	FieldNumber = "number"
+	FieldDate   = "date"
+	FieldEnum   = "enum"
+	FieldUser   = "user"
+
+	DateLayout = "2006-01-02"
+)
+
+var (
+	ErrFieldExists   = errors.New("field with this name already exists")
+	ErrFieldNotFound = errors.New("field not found")
+)
+
+type fieldDefinition struct {
+	gorm.Model
+	Author  string
+	Project string
+	Name    string
+	Type    string
+	Options []string `gorm:"serializer:json"`
+}
+
+type taskFieldValue struct {
+	TaskID      uint `gorm:"primaryKey"`
+	FieldID     uint `gorm:"primaryKey"`
+	TextValue   *string
+	NumberValue *float64
+	DateValue   *time.Time
+}
+
+type FieldDefinition struct {
+	ID      uint
+	Author  string
+	Project string
+	Name    string
+	Type    string
+	Options []string
+}
+
+// FieldValue is a typed value of a custom field. Exactly one of the value
+// pointers is set, depending on the field type.
+type FieldValue struct {
+	FieldID uint
+	Text    *string
+	Number  *float64
+	Date    *time.Time
+}
+
+func (fd fieldDefinition) toFieldDefinition() FieldDefinition {
+	return FieldDefinition{
+		ID:      fd.ID,
+		Author:  fd.Author,
+		Project: fd.Project,
+		Name:    fd.Name,
+		Type:    fd.Type,
+		Options: fd.Options,
+	}
+}
+
+func toFieldDefinitions(infos []fieldDefinition) []FieldDefinition {
+	defs := make([]FieldDefinition, 0, len(infos))
+	for _, info := range infos {
+		defs = append(defs, info.toFieldDefinition())
+	}
+	return defs
+}
+
+// ValueColumn returns the task_field_values column that stores values of
+// the given field type.
+func ValueColumn(fieldType string) string {
+	switch fieldType {
+	case FieldNumber:
+		return "number_value"
+	case FieldDate:
+		return "date_value"
+	}
+	return "text_value"
+}
+
+// FieldValueClause matches tasks which have a value of one of the fields
+// compared with value by op. The "!=" operator also matches tasks without
+// the field.
+func FieldValueClause(fieldIDs []uint, column, op string, value any) Clause {
+	if op == "!=" {
+		return Clause{
+			SQL:  fmt.Sprintf("NOT EXISTS (SELECT 1 FROM task_field_values v WHERE v.task_id = task_infos.id AND v.field_id IN ? AND v.%s = ?)", column),
+			Args: []any{fieldIDs, value},
+		}
+	}
+	return Clause{
+		SQL:  fmt.Sprintf("EXISTS (SELECT 1 FROM task_field_values v WHERE v.task_id = task_infos.id AND v.field_id IN ? AND v.%s %s ?)", column, op),
+		Args: []any{fieldIDs, value},
+	}
+}
+
+// FieldValueExpr selects the task's value of one of the fields.
+func FieldValueExpr(fieldIDs []uint, column string) Clause {
+	return Clause{
+		SQL:  fmt.Sprintf("(SELECT v.%s FROM task_field_values v WHERE v.task_id = task_infos.id AND v.field_id IN ? LIMIT 1)", column),
+		Args: []any{fieldIDs},
+	}
+}
+
+// FieldOrderClause orders tasks by the value of one of the fields. Tasks
+// without the field go last.
+func FieldOrderClause(fieldIDs []uint, column string, desc bool) Clause {
+	clause := FieldValueExpr(fieldIDs, column)
+	if desc {
+		clause.SQL += " DESC NULLS LAST"
+	} else {
+		clause.SQL += " ASC NULLS LAST"
+	}
+	return clause
+}
+
+func (db *DataBase) CreateFieldDefinition(def *FieldDefinition) (uint, error) {
+	var count int64
+	result := db.Model(&fieldDefinition{}).
+		Where("name = ? AND (project = ? OR project = '' OR ? = '')", def.Name, def.Project, def.Project).
+		Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if count > 0 {
+		return 0, ErrFieldExists
+	}
+
+	info := &fieldDefinition{
+		Author:  def.Author,
+		Project: def.Project,
+		Name:    def.Name,
+		Type:    def.Type,
+		Options: def.Options,
+	}
+	result = db.Create(info)
+	return info.ID, result.Error
+}
+
+// GetFieldDefinitions returns fields that apply to tasks of the project:
+// the project's own fields and the workspace-wide ones.
+func (db *DataBase) GetFieldDefinitions(project string) ([]FieldDefinition, error) {
+	var infos []fieldDefinition
+	result := db.Where("project = ? OR project = ''", project).Order("id").Find(&infos)
+	return toFieldDefinitions(infos), result.Error
+}
+
+func (db *DataBase) FieldDefinitionsByName(name string) ([]FieldDefinition, error) {
+	var infos []fieldDefinition
+	result := db.Where("name = ?", name).Order("id").Find(&infos)
+	return toFieldDefinitions(infos), result.Error
+}
+
+func (db *DataBase) DeleteFieldDefinition(id uint, author string) error {
+	var info fieldDefinition
+	result := db.First(&info, "id = ?", id)
+	if result.Error == gorm.ErrRecordNotFound {
+		return ErrFieldNotFound
+	} else if result.Error != nil {
+		return result.Error
+	}
+	if info.Author != author {
+		return ErrPermissionDenied
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("field_id = ?", id).Delete(&taskFieldValue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&fieldDefinition{}, id).Error
+	})
+}
+
+func setFieldValues(tx *gorm.DB, taskID uint, values []FieldValue, cleared []uint) error {
+	if len(cleared) > 0 {
+		result := tx.Where("task_id = ? AND field_id IN ?", taskID, cleared).Delete(&taskFieldValue{})
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	rows := make([]taskFieldValue, 0, len(values))
+	for _, value := range values {
+		rows = append(rows, taskFieldValue{
+			TaskID:      taskID,
+			FieldID:     value.FieldID,
+			TextValue:   value.Text,
+			NumberValue: value.Number,
+			DateValue:   value.Date,
+		})
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "task_id"}, {Name: "field_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"text_value", "number_value", "date_value"}),
+	}).Create(&rows).Error
+}
+
+// fieldValues returns values of custom fields by task ID and field name,
+// formatted as strings.
+func (db *DataBase) fieldValues(taskIDs []uint) (map[uint]map[string]string, error) {
+	result := make(map[uint]map[string]string)
+	if len(taskIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		taskFieldValue
+		Name string
+	}
+	err := db.Table("task_field_values v").
+		Select("v.*, f.name").
+		Joins("JOIN field_definitions f ON f.id = v.field_id AND f.deleted_at IS NULL").
+		Where("v.task_id IN ?", taskIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if result[row.TaskID] == nil {
+			result[row.TaskID] = make(map[string]string)
+		}
+		result[row.TaskID][row.Name] = formatValue(row.taskFieldValue)
+	}
+	return result, nil
+}
+
+func formatValue(value taskFieldValue) string {
+	switch {
+	case value.NumberValue != nil:
+		return strconv.FormatFloat(*value.NumberValue, 'f', -1, 64)
+	case value.DateValue != nil:
+		return value.DateValue.Format(DateLayout)
+	case value.TextValue != nil:
+		return *value.TextValue
+	}
+	return ""
+}
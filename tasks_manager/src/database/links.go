@@ -0,0 +1,166 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	LinkRelatesTo  = "relates_to"
+	LinkDuplicates = "duplicates"
+	LinkCausedBy   = "caused_by"
+)
+
+var LinkTypes = []string{LinkRelatesTo, LinkDuplicates, LinkCausedBy}
+
+var (
+	ErrLinkExists    = errors.New("link already exists")
+	ErrLinkNotFound  = errors.New("link not found")
+	ErrAlreadyMerged = errors.New("task is already merged into another task")
+)
+
+type taskLink struct {
+	ID        uint
+	CreatedAt time.Time
+	Author    string
+	Type      string
+	SourceID  uint
+	TargetID  uint
+}
+
+// TaskLink reads as "source <type> target", e.g. 5 duplicates 3. relates_to
+// is symmetric and is stored with the smaller ID as the source.
+type TaskLink struct {
+	ID       uint
+	Type     string
+	SourceID uint
+	TargetID uint
+}
+
+func (link taskLink) toTaskLink() TaskLink {
+	return TaskLink{
+		ID:       link.ID,
+		Type:     link.Type,
+		SourceID: link.SourceID,
+		TargetID: link.TargetID,
+	}
+}
+
+// LinkTasks links source to target. The author must own the source task.
+func (db *DataBase) LinkTasks(author, linkType string, source, target uint) (*TaskLink, error) {
+	if err := db.CheckTaskPermission(source, author); err != nil {
+		return nil, err
+	}
+	if err := db.taskExists(target); err != nil {
+		return nil, err
+	}
+	if linkType == LinkRelatesTo && source > target {
+		source, target = target, source
+	}
+
+	link := &taskLink{Author: author, Type: linkType, SourceID: source, TargetID: target}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(link)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrLinkExists
+	}
+	taskLink := link.toTaskLink()
+	return &taskLink, nil
+}
+
+// UnlinkTasks removes a link. The author must own one of the linked tasks.
+func (db *DataBase) UnlinkTasks(author string, id uint) error {
+	var link taskLink
+	result := db.First(&link, id)
+	if result.Error == gorm.ErrRecordNotFound {
+		return ErrLinkNotFound
+	} else if result.Error != nil {
+		return result.Error
+	}
+
+	if err := db.CheckTaskPermission(link.SourceID, author); err != nil {
+		if err := db.CheckTaskPermission(link.TargetID, author); err != nil {
+			return err
+		}
+	}
+	return db.Delete(&link).Error
+}
+
+// MergeTasks closes the duplicate task, links it to the canonical one and
+// marks it merged. Tasks merged into the duplicate earlier are moved to the
+// canonical task, so merges never chain. Merging again into the same task
+// is a no-op.
+func (db *DataBase) MergeTasks(author string, duplicate, canonical uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var dup taskInfo
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dup, duplicate)
+		if result.Error == gorm.ErrRecordNotFound {
+			return ErrNotFound
+		} else if result.Error != nil {
+			return result.Error
+		}
+		if dup.Author != author {
+			return ErrPermissionDenied
+		}
+		if dup.MergedInto != nil {
+			if *dup.MergedInto == canonical {
+				return nil
+			}
+			return ErrAlreadyMerged
+		}
+
+		var target taskInfo
+		result = tx.First(&target, canonical)
+		if result.Error == gorm.ErrRecordNotFound {
+			return ErrNotFound
+		} else if result.Error != nil {
+			return result.Error
+		}
+		if target.MergedInto != nil {
+			return ErrAlreadyMerged
+		}
+
+		result = tx.Model(&dup).Updates(map[string]any{
+			"merged_into": canonical,
+			"status":      StatusClosed,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		result = tx.Model(&taskInfo{}).Where("merged_into = ?", duplicate).Update("merged_into", canonical)
+		if result.Error != nil {
+			return result.Error
+		}
+		link := &taskLink{Author: author, Type: LinkDuplicates, SourceID: duplicate, TargetID: canonical}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(link).Error
+	})
+}
+
+func (db *DataBase) GetTaskLinks(taskID uint) ([]TaskLink, error) {
+	var links []taskLink
+	result := db.Where("source_id = ? OR target_id = ?", taskID, taskID).Order("id").Find(&links)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	taskLinks := make([]TaskLink, 0, len(links))
+	for _, link := range links {
+		taskLinks = append(taskLinks, link.toTaskLink())
+	}
+	return taskLinks, nil
+}
+
+func (db *DataBase) taskExists(id uint) error {
+	var count int64
+	if err := db.Model(&taskInfo{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
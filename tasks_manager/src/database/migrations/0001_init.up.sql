@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS task_infos (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    author     TEXT,
+    title      TEXT,
+    content    TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_infos_deleted_at ON task_infos (deleted_at);
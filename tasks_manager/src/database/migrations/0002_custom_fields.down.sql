@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS task_field_values;
+DROP TABLE IF EXISTS field_definitions;
+DROP INDEX IF EXISTS idx_task_infos_project;
+ALTER TABLE task_infos DROP COLUMN IF EXISTS project;
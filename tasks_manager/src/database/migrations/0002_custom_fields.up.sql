@@ -0,0 +1,31 @@
+ALTER TABLE task_infos ADD COLUMN IF NOT EXISTS project TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_task_infos_project ON task_infos (project);
+
+CREATE TABLE field_definitions (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    author     TEXT NOT NULL,
+    project    TEXT NOT NULL DEFAULT '',
+    name       TEXT NOT NULL,
+    type       TEXT NOT NULL,
+    options    TEXT
+);
+
+CREATE INDEX idx_field_definitions_deleted_at ON field_definitions (deleted_at);
+CREATE UNIQUE INDEX idx_field_definitions_project_name ON field_definitions (project, name) WHERE deleted_at IS NULL;
+
+CREATE TABLE task_field_values (
+    task_id      BIGINT NOT NULL REFERENCES task_infos (id) ON DELETE CASCADE,
+    field_id     BIGINT NOT NULL REFERENCES field_definitions (id) ON DELETE CASCADE,
+    text_value   TEXT,
+    number_value DOUBLE PRECISION,
+    date_value   DATE,
+    PRIMARY KEY (task_id, field_id)
+);
+
+CREATE INDEX idx_task_field_values_text ON task_field_values (field_id, text_value);
+CREATE INDEX idx_task_field_values_number ON task_field_values (field_id, number_value);
+CREATE INDEX idx_task_field_values_date ON task_field_values (field_id, date_value);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_task_infos_status;
+ALTER TABLE task_infos DROP COLUMN IF EXISTS status;
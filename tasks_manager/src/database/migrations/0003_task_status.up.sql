@@ -0,0 +1,3 @@
+ALTER TABLE task_infos ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'open';
+
+CREATE INDEX IF NOT EXISTS idx_task_infos_status ON task_infos (status);
@@ -0,0 +1,11 @@
+CREATE TABLE checklist_items (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    task_id    BIGINT NOT NULL REFERENCES task_infos (id) ON DELETE CASCADE,
+    position   INTEGER NOT NULL,
+    text       TEXT NOT NULL,
+    done       BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX idx_checklist_items_task_position ON checklist_items (task_id, position);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS task_links;
+ALTER TABLE task_infos DROP COLUMN IF EXISTS merged_into;
@@ -0,0 +1,14 @@
+ALTER TABLE task_infos ADD COLUMN IF NOT EXISTS merged_into BIGINT REFERENCES task_infos (id);
+
+CREATE TABLE task_links (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    author     TEXT NOT NULL,
+    type       TEXT NOT NULL,
+    source_id  BIGINT NOT NULL REFERENCES task_infos (id) ON DELETE CASCADE,
+    target_id  BIGINT NOT NULL REFERENCES task_infos (id) ON DELETE CASCADE,
+    CHECK (source_id <> target_id)
+);
+
+CREATE UNIQUE INDEX idx_task_links_source_target_type ON task_links (source_id, target_id, type);
+CREATE INDEX idx_task_links_target ON task_links (target_id);
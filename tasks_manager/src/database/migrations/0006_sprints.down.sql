@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_task_infos_sprint_id;
+ALTER TABLE task_infos DROP COLUMN IF EXISTS sprint_id;
+ALTER TABLE task_infos DROP COLUMN IF EXISTS estimate_unit;
+ALTER TABLE task_infos DROP COLUMN IF EXISTS estimate;
+DROP TABLE IF EXISTS sprints;
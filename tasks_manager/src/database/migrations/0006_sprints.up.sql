@@ -0,0 +1,27 @@
+CREATE TABLE sprints (
+    id               BIGSERIAL PRIMARY KEY,
+    created_at       TIMESTAMPTZ,
+    updated_at       TIMESTAMPTZ,
+    author           TEXT NOT NULL,
+    project          TEXT NOT NULL DEFAULT '',
+    name             TEXT NOT NULL,
+    state            TEXT NOT NULL DEFAULT 'planned',
+    start_date       DATE NOT NULL,
+    end_date         DATE NOT NULL,
+    started_at       TIMESTAMPTZ,
+    closed_at        TIMESTAMPTZ,
+    committed_points DOUBLE PRECISION NOT NULL DEFAULT 0,
+    committed_hours  DOUBLE PRECISION NOT NULL DEFAULT 0,
+    completed_points DOUBLE PRECISION NOT NULL DEFAULT 0,
+    completed_hours  DOUBLE PRECISION NOT NULL DEFAULT 0,
+    CHECK (end_date > start_date)
+);
+
+CREATE INDEX idx_sprints_project ON sprints (project, start_date);
+CREATE UNIQUE INDEX idx_sprints_active_project ON sprints (project) WHERE state = 'active';
+
+ALTER TABLE task_infos ADD COLUMN IF NOT EXISTS estimate DOUBLE PRECISION;
+ALTER TABLE task_infos ADD COLUMN IF NOT EXISTS estimate_unit TEXT NOT NULL DEFAULT 'points';
+ALTER TABLE task_infos ADD COLUMN IF NOT EXISTS sprint_id BIGINT REFERENCES sprints (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_task_infos_sprint_id ON task_infos (sprint_id);
@@ -0,0 +1,297 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	EstimatePoints = "points"
+	EstimateHours  = "hours"
+
+	SprintPlanned = "planned"
+	SprintActive  = "active"
+	SprintClosed  = "closed"
+)
+
+var EstimateUnits = []string{EstimatePoints, EstimateHours}
+
+var (
+	ErrSprintNotFound = errors.New("sprint not found")
+	ErrSprintState    = errors.New("wrong sprint state")
+)
+
+type sprint struct {
+	ID              uint
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Author          string
+	Project         string
+	Name            string
+	State           string
+	StartDate       time.Time
+	EndDate         time.Time
+	StartedAt       *time.Time
+	ClosedAt        *time.Time
+	CommittedPoints float64
+	CommittedHours  float64
+	CompletedPoints float64
+	CompletedHours  float64
+}
+
+// Sprint is a time box tasks of a project are planned into. Committed
+// estimates are fixed when the sprint starts, completed ones when it closes.
+type Sprint struct {
+	ID              uint
+	Author          string
+	Project         string
+	Name            string
+	State           string
+	StartDate       time.Time
+	EndDate         time.Time
+	StartedAt       *time.Time
+	ClosedAt        *time.Time
+	CommittedPoints float64
+	CommittedHours  float64
+	CompletedPoints float64
+	CompletedHours  float64
+}
+
+func (s sprint) toSprint() Sprint {
+	return Sprint{
+		ID:              s.ID,
+		Author:          s.Author,
+		Project:         s.Project,
+		Name:            s.Name,
+		State:           s.State,
+		StartDate:       s.StartDate,
+		EndDate:         s.EndDate,
+		StartedAt:       s.StartedAt,
+		ClosedAt:        s.ClosedAt,
+		CommittedPoints: s.CommittedPoints,
+		CommittedHours:  s.CommittedHours,
+		CompletedPoints: s.CompletedPoints,
+		CompletedHours:  s.CompletedHours,
+	}
+}
+
+func (db *DataBase) CreateSprint(data *Sprint) (*Sprint, error) {
+	info := &sprint{
+		Author:    data.Author,
+		Project:   data.Project,
+		Name:      data.Name,
+		State:     SprintPlanned,
+		StartDate: data.StartDate,
+		EndDate:   data.EndDate,
+	}
+	if err := db.Create(info).Error; err != nil {
+		return nil, err
+	}
+	created := info.toSprint()
+	return &created, nil
+}
+
+func (db *DataBase) GetSprint(id uint) (*Sprint, error) {
+	info, err := getSprint(db.DB, id, false)
+	if err != nil {
+		return nil, err
+	}
+	data := info.toSprint()
+	return &data, nil
+}
+
+func (db *DataBase) GetSprints(project string) ([]Sprint, error) {
+	var infos []sprint
+	if err := db.Where("project = ?", project).Order("start_date, id").Find(&infos).Error; err != nil {
+		return nil, err
+	}
+	sprints := make([]Sprint, 0, len(infos))
+	for _, info := range infos {
+		sprints = append(sprints, info.toSprint())
+	}
+	return sprints, nil
+}
+
+// GetClosedSprints returns up to limit most recently closed sprints of the
+// project, latest first.
+func (db *DataBase) GetClosedSprints(project string, limit int) ([]Sprint, error) {
+	var infos []sprint
+	result := db.Where("project = ? AND state = ?", project, SprintClosed).
+		Order("closed_at DESC, id DESC").
+		Limit(limit).
+		Find(&infos)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	sprints := make([]Sprint, 0, len(infos))
+	for _, info := range infos {
+		sprints = append(sprints, info.toSprint())
+	}
+	return sprints, nil
+}
+
+// StartSprint activates a planned sprint and fixes the estimates committed
+// to it. A project has at most one active sprint.
+func (db *DataBase) StartSprint(id uint, author string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		info, err := getSprint(tx, id, true)
+		if err != nil {
+			return err
+		}
+		if info.Author != author {
+			return ErrPermissionDenied
+		}
+		if info.State != SprintPlanned {
+			return fmt.Errorf("%w: sprint is %s", ErrSprintState, info.State)
+		}
+
+		var active int64
+		result := tx.Model(&sprint{}).Where("project = ? AND state = ?", info.Project, SprintActive).Count(&active)
+		if result.Error != nil {
+			return result.Error
+		}
+		if active > 0 {
+			return fmt.Errorf("%w: project already has an active sprint", ErrSprintState)
+		}
+
+		points, hours, err := sumEstimates(tx, id, false)
+		if err != nil {
+			return err
+		}
+		return tx.Model(info).Updates(map[string]any{
+			"state":            SprintActive,
+			"started_at":       time.Now(),
+			"committed_points": points,
+			"committed_hours":  hours,
+		}).Error
+	})
+}
+
+// CloseSprint closes an active sprint, fixes the completed estimates and
+// moves unfinished tasks to the next sprint, or to the backlog if next is
+// zero. It returns the number of moved tasks.
+func (db *DataBase) CloseSprint(id uint, author string, next uint) (int64, error) {
+	var moved int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		info, err := getSprint(tx, id, true)
+		if err != nil {
+			return err
+		}
+		if info.Author != author {
+			return ErrPermissionDenied
+		}
+		if info.State != SprintActive {
+			return fmt.Errorf("%w: sprint is %s", ErrSprintState, info.State)
+		}
+
+		var nextID any
+		if next != 0 {
+			nextInfo, err := getSprint(tx, next, false)
+			if err != nil {
+				return err
+			}
+			if nextInfo.Project != info.Project || nextInfo.State != SprintPlanned {
+				return fmt.Errorf("%w: next sprint must be a planned sprint of the same project", ErrSprintState)
+			}
+			nextID = next
+		}
+
+		points, hours, err := sumEstimates(tx, id, true)
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&taskInfo{}).
+			Where("sprint_id = ? AND status <> ?", id, StatusClosed).
+			Update("sprint_id", nextID)
+		if result.Error != nil {
+			return result.Error
+		}
+		moved = result.RowsAffected
+
+		return tx.Model(info).Updates(map[string]any{
+			"state":            SprintClosed,
+			"closed_at":        time.Now(),
+			"completed_points": points,
+			"completed_hours":  hours,
+		}).Error
+	})
+	return moved, err
+}
+
+// PlanTask puts a task into a sprint of its project. Zero sprint moves the
+// task to the backlog.
+func (db *DataBase) PlanTask(taskID uint, author string, sprintID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var task taskInfo
+		result := tx.First(&task, taskID)
+		if result.Error == gorm.ErrRecordNotFound {
+			return ErrNotFound
+		} else if result.Error != nil {
+			return result.Error
+		}
+		if task.Author != author {
+			return ErrPermissionDenied
+		}
+
+		var value any
+		if sprintID != 0 {
+			info, err := getSprint(tx, sprintID, false)
+			if err != nil {
+				return err
+			}
+			if info.State == SprintClosed {
+				return fmt.Errorf("%w: sprint is closed", ErrSprintState)
+			}
+			if info.Project != task.Project {
+				return fmt.Errorf("%w: sprint belongs to another project", ErrSprintState)
+			}
+			value = sprintID
+		}
+		return tx.Model(&task).Update("sprint_id", value).Error
+	})
+}
+
+func getSprint(tx *gorm.DB, id uint, lock bool) (*sprint, error) {
+	if lock {
+		tx = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var info sprint
+	result := tx.First(&info, id)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, ErrSprintNotFound
+	}
+	return &info, result.Error
+}
+
+// sumEstimates sums estimates of the sprint tasks by unit. With done set
+// only closed tasks are counted.
+func sumEstimates(tx *gorm.DB, sprintID uint, done bool) (points, hours float64, err error) {
+	query := tx.Model(&taskInfo{}).
+		Select("estimate_unit AS unit, COALESCE(SUM(estimate), 0) AS total").
+		Where("sprint_id = ?", sprintID).
+		Group("estimate_unit")
+	if done {
+		query = query.Where("status = ?", StatusClosed)
+	}
+
+	var rows []struct {
+		Unit  string
+		Total float64
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return 0, 0, err
+	}
+	for _, row := range rows {
+		switch row.Unit {
+		case EstimatePoints:
+			points = row.Total
+		case EstimateHours:
+			hours = row.Total
+		}
+	}
+	return points, hours, nil
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// GetUserTaskIDs returns IDs of all tasks of the author, deleted ones
+// included.
+func (db *DataBase) GetUserTaskIDs(author string) ([]uint, error) {
+	var ids []uint
+	result := db.Unscoped().Model(&taskInfo{}).Where("author = ?", author).Order("id").Pluck("id", &ids)
+	return ids, result.Error
+}
+
+// DeleteUserData removes tasks of the author for good, together with their
+// field values, checklists and links. Sprints, field definitions and links
+// the author created belong to projects shared with others, so they stay
+// with the author cleared. Values of user fields naming the author are
+// cleared too.
+func (db *DataBase) DeleteUserData(author string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		tasks := tx.Unscoped().Model(&taskInfo{}).Select("id").Where("author = ?", author)
+		err := tx.Unscoped().Model(&taskInfo{}).
+			Where("merged_into IN (?)", tasks).
+			Update("merged_into", nil).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("author = ?", author).Delete(&taskInfo{}).Error; err != nil {
+			return err
+		}
+
+		userFields := tx.Unscoped().Model(&fieldDefinition{}).Select("id").Where("type = ?", FieldUser)
+		err = tx.Where("field_id IN (?) AND text_value = ?", userFields, author).Delete(&taskFieldValue{}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, model := range []any{&fieldDefinition{}, &sprint{}, &taskLink{}} {
+			err := tx.Unscoped().Model(model).Where("author = ?", author).Update("author", "").Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,216 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type Op string
+
+const (
+	OpEqual        Op = ":"
+	OpGreater      Op = ">"
+	OpGreaterEqual Op = ">="
+	OpLess         Op = "<"
+	OpLessEqual    Op = "<="
+)
+
+// Error is a syntax or validation error. Pos is the byte offset of the
+// offending term in the query.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos+1, e.Msg)
+}
+
+func errorf(pos int, format string, args ...any) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Term is either a field comparison like created>2026-01-01 or, when Field
+// is empty, a free text word or "exact phrase".
+type Term struct {
+	Pos     int
+	Negated bool
+	Field   string
+	Op      Op
+	Value   string
+}
+
+type SortKey struct {
+	Pos   int
+	Field string
+	Desc  bool
+}
+
+type Query struct {
+	Terms []Term
+	Sort  []SortKey
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+// Parse parses a query like
+//
+//	author:kek status:open -label:bug created>2026-01-01 "exact phrase" sort:-likes
+//
+// Terms are separated by whitespace and combined with AND. A leading "-"
+// negates a term.
+func Parse(input string) (*Query, error) {
+	p := &parser{input: input}
+	q := &Query{}
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.input) {
+			return q, nil
+		}
+		term, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		if term.Field != "sort" {
+			q.Terms = append(q.Terms, term)
+			continue
+		}
+		if term.Negated {
+			return nil, errorf(term.Pos, "sort can not be negated")
+		}
+		if term.Op != OpEqual {
+			return nil, errorf(term.Pos, "sort expects ':'")
+		}
+		key := SortKey{Pos: term.Pos, Field: term.Value}
+		if strings.HasPrefix(key.Field, "-") {
+			key.Field, key.Desc = key.Field[1:], true
+		}
+		if key.Field == "" {
+			return nil, errorf(term.Pos, "missing sort field")
+		}
+		q.Sort = append(q.Sort, key)
+	}
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+func isIdent(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
+
+func (p *parser) term() (Term, error) {
+	term := Term{Pos: p.pos}
+	if p.peek() == '-' {
+		term.Negated = true
+		p.pos++
+		if p.pos >= len(p.input) || unicode.IsSpace(rune(p.peek())) {
+			return term, errorf(term.Pos, "'-' must be followed by a term")
+		}
+	}
+
+	if p.peek() == '"' {
+		phrase, err := p.quoted()
+		if err != nil {
+			return term, err
+		}
+		term.Value = phrase
+		return term, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && isIdent(p.input[p.pos], p.pos == start) {
+		p.pos++
+	}
+	end := p.pos
+	if op := p.op(); end > start && op != "" {
+		term.Field = strings.ToLower(p.input[start:end])
+		term.Op = op
+		value, err := p.value(term.Field)
+		if err != nil {
+			return term, err
+		}
+		term.Value = value
+		return term, nil
+	}
+
+	p.pos = start
+	word, err := p.word()
+	if err != nil {
+		return term, err
+	}
+	term.Value = word
+	return term, nil
+}
+
+func (p *parser) op() Op {
+	rest := p.input[p.pos:]
+	for _, op := range []Op{OpGreaterEqual, OpLessEqual, OpEqual, OpGreater, OpLess} {
+		if strings.HasPrefix(rest, string(op)) {
+			p.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *parser) value(field string) (string, error) {
+	if p.peek() == '"' {
+		return p.quoted()
+	}
+	if p.pos >= len(p.input) || unicode.IsSpace(rune(p.peek())) {
+		return "", errorf(p.pos, "missing value for %s", field)
+	}
+	return p.word()
+}
+
+func (p *parser) word() (string, error) {
+	start := p.pos
+	for p.pos < len(p.input) && !unicode.IsSpace(rune(p.input[p.pos])) {
+		if p.input[p.pos] == '"' {
+			return "", errorf(p.pos, "unexpected '\"' inside a word")
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos], nil
+}
+
+func (p *parser) quoted() (string, error) {
+	start := p.pos
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.input):
+			b.WriteByte(p.input[p.pos+1])
+			p.pos += 2
+		case c == '"':
+			p.pos++
+			if p.pos < len(p.input) && !unicode.IsSpace(rune(p.peek())) {
+				return "", errorf(p.pos, "expected space after closing '\"'")
+			}
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", errorf(start, "unterminated quote")
+}
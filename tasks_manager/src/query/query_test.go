@@ -0,0 +1,183 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"tasksmanager/src/database"
+	"testing"
+)
+
+type fakeResolver map[string]database.FieldDefinition
+
+func (r fakeResolver) ResolveField(name string) ([]uint, *database.FieldDefinition, error) {
+	def, ok := r[name]
+	if !ok {
+		return nil, nil, nil
+	}
+	return []uint{def.ID}, &def, nil
+}
+
+var resolver = fakeResolver{
+	"label":        {ID: 1, Name: "label", Type: database.FieldEnum, Options: []string{"bug", "feature"}},
+	"story_points": {ID: 2, Name: "story_points", Type: database.FieldNumber},
+}
+
+func TestParse(t *testing.T) {
+	q, err := Parse(`author:kek -status:closed created>=2026-01-01 "exact \"phrase\"" word sort:-likes sort:id`)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	target := []Term{
+		{Pos: 0, Field: "author", Op: OpEqual, Value: "kek"},
+		{Pos: 11, Negated: true, Field: "status", Op: OpEqual, Value: "closed"},
+		{Pos: 26, Field: "created", Op: OpGreaterEqual, Value: "2026-01-01"},
+		{Pos: 46, Value: `exact "phrase"`},
+		{Pos: 65, Value: "word"},
+	}
+	if len(q.Terms) != len(target) {
+		t.Fatalf("expected %d terms, got %#v", len(target), q.Terms)
+	}
+	for i, term := range q.Terms {
+		if term != target[i] {
+			t.Errorf("expected: %#v; got: %#v", target[i], term)
+		}
+	}
+
+	sort := []SortKey{{Pos: 70, Field: "likes", Desc: true}, {Pos: 82, Field: "id"}}
+	if len(q.Sort) != len(sort) || q.Sort[0] != sort[0] || q.Sort[1] != sort[1] {
+		t.Errorf("expected: %#v; got: %#v", sort, q.Sort)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		pos int
+	}{
+		{`title:"unterminated`, 6},
+		{`author: kek`, 7},
+		{`ok - lonely`, 3},
+		{`wo"rd`, 2},
+		{`-sort:id`, 0},
+		{`"phrase"glued`, 8},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.in)
+		var queryErr *Error
+		if !errors.As(err, &queryErr) {
+			t.Errorf("%s: expected query error, got %v", test.in, err)
+			continue
+		}
+		if queryErr.Pos != test.pos {
+			t.Errorf("%s: expected position %d, got %d (%v)", test.in, test.pos, queryErr.Pos, err)
+		}
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		pos int
+	}{
+		{`nope:1`, 0},
+		{`status:done`, 0},
+		{`ok author>kek`, 3},
+		{`created:yesterday`, 0},
+		{`label:question`, 0},
+		{`story_points>many`, 0},
+		{`sort:nope`, 0},
+		{`sprint>3`, 0},
+	}
+
+	for _, test := range tests {
+		q, err := Parse(test.in)
+		if err != nil {
+			t.Fatalf("%s: expected no parse error, got %v", test.in, err)
+		}
+		_, err = Validate(q, resolver)
+		var queryErr *Error
+		if !errors.As(err, &queryErr) {
+			t.Errorf("%s: expected query error, got %v", test.in, err)
+			continue
+		}
+		if queryErr.Pos != test.pos {
+			t.Errorf("%s: expected position %d, got %d (%v)", test.in, test.pos, queryErr.Pos, err)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	filter, err := Compile(
+		`author:kek status:open -label:bug story_points>=3 created>2026-01-01 "50%" likes>2 estimate<=5 sort:-likes`,
+		resolver,
+		Counts{Likes: map[uint32]int32{1: 5}},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	target := []string{
+		"author = ?",
+		"status = ?",
+		"NOT (EXISTS (SELECT 1 FROM task_field_values v WHERE v.task_id = task_infos.id AND v.field_id IN ? AND v.text_value = ?))",
+		"EXISTS (SELECT 1 FROM task_field_values v WHERE v.task_id = task_infos.id AND v.field_id IN ? AND v.number_value >= ?)",
+		"created_at >= ?",
+		"(title ILIKE ? OR content ILIKE ?)",
+		"COALESCE(CAST(CAST(? AS jsonb) ->> CAST(id AS text) AS integer), 0) > ?",
+		"estimate <= ?",
+	}
+	if len(filter.Where) != len(target) {
+		t.Fatalf("expected %d clauses, got %#v", len(target), filter.Where)
+	}
+	for i, clause := range filter.Where {
+		if clause.SQL != target[i] {
+			t.Errorf("expected: %s; got: %s", target[i], clause.SQL)
+		}
+	}
+	if filter.Where[5].Args[0] != `%50\%%` {
+		t.Errorf("expected escaped pattern, got %v", filter.Where[5].Args[0])
+	}
+	if filter.Where[6].Args[0] != `{"1":5}` {
+		t.Errorf("expected likes as json, got %v", filter.Where[6].Args[0])
+	}
+
+	if len(filter.Order) != 1 || !strings.HasSuffix(filter.Order[0].SQL, " DESC NULLS LAST") {
+		t.Errorf("unexpected order: %#v", filter.Order)
+	}
+}
+
+func TestUsesCounts(t *testing.T) {
+	tests := []struct {
+		in      string
+		counts  bool
+		filters bool
+		rest    int
+	}{
+		{`author:kek "likes" title:views`, false, false, 3},
+		{`author:kek -likes>2`, true, true, 1},
+		{`status:open sort:-views`, true, false, 1},
+	}
+
+	for _, test := range tests {
+		q, err := Parse(test.in)
+		if err != nil {
+			t.Fatalf("%s: %v", test.in, err)
+		}
+		checked, err := Validate(q, resolver)
+		if err != nil {
+			t.Fatalf("%s: %v", test.in, err)
+		}
+		if checked.UsesCounts() != test.counts {
+			t.Errorf("%s: expected counts used: %v", test.in, test.counts)
+		}
+		if checked.FiltersByCounts() != test.filters {
+			t.Errorf("%s: expected filtering by counts: %v", test.in, test.filters)
+		}
+		rest := checked.WithoutCounts()
+		if len(rest.conditions) != test.rest || len(rest.order) != 0 || rest.UsesCounts() {
+			t.Errorf("%s: expected %d conditions without counts, got %#v", test.in, test.rest, rest)
+		}
+	}
+}
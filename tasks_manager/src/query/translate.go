@@ -0,0 +1,133 @@
+package query
+
+import (
+	"encoding/json"
+	"strings"
+	"tasksmanager/src/database"
+	"time"
+)
+
+// Counts holds likes and views of tasks by task ID. They live in the
+// statistics service, so the caller has to pass them in.
+type Counts struct {
+	Likes map[uint32]int32
+	Views map[uint32]int32
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func sqlOp(op Op) string {
+	if op == OpEqual {
+		return "="
+	}
+	return string(op)
+}
+
+// Translate turns a checked query into SQL clauses over task_infos.
+func Translate(checked *Checked, counts Counts) (database.TaskFilter, error) {
+	var filter database.TaskFilter
+	for _, cond := range checked.conditions {
+		clause, err := conditionClause(cond, counts)
+		if err != nil {
+			return filter, err
+		}
+		if cond.term.Negated {
+			clause.SQL = "NOT (" + clause.SQL + ")"
+		}
+		filter.Where = append(filter.Where, clause)
+	}
+
+	for _, o := range checked.order {
+		clause, err := orderExpr(o.field, counts)
+		if err != nil {
+			return filter, err
+		}
+		if o.key.Desc {
+			clause.SQL += " DESC NULLS LAST"
+		} else {
+			clause.SQL += " ASC NULLS LAST"
+		}
+		filter.Order = append(filter.Order, clause)
+	}
+	return filter, nil
+}
+
+// Compile parses, validates and translates a query.
+func Compile(input string, resolver Resolver, counts Counts) (database.TaskFilter, error) {
+	q, err := Parse(input)
+	if err != nil {
+		return database.TaskFilter{}, err
+	}
+	checked, err := Validate(q, resolver)
+	if err != nil {
+		return database.TaskFilter{}, err
+	}
+	return Translate(checked, counts)
+}
+
+func conditionClause(cond condition, counts Counts) (database.Clause, error) {
+	f := cond.field
+	switch {
+	case cond.term.Field == "":
+		pattern := "%" + likeEscaper.Replace(cond.term.Value) + "%"
+		return database.Clause{SQL: "(title ILIKE ? OR content ILIKE ?)", Args: []any{pattern, pattern}}, nil
+	case f.ids != nil:
+		return database.FieldValueClause(f.ids, database.ValueColumn(f.typ), sqlOp(cond.term.Op), cond.value), nil
+	case f.counter != "":
+		expr, err := counterExpr(f.counter, counts)
+		if err != nil {
+			return expr, err
+		}
+		expr.SQL += " " + sqlOp(cond.term.Op) + " ?"
+		expr.Args = append(expr.Args, cond.value)
+		return expr, nil
+	case f.contains:
+		pattern := "%" + likeEscaper.Replace(cond.term.Value) + "%"
+		return database.Clause{SQL: f.column + " ILIKE ?", Args: []any{pattern}}, nil
+	case f.typ == database.FieldDate:
+		return dateClause(f.column, cond.term.Op, cond.value.(time.Time)), nil
+	}
+	return database.Clause{SQL: f.column + " " + sqlOp(cond.term.Op) + " ?", Args: []any{cond.value}}, nil
+}
+
+// dateClause compares timestamps with whole days, so created:2026-01-01
+// matches anything created during that day.
+func dateClause(column string, op Op, day time.Time) database.Clause {
+	next := day.AddDate(0, 0, 1)
+	switch op {
+	case OpGreater:
+		return database.Clause{SQL: column + " >= ?", Args: []any{next}}
+	case OpGreaterEqual:
+		return database.Clause{SQL: column + " >= ?", Args: []any{day}}
+	case OpLess:
+		return database.Clause{SQL: column + " < ?", Args: []any{day}}
+	case OpLessEqual:
+		return database.Clause{SQL: column + " < ?", Args: []any{next}}
+	}
+	return database.Clause{SQL: column + " >= ? AND " + column + " < ?", Args: []any{day, next}}
+}
+
+func counterExpr(counter string, counts Counts) (database.Clause, error) {
+	values := counts.Likes
+	if counter == "views" {
+		values = counts.Views
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return database.Clause{}, err
+	}
+	return database.Clause{
+		SQL:  "COALESCE(CAST(CAST(? AS jsonb) ->> CAST(id AS text) AS integer), 0)",
+		Args: []any{string(encoded)},
+	}, nil
+}
+
+func orderExpr(f field, counts Counts) (database.Clause, error) {
+	switch {
+	case f.ids != nil:
+		return database.FieldValueExpr(f.ids, database.ValueColumn(f.typ)), nil
+	case f.counter != "":
+		return counterExpr(f.counter, counts)
+	}
+	return database.Clause{SQL: f.column}, nil
+}
@@ -0,0 +1,174 @@
+package query
+
+import (
+	"slices"
+	"strconv"
+	"tasksmanager/src/database"
+	"time"
+)
+
+var (
+	equalOnly  = []Op{OpEqual}
+	comparable = []Op{OpEqual, OpGreater, OpGreaterEqual, OpLess, OpLessEqual}
+)
+
+// Resolver looks up custom fields by name. It returns the IDs of all fields
+// with this name and one of their definitions, or nil if there is no such
+// field.
+type Resolver interface {
+	ResolveField(name string) ([]uint, *database.FieldDefinition, error)
+}
+
+type field struct {
+	name     string
+	typ      string
+	ops      []Op
+	options  []string
+	column   string
+	contains bool
+	counter  string
+	ids      []uint
+}
+
+var builtins = map[string]field{
+	"author":   {typ: database.FieldText, ops: equalOnly, column: "author"},
+	"project":  {typ: database.FieldText, ops: equalOnly, column: "project"},
+	"status":   {typ: database.FieldEnum, ops: equalOnly, column: "status", options: database.TaskStatuses},
+	"title":    {typ: database.FieldText, ops: equalOnly, column: "title", contains: true},
+	"id":       {typ: database.FieldNumber, ops: comparable, column: "id"},
+	"created":  {typ: database.FieldDate, ops: comparable, column: "created_at"},
+	"updated":  {typ: database.FieldDate, ops: comparable, column: "updated_at"},
+	"estimate": {typ: database.FieldNumber, ops: comparable, column: "estimate"},
+	"sprint":   {typ: database.FieldNumber, ops: equalOnly, column: "sprint_id"},
+	"likes":    {typ: database.FieldNumber, ops: comparable, counter: "likes"},
+	"views":    {typ: database.FieldNumber, ops: comparable, counter: "views"},
+}
+
+type condition struct {
+	term  Term
+	field field
+	value any
+}
+
+type order struct {
+	key   SortKey
+	field field
+}
+
+// Checked is a query whose fields are resolved and values are parsed.
+type Checked struct {
+	conditions []condition
+	order      []order
+}
+
+// UsesCounts reports whether the query filters or sorts by likes or views.
+func (c *Checked) UsesCounts() bool {
+	if c.FiltersByCounts() {
+		return true
+	}
+	for _, o := range c.order {
+		if o.field.counter != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// FiltersByCounts reports whether likes or views decide which tasks match,
+// not only their order.
+func (c *Checked) FiltersByCounts() bool {
+	for _, cond := range c.conditions {
+		if cond.field.counter != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutCounts drops likes and views terms and the order. The rest matches
+// every task the whole query can match, so it finds the tasks whose counts
+// are needed.
+func (c *Checked) WithoutCounts() *Checked {
+	rest := &Checked{}
+	for _, cond := range c.conditions {
+		if cond.field.counter == "" {
+			rest.conditions = append(rest.conditions, cond)
+		}
+	}
+	return rest
+}
+
+func Validate(q *Query, resolver Resolver) (*Checked, error) {
+	checked := &Checked{}
+	for _, term := range q.Terms {
+		if term.Field == "" {
+			checked.conditions = append(checked.conditions, condition{term: term, value: term.Value})
+			continue
+		}
+
+		f, err := lookup(term.Field, term.Pos, resolver)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(f.ops, term.Op) {
+			return nil, errorf(term.Pos, "operator '%s' is not supported for %s", term.Op, term.Field)
+		}
+		value, err := parseValue(f, term)
+		if err != nil {
+			return nil, err
+		}
+		checked.conditions = append(checked.conditions, condition{term: term, field: f, value: value})
+	}
+
+	for _, key := range q.Sort {
+		f, err := lookup(key.Field, key.Pos, resolver)
+		if err != nil {
+			return nil, err
+		}
+		checked.order = append(checked.order, order{key: key, field: f})
+	}
+	return checked, nil
+}
+
+func lookup(name string, pos int, resolver Resolver) (field, error) {
+	if f, ok := builtins[name]; ok {
+		f.name = name
+		return f, nil
+	}
+
+	ids, def, err := resolver.ResolveField(name)
+	if err != nil {
+		return field{}, err
+	}
+	if def == nil {
+		return field{}, errorf(pos, "unknown field %q", name)
+	}
+
+	f := field{name: name, typ: def.Type, ops: equalOnly, options: def.Options, ids: ids}
+	if def.Type == database.FieldNumber || def.Type == database.FieldDate {
+		f.ops = comparable
+	}
+	return f, nil
+}
+
+func parseValue(f field, term Term) (any, error) {
+	switch f.typ {
+	case database.FieldNumber:
+		number, err := strconv.ParseFloat(term.Value, 64)
+		if err != nil {
+			return nil, errorf(term.Pos, "%s expects a number, got %q", term.Field, term.Value)
+		}
+		return number, nil
+	case database.FieldDate:
+		date, err := time.Parse(database.DateLayout, term.Value)
+		if err != nil {
+			return nil, errorf(term.Pos, "%s expects a date like 2026-01-31, got %q", term.Field, term.Value)
+		}
+		return date, nil
+	case database.FieldEnum:
+		if !slices.Contains(f.options, term.Value) {
+			return nil, errorf(term.Pos, "%s must be one of %v, got %q", term.Field, f.options, term.Value)
+		}
+	}
+	return term.Value, nil
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"strings"
+	pb "tasksmanager/proto"
+	"tasksmanager/src/database"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const maxChecklistItemLength = 500
+
+func checklistToProto(items []database.ChecklistItem) []*pb.ChecklistItem {
+	if len(items) == 0 {
+		return nil
+	}
+	checklist := make([]*pb.ChecklistItem, 0, len(items))
+	for _, item := range items {
+		checklist = append(checklist, checklistItemToProto(&item))
+	}
+	return checklist
+}
+
+func checklistItemToProto(item *database.ChecklistItem) *pb.ChecklistItem {
+	return &pb.ChecklistItem{
+		Id:       uint32(item.ID),
+		Position: uint32(item.Position),
+		Text:     item.Text,
+		Done:     item.Done,
+	}
+}
+
+func checkChecklistItemRef(taskID, id uint32) error {
+	if taskID == 0 {
+		return invalidArgument("task_id is required")
+	}
+	if id == 0 {
+		return invalidArgument("id is required")
+	}
+	return nil
+}
+
+func (s *Server) AddChecklistItem(ctx context.Context, req *pb.AddChecklistItemRequest) (*pb.ChecklistItem, error) {
+	if req.TaskId == 0 {
+		return nil, invalidArgument("task_id is required")
+	}
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, invalidArgument("text is required")
+	}
+	if len(text) > maxChecklistItemLength {
+		return nil, invalidArgument("text is too long")
+	}
+	item, err := s.db.WithContext(ctx).AddChecklistItem(uint(req.TaskId), req.Author, text, int(req.Position))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return checklistItemToProto(item), nil
+}
+
+func (s *Server) CheckChecklistItem(ctx context.Context, req *pb.CheckChecklistItemRequest) (*emptypb.Empty, error) {
+	if err := checkChecklistItemRef(req.TaskId, req.Id); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).SetChecklistItemDone(uint(req.TaskId), uint(req.Id), req.Author, req.Done); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) MoveChecklistItem(ctx context.Context, req *pb.MoveChecklistItemRequest) (*emptypb.Empty, error) {
+	if err := checkChecklistItemRef(req.TaskId, req.Id); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).MoveChecklistItem(uint(req.TaskId), uint(req.Id), req.Author, int(req.Position)); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) DeleteChecklistItem(ctx context.Context, req *pb.DeleteChecklistItemRequest) (*emptypb.Empty, error) {
+	if err := checkChecklistItemRef(req.TaskId, req.Id); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).DeleteChecklistItem(uint(req.TaskId), uint(req.Id), req.Author); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
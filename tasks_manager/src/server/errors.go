@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"tasksmanager/src/database"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch {
+	case errors.Is(err, database.ErrNotFound), errors.Is(err, database.ErrFieldNotFound),
+		errors.Is(err, database.ErrChecklistItemNotFound), errors.Is(err, database.ErrLinkNotFound),
+		errors.Is(err, database.ErrSprintNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, database.ErrFieldExists), errors.Is(err, database.ErrLinkExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, database.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, database.ErrAlreadyMerged), errors.Is(err, database.ErrSprintState),
+		errors.Is(err, database.ErrNotDeleted):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func invalidArgument(msg string) error {
+	return status.Error(codes.InvalidArgument, msg)
+}
@@ -0,0 +1,396 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	pb "tasksmanager/proto"
+	"tasksmanager/src/database"
+	"tasksmanager/src/query"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const maxTextValueLen = 1000
+
+var (
+	fieldName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+	userLogin = regexp.MustCompile(`^[A-Za-z0-9]{3,30}$`)
+
+	fieldTypes = []string{
+		database.FieldText,
+		database.FieldNumber,
+		database.FieldDate,
+		database.FieldEnum,
+		database.FieldUser,
+	}
+	filterOps = []string{"=", "!=", "<", "<=", ">", ">="}
+)
+
+func definitionToProto(def *database.FieldDefinition) *pb.FieldDefinition {
+	return &pb.FieldDefinition{
+		Id:      uint32(def.ID),
+		Author:  def.Author,
+		Project: def.Project,
+		Name:    def.Name,
+		Type:    def.Type,
+		Options: def.Options,
+	}
+}
+
+func (s *Server) CreateFieldDefinition(ctx context.Context, req *pb.CreateFieldDefinitionRequest) (*pb.FieldDefinition, error) {
+	if req.Author == "" {
+		return nil, invalidArgument("author is required")
+	}
+	if !fieldName.MatchString(req.Name) {
+		return nil, invalidArgument("field name must be lowercase letters, digits and underscores")
+	}
+	if !slices.Contains(fieldTypes, req.Type) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown field type %q", req.Type)
+	}
+	if req.Type == database.FieldEnum && len(req.Options) == 0 {
+		return nil, invalidArgument("enum field needs options")
+	}
+	if req.Type != database.FieldEnum && len(req.Options) > 0 {
+		return nil, invalidArgument("only enum fields have options")
+	}
+
+	def := &database.FieldDefinition{
+		Author:  req.Author,
+		Project: req.Project,
+		Name:    req.Name,
+		Type:    req.Type,
+		Options: req.Options,
+	}
+	id, err := s.db.WithContext(ctx).CreateFieldDefinition(def)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	def.ID = id
+	return definitionToProto(def), nil
+}
+
+func (s *Server) GetFieldDefinitions(ctx context.Context, req *pb.GetFieldDefinitionsRequest) (*pb.GetFieldDefinitionsResponse, error) {
+	defs, err := s.db.WithContext(ctx).GetFieldDefinitions(req.Project)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	fields := make([]*pb.FieldDefinition, 0, len(defs))
+	for _, def := range defs {
+		fields = append(fields, definitionToProto(&def))
+	}
+	return &pb.GetFieldDefinitionsResponse{Fields: fields}, nil
+}
+
+func (s *Server) DeleteFieldDefinition(ctx context.Context, req *pb.DeleteFieldDefinitionRequest) (*emptypb.Empty, error) {
+	if req.Id == 0 {
+		return nil, invalidArgument("id is required")
+	}
+	if err := s.db.WithContext(ctx).DeleteFieldDefinition(uint(req.Id), req.Author); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func parseFieldValue(def *database.FieldDefinition, raw string) (database.FieldValue, error) {
+	value := database.FieldValue{FieldID: def.ID}
+	switch def.Type {
+	case database.FieldNumber:
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return value, fmt.Errorf("field %s: %q is not a number", def.Name, raw)
+		}
+		value.Number = &number
+	case database.FieldDate:
+		date, err := time.Parse(database.DateLayout, raw)
+		if err != nil {
+			return value, fmt.Errorf("field %s: %q is not a date in YYYY-MM-DD format", def.Name, raw)
+		}
+		value.Date = &date
+	case database.FieldEnum:
+		if !slices.Contains(def.Options, raw) {
+			return value, fmt.Errorf("field %s: %q is not one of %v", def.Name, raw, def.Options)
+		}
+		value.Text = &raw
+	case database.FieldUser:
+		if !userLogin.MatchString(raw) {
+			return value, fmt.Errorf("field %s: %q is not a user login", def.Name, raw)
+		}
+		value.Text = &raw
+	default:
+		if len(raw) > maxTextValueLen {
+			return value, fmt.Errorf("field %s: text is longer than %d", def.Name, maxTextValueLen)
+		}
+		value.Text = &raw
+	}
+	return value, nil
+}
+
+// resolveFieldValues validates raw values against the fields defined for
+// the task's project. Empty values are returned as fields to clear.
+func resolveFieldValues(defs []database.FieldDefinition, raw map[string]string) ([]database.FieldValue, []uint, error) {
+	byName := make(map[string]*database.FieldDefinition, len(defs))
+	for i := range defs {
+		byName[defs[i].Name] = &defs[i]
+	}
+
+	var values []database.FieldValue
+	var cleared []uint
+	for name, rawValue := range raw {
+		def, ok := byName[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown field %q", name)
+		}
+		if rawValue == "" {
+			cleared = append(cleared, def.ID)
+			continue
+		}
+		value, err := parseFieldValue(def, rawValue)
+		if err != nil {
+			return nil, nil, err
+		}
+		values = append(values, value)
+	}
+	return values, cleared, nil
+}
+
+// fieldsNamed returns the definitions of the field visible in the project.
+// When project is empty, fields with this name from every project are used.
+func (s *Server) fieldsNamed(ctx context.Context, project, name string) ([]database.FieldDefinition, error) {
+	var defs []database.FieldDefinition
+	var err error
+	if project != "" {
+		defs, err = s.db.WithContext(ctx).GetFieldDefinitions(project)
+	} else {
+		defs, err = s.db.WithContext(ctx).FieldDefinitionsByName(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	named := defs[:0]
+	for _, def := range defs {
+		if def.Name == name {
+			named = append(named, def)
+		}
+	}
+	return named, nil
+}
+
+// resolveField resolves a field name into field IDs and one of the
+// definitions, requiring every matching definition to be of the same type.
+// It returns a nil definition if there is no such field.
+func (s *Server) resolveField(ctx context.Context, project, name string) ([]uint, *database.FieldDefinition, error) {
+	defs, err := s.fieldsNamed(ctx, project, name)
+	if err != nil {
+		return nil, nil, toStatus(err)
+	}
+	if len(defs) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make([]uint, 0, len(defs))
+	for _, def := range defs {
+		if def.Type != defs[0].Type {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "field %q has different types in different projects", name)
+		}
+		ids = append(ids, def.ID)
+	}
+	return ids, &defs[0], nil
+}
+
+func (s *Server) fieldColumn(ctx context.Context, project, name string) ([]uint, *database.FieldDefinition, error) {
+	ids, def, err := s.resolveField(ctx, project, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if def == nil {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "unknown field %q", name)
+	}
+	return ids, def, nil
+}
+
+type fieldResolver struct {
+	ctx     context.Context
+	server  *Server
+	project string
+}
+
+func (r fieldResolver) ResolveField(name string) ([]uint, *database.FieldDefinition, error) {
+	return r.server.resolveField(r.ctx, r.project, name)
+}
+
+func (s *Server) taskFilter(ctx context.Context, req *pb.GetTasksRequest) (database.TaskFilter, error) {
+	filter, err := s.fieldFilter(ctx, req)
+	if err != nil {
+		return filter, err
+	}
+
+	checked, err := s.checkQuery(ctx, req)
+	if err != nil {
+		return filter, err
+	}
+	if checked != nil {
+		compiled, err := query.Translate(checked, query.Counts{Likes: req.Likes, Views: req.Views})
+		if err != nil {
+			return filter, toStatus(err)
+		}
+		filter.Where = append(filter.Where, compiled.Where...)
+		filter.Order = append(filter.Order, compiled.Order...)
+	}
+
+	if req.SortField != "" {
+		ids, def, err := s.fieldColumn(ctx, req.Project, req.SortField)
+		if err != nil {
+			return filter, err
+		}
+		filter.Order = append(filter.Order, database.FieldOrderClause(ids, database.ValueColumn(def.Type), req.SortDesc))
+	}
+	return filter, nil
+}
+
+// fieldFilter is the part of the filter that does not come from the query.
+func (s *Server) fieldFilter(ctx context.Context, req *pb.GetTasksRequest) (database.TaskFilter, error) {
+	filter := database.TaskFilter{
+		Author:  req.Author,
+		Project: req.Project,
+	}
+
+	for _, f := range req.Filters {
+		if !slices.Contains(filterOps, f.Op) {
+			return filter, status.Errorf(codes.InvalidArgument, "unknown filter operator %q", f.Op)
+		}
+		ids, def, err := s.fieldColumn(ctx, req.Project, f.Field)
+		if err != nil {
+			return filter, err
+		}
+		value, err := parseFieldValue(def, f.Value)
+		if err != nil {
+			return filter, invalidArgument(err.Error())
+		}
+		filter.Where = append(filter.Where, database.FieldValueClause(ids, database.ValueColumn(def.Type), f.Op, sqlValue(value)))
+	}
+	return filter, nil
+}
+
+// checkQuery parses and validates the query of the request. It returns nil
+// if there is no query.
+func (s *Server) checkQuery(ctx context.Context, req *pb.GetTasksRequest) (*query.Checked, error) {
+	if req.Query == "" {
+		return nil, nil
+	}
+	q, err := query.Parse(req.Query)
+	if err != nil {
+		return nil, queryStatus(err)
+	}
+	checked, err := query.Validate(q, fieldResolver{ctx: ctx, server: s, project: req.Project})
+	if err != nil {
+		return nil, queryStatus(err)
+	}
+	return checked, nil
+}
+
+func queryStatus(err error) error {
+	var queryErr *query.Error
+	if errors.As(err, &queryErr) {
+		return status.Errorf(codes.InvalidArgument, "bad query: %v", err)
+	}
+	return toStatus(err)
+}
+
+// GetCountCandidates finds the tasks whose likes and views a query needs:
+// those matching everything but its likes and views terms.
+func (s *Server) GetCountCandidates(ctx context.Context, req *pb.GetTasksRequest) (*pb.GetCountCandidatesResponse, error) {
+	checked, err := s.checkQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if checked == nil || !checked.UsesCounts() {
+		return &pb.GetCountCandidatesResponse{}, nil
+	}
+
+	filter, err := s.fieldFilter(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.countCandidates(ctx, filter, checked)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetCountCandidatesResponse{
+		UsesCounts:      true,
+		TaskIds:         ids,
+		FiltersByCounts: checked.FiltersByCounts(),
+	}, nil
+}
+
+// countCandidates returns IDs of tasks matching the filter and the query
+// without its likes and views terms.
+func (s *Server) countCandidates(ctx context.Context, filter database.TaskFilter, checked *query.Checked) ([]uint32, error) {
+	compiled, err := query.Translate(checked.WithoutCounts(), query.Counts{})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	filter.Where = append(filter.Where, compiled.Where...)
+
+	ids, err := s.db.WithContext(ctx).TaskIDs(filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return ids, nil
+}
+
+func (s *Server) CountQueries(ctx context.Context, req *pb.CountQueriesRequest) (*pb.CountQueriesResponse, error) {
+	counts := query.Counts{Likes: req.Likes, Views: req.Views}
+	resp := &pb.CountQueriesResponse{Counts: make([]*pb.QueryCount, 0, len(req.Queries))}
+	for _, q := range req.Queries {
+		result := &pb.QueryCount{}
+		resp.Counts = append(resp.Counts, result)
+
+		checked, err := s.checkQuery(ctx, &pb.GetTasksRequest{Query: q})
+		if status.Code(err) == codes.InvalidArgument {
+			result.Error = status.Convert(err).Message()
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var filter database.TaskFilter
+		if checked != nil && checked.FiltersByCounts() && !req.WithCounts {
+			result.NeedsCounts = true
+			if result.TaskIds, err = s.countCandidates(ctx, filter, checked); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if checked != nil {
+			compiled, err := query.Translate(checked, counts)
+			if err != nil {
+				return nil, toStatus(err)
+			}
+			filter.Where = compiled.Where
+		}
+		count, err := s.db.WithContext(ctx).CountTasks(filter)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		result.Count = uint32(count)
+	}
+	return resp, nil
+}
+
+func sqlValue(value database.FieldValue) any {
+	switch {
+	case value.Number != nil:
+		return *value.Number
+	case value.Date != nil:
+		return *value.Date
+	}
+	return *value.Text
+}
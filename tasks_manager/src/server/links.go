@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"slices"
+	pb "tasksmanager/proto"
+	"tasksmanager/src/database"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func linksToProto(links []database.TaskLink) []*pb.TaskLink {
+	if len(links) == 0 {
+		return nil
+	}
+	result := make([]*pb.TaskLink, 0, len(links))
+	for _, link := range links {
+		result = append(result, linkToProto(&link))
+	}
+	return result
+}
+
+func linkToProto(link *database.TaskLink) *pb.TaskLink {
+	return &pb.TaskLink{
+		Id:       uint32(link.ID),
+		Type:     link.Type,
+		SourceId: uint32(link.SourceID),
+		TargetId: uint32(link.TargetID),
+	}
+}
+
+func (s *Server) LinkTasks(ctx context.Context, req *pb.LinkTasksRequest) (*pb.TaskLink, error) {
+	if !slices.Contains(database.LinkTypes, req.Type) {
+		return nil, invalidArgument("type must be one of relates_to, duplicates, caused_by")
+	}
+	if req.SourceId == 0 || req.TargetId == 0 {
+		return nil, invalidArgument("source_id and target_id are required")
+	}
+	if req.SourceId == req.TargetId {
+		return nil, invalidArgument("can not link a task to itself")
+	}
+	link, err := s.db.WithContext(ctx).LinkTasks(req.Author, req.Type, uint(req.SourceId), uint(req.TargetId))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return linkToProto(link), nil
+}
+
+func (s *Server) UnlinkTasks(ctx context.Context, req *pb.UnlinkTasksRequest) (*emptypb.Empty, error) {
+	if req.Id == 0 {
+		return nil, invalidArgument("id is required")
+	}
+	if err := s.db.WithContext(ctx).UnlinkTasks(req.Author, uint(req.Id)); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) MergeTasks(ctx context.Context, req *pb.MergeTasksRequest) (*emptypb.Empty, error) {
+	if req.DuplicateId == 0 || req.CanonicalId == 0 {
+		return nil, invalidArgument("duplicate_id and canonical_id are required")
+	}
+	if req.DuplicateId == req.CanonicalId {
+		return nil, invalidArgument("can not merge a task into itself")
+	}
+	if err := s.db.WithContext(ctx).MergeTasks(req.Author, uint(req.DuplicateId), uint(req.CanonicalId)); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
@@ -2,14 +2,17 @@ package server
 
 import (
 	"context"
-	"fmt"
 	"log"
+	"log/slog"
 	"net"
+	"slices"
 	pb "tasksmanager/proto"
 	"tasksmanager/src/database"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -20,52 +23,140 @@ type Server struct {
 }
 
 func DataToProto(data *database.TaskData) *pb.Task {
+	var estimate float64
+	if data.Estimate != nil {
+		estimate = *data.Estimate
+	}
 	return &pb.Task{
-		Id:           uint32(data.ID),
-		Author:       data.Author,
-		Title:        data.Title,
-		Content:      data.Content,
-		CreationTime: timestamppb.New(data.CreationTime),
+		Id:             uint32(data.ID),
+		Author:         data.Author,
+		Title:          data.Title,
+		Content:        data.Content,
+		CreationTime:   timestamppb.New(data.CreationTime),
+		Project:        data.Project,
+		Fields:         data.Fields,
+		Status:         data.Status,
+		UpdateTime:     timestamppb.New(data.UpdateTime),
+		Checklist:      checklistToProto(data.Checklist),
+		ChecklistDone:  uint32(data.Progress.Done),
+		ChecklistTotal: uint32(data.Progress.Total),
+		Links:          linksToProto(data.Links),
+		MergedInto:     uint32(data.MergedInto),
+		Estimate:       estimate,
+		EstimateUnit:   data.EstimateUnit,
+		SprintId:       uint32(data.SprintID),
 	}
 }
 
 func (s *Server) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.CreateTaskResponse, error) {
+	if req.Author == "" {
+		return nil, invalidArgument("author is required")
+	}
+	if req.Title == "" {
+		return nil, invalidArgument("title is required")
+	}
+	defs, err := s.db.WithContext(ctx).GetFieldDefinitions(req.Project)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	values, _, err := resolveFieldValues(defs, req.Fields)
+	if err != nil {
+		return nil, invalidArgument(err.Error())
+	}
+	if err := checkEstimate(&req.Estimate, req.EstimateUnit); err != nil {
+		return nil, err
+	}
+
 	data := &database.TaskData{
-		Author:  req.Author,
-		Title:   req.Title,
-		Content: req.Content,
+		Author:       req.Author,
+		Title:        req.Title,
+		Content:      req.Content,
+		Project:      req.Project,
+		Estimate:     &req.Estimate,
+		EstimateUnit: req.EstimateUnit,
+	}
+	id, err := s.db.WithContext(ctx).CreateTask(data, values)
+	if err != nil {
+		return nil, toStatus(err)
 	}
-	id, err := s.db.CreateTask(data)
-	return &pb.CreateTaskResponse{Id: id}, err
+	return &pb.CreateTaskResponse{Id: id}, nil
 }
 
 func (s *Server) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.Task, error) {
-	data, err := s.db.GetTaskData(uint(req.Id), req.Author)
+	if req.Id == 0 {
+		return nil, invalidArgument("id is required")
+	}
+	data, err := s.db.WithContext(ctx).GetTaskData(uint(req.Id), req.Author)
 	if err != nil {
-		return nil, err
+		return nil, toStatus(err)
 	}
 	return DataToProto(data), nil
 }
 
 func (s *Server) UpdateTask(ctx context.Context, req *pb.UpdateTaskRequest) (*emptypb.Empty, error) {
-	err := s.db.UpdateTaskData(&database.TaskData{
-		Author:  req.Author,
-		ID:      uint(req.Id),
-		Content: req.Content,
-		Title:   req.Title,
-	})
-	return nil, err
+	if req.Id == 0 {
+		return nil, invalidArgument("id is required")
+	}
+	if req.Status != "" && !slices.Contains(database.TaskStatuses, req.Status) {
+		return nil, status.Errorf(codes.InvalidArgument, "status must be one of %v", database.TaskStatuses)
+	}
+	if err := checkEstimate(req.Estimate, req.EstimateUnit); err != nil {
+		return nil, err
+	}
+	var values []database.FieldValue
+	var cleared []uint
+	if len(req.Fields) > 0 {
+		task, err := s.db.WithContext(ctx).GetTaskData(uint(req.Id), req.Author)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		defs, err := s.db.WithContext(ctx).GetFieldDefinitions(task.Project)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		values, cleared, err = resolveFieldValues(defs, req.Fields)
+		if err != nil {
+			return nil, invalidArgument(err.Error())
+		}
+	}
+
+	err := s.db.WithContext(ctx).UpdateTaskData(&database.TaskData{
+		Author:       req.Author,
+		ID:           uint(req.Id),
+		Content:      req.Content,
+		Title:        req.Title,
+		Status:       req.Status,
+		Estimate:     req.Estimate,
+		EstimateUnit: req.EstimateUnit,
+	}, values, cleared)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
 }
 
 func (s *Server) DeleteTask(ctx context.Context, req *pb.DeleteTaskRequest) (*emptypb.Empty, error) {
-	return nil, s.db.DeleteTask(uint(req.Id), req.Author)
+	if req.Id == 0 {
+		return nil, invalidArgument("id is required")
+	}
+	if err := s.db.WithContext(ctx).DeleteTask(uint(req.Id), req.Author); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
 }
 
 func (s *Server) GetTasks(ctx context.Context, req *pb.GetTasksRequest) (*pb.GetTasksReponse, error) {
-	data, err := s.db.GetTasks(int(req.Offset), int(req.BatchSize))
+	if req.BatchSize < -1 {
+		return nil, invalidArgument("batch_size must be -1 or non-negative")
+	}
+	filter, err := s.taskFilter(ctx, req)
 	if err != nil {
 		return nil, err
 	}
+	data, err := s.db.WithContext(ctx).GetTasks(int(req.Offset), int(req.BatchSize), filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
 	tasks := dataToTasks(data)
 	return &pb.GetTasksReponse{
 		Tasks:  tasks,
@@ -73,6 +164,20 @@ func (s *Server) GetTasks(ctx context.Context, req *pb.GetTasksRequest) (*pb.Get
 	}, nil
 }
 
+// CountTasks counts tasks matching the same filters as GetTasks. Paging and
+// sort fields of the request are ignored.
+func (s *Server) CountTasks(ctx context.Context, req *pb.GetTasksRequest) (*pb.CountTasksResponse, error) {
+	filter, err := s.taskFilter(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	count, err := s.db.WithContext(ctx).CountTasks(filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.CountTasksResponse{Count: uint32(count)}, nil
+}
+
 func dataToTasks(data []database.TaskData) []*pb.Task {
 	tasks := make([]*pb.Task, 0, len(data))
 	for _, task := range data {
@@ -93,12 +198,12 @@ func (s *Server) RegisterAndListen(addr string) {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(logCalls(slog.Default())))
 	reflection.Register(grpcServer)
 
 	pb.RegisterTaskServiceServer(grpcServer, s)
 
-	fmt.Println("Tasks manager server started.")
+	slog.Info("Tasks manager server started.", slog.String("addr", addr))
 	err = grpcServer.Serve(lis)
 	if err != nil {
 		log.Fatalf("Server stopped: %v", err)
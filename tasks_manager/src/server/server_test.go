@@ -1,9 +1,20 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	pb "tasksmanager/proto"
 	"tasksmanager/src/database"
 	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func TestDataToProto(t *testing.T) {
@@ -39,3 +50,211 @@ func TestDataToTasks(t *testing.T) {
 		}
 	}
 }
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		in   error
+		code codes.Code
+	}{
+		{database.ErrNotFound, codes.NotFound},
+		{database.ErrPermissionDenied, codes.PermissionDenied},
+		{fmt.Errorf("wrapped: %w", database.ErrNotFound), codes.NotFound},
+		{database.ErrLinkExists, codes.AlreadyExists},
+		{database.ErrAlreadyMerged, codes.FailedPrecondition},
+		{database.ErrNotDeleted, codes.FailedPrecondition},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+		{context.Canceled, codes.Canceled},
+		{invalidArgument("bad"), codes.InvalidArgument},
+		{errors.New("boom"), codes.Internal},
+	}
+
+	for _, test := range tests {
+		if code := status.Code(toStatus(test.in)); code != test.code {
+			t.Errorf("%v: expected: %v; got: %v", test.in, test.code, code)
+		}
+	}
+	if toStatus(nil) != nil {
+		t.Error("expected nil")
+	}
+}
+
+func TestResolveFieldValues(t *testing.T) {
+	defs := []database.FieldDefinition{
+		{ID: 1, Name: "story_points", Type: database.FieldNumber},
+		{ID: 2, Name: "customer", Type: database.FieldText},
+		{ID: 3, Name: "severity", Type: database.FieldEnum, Options: []string{"low", "high"}},
+		{ID: 4, Name: "due", Type: database.FieldDate},
+		{ID: 5, Name: "reviewer", Type: database.FieldUser},
+	}
+
+	t.Run("Good values", func(t *testing.T) {
+		values, cleared, err := resolveFieldValues(defs, map[string]string{
+			"story_points": "3.5",
+			"customer":     "ACME",
+			"severity":     "high",
+			"due":          "2026-01-02",
+			"reviewer":     "",
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(values) != 4 {
+			t.Errorf("expected 4 values, got %d", len(values))
+		}
+		if len(cleared) != 1 || cleared[0] != 5 {
+			t.Errorf("expected reviewer to be cleared, got %v", cleared)
+		}
+		for _, value := range values {
+			if value.FieldID == 1 && (value.Number == nil || *value.Number != 3.5) {
+				t.Errorf("expected number 3.5, got %#v", value)
+			}
+		}
+	})
+
+	bad := map[string]map[string]string{
+		"Unknown field": {"nope": "1"},
+		"Bad number":    {"story_points": "many"},
+		"Bad enum":      {"severity": "medium"},
+		"Bad date":      {"due": "02/01/2026"},
+		"Bad user":      {"reviewer": "not a login"},
+	}
+	for name, raw := range bad {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := resolveFieldValues(defs, raw); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestChecklistToProto(t *testing.T) {
+	in := &database.TaskData{
+		ID: 1,
+		Checklist: []database.ChecklistItem{
+			{ID: 4, Position: 0, Text: "write", Done: true},
+			{ID: 2, Position: 1, Text: "review"},
+		},
+		Progress: database.ChecklistProgress{Done: 1, Total: 2},
+	}
+
+	out := DataToProto(in)
+	if out.ChecklistDone != 1 || out.ChecklistTotal != 2 {
+		t.Errorf("expected progress 1/2, got %d/%d", out.ChecklistDone, out.ChecklistTotal)
+	}
+	if len(out.Checklist) != 2 ||
+		out.Checklist[0].Id != 4 || !out.Checklist[0].Done ||
+		out.Checklist[1].Position != 1 || out.Checklist[1].Text != "review" {
+		t.Errorf("unexpected checklist: %v", out.Checklist)
+	}
+}
+
+func TestChecklistValidation(t *testing.T) {
+	s := &Server{}
+	_, err := s.AddChecklistItem(context.Background(), &pb.AddChecklistItemRequest{TaskId: 1, Text: "  "})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for empty text, got %v", err)
+	}
+	_, err = s.MoveChecklistItem(context.Background(), &pb.MoveChecklistItemRequest{TaskId: 1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for missing id, got %v", err)
+	}
+}
+
+func TestLinkValidation(t *testing.T) {
+	s := &Server{}
+	for _, req := range []*pb.LinkTasksRequest{
+		{Type: "blocks", SourceId: 1, TargetId: 2},
+		{Type: database.LinkRelatesTo, SourceId: 1},
+		{Type: database.LinkCausedBy, SourceId: 3, TargetId: 3},
+	} {
+		if _, err := s.LinkTasks(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%v: expected InvalidArgument, got %v", req, err)
+		}
+	}
+
+	_, err := s.MergeTasks(context.Background(), &pb.MergeTasksRequest{DuplicateId: 4, CanonicalId: 4})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for merge into itself, got %v", err)
+	}
+}
+
+func TestAverageVelocity(t *testing.T) {
+	points, hours := averageVelocity([]database.Sprint{
+		{CompletedPoints: 21, CompletedHours: 10},
+		{CompletedPoints: 13},
+		{CompletedPoints: 20, CompletedHours: 5},
+	})
+	if points != 18 || hours != 5 {
+		t.Errorf("expected 18 points and 5 hours, got %v and %v", points, hours)
+	}
+
+	if points, hours := averageVelocity(nil); points != 0 || hours != 0 {
+		t.Errorf("expected zero velocity without sprints, got %v and %v", points, hours)
+	}
+}
+
+func TestSprintValidation(t *testing.T) {
+	negative := -1.0
+	if err := checkEstimate(&negative, ""); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for negative estimate, got %v", err)
+	}
+	if err := checkEstimate(nil, "days"); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for unknown unit, got %v", err)
+	}
+	if err := checkEstimate(nil, database.EstimateHours); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	s := &Server{}
+	for _, req := range []*pb.CreateSprintRequest{
+		{Name: "", StartDate: "2026-01-05"},
+		{Name: "Sprint 1", StartDate: "next monday"},
+		{Name: "Sprint 1", StartDate: "2026-01-05", EndDate: "2026-01-05"},
+	} {
+		if _, err := s.CreateSprint(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%v: expected InvalidArgument, got %v", req, err)
+		}
+	}
+}
+
+func TestUserDataValidation(t *testing.T) {
+	s := &Server{}
+	if _, err := s.GetUserTaskIds(context.Background(), &pb.UserDataRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without login, got %v", err)
+	}
+	if _, err := s.DeleteUserData(context.Background(), &pb.UserDataRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without login, got %v", err)
+	}
+	if _, err := s.AdminDeleteTask(context.Background(), &pb.AdminTaskRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without id, got %v", err)
+	}
+	if _, err := s.AdminRestoreTask(context.Background(), &pb.AdminTaskRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without id, got %v", err)
+	}
+}
+
+func TestLogCalls(t *testing.T) {
+	var logs bytes.Buffer
+	interceptor := logCalls(slog.New(slog.NewJSONHandler(&logs, nil)))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestIDMetadata, "req-1"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/TaskService/GetTask"}
+
+	_, err := interceptor(ctx, nil, info, func(context.Context, any) (any, error) {
+		return nil, status.Error(codes.NotFound, "task not found")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected the handler error, got %v", err)
+	}
+
+	var line struct {
+		RequestID string `json:"request_id"`
+		Method    string `json:"method"`
+		Code      string `json:"code"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", logs.String(), err)
+	}
+	if line.RequestID != "req-1" || line.Method != info.FullMethod || line.Code != "NotFound" {
+		t.Errorf("unexpected log line %+v", line)
+	}
+}
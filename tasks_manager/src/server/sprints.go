@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	pb "tasksmanager/proto"
+	"tasksmanager/src/database"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultSprintLength    = 14 * 24 * time.Hour
+	defaultVelocitySprints = 3
+)
+
+// checkEstimate validates an estimate of a created or updated task. A nil
+// estimate is allowed and means it is not changed.
+func checkEstimate(estimate *float64, unit string) error {
+	if estimate != nil && (*estimate < 0 || math.IsNaN(*estimate) || math.IsInf(*estimate, 0)) {
+		return invalidArgument("estimate must be a non-negative number")
+	}
+	if unit != "" && !slices.Contains(database.EstimateUnits, unit) {
+		return status.Errorf(codes.InvalidArgument, "estimate_unit must be one of %v", database.EstimateUnits)
+	}
+	return nil
+}
+
+func sprintToProto(sprint *database.Sprint) *pb.Sprint {
+	result := &pb.Sprint{
+		Id:              uint32(sprint.ID),
+		Author:          sprint.Author,
+		Project:         sprint.Project,
+		Name:            sprint.Name,
+		State:           sprint.State,
+		StartDate:       sprint.StartDate.Format(database.DateLayout),
+		EndDate:         sprint.EndDate.Format(database.DateLayout),
+		CommittedPoints: sprint.CommittedPoints,
+		CommittedHours:  sprint.CommittedHours,
+		CompletedPoints: sprint.CompletedPoints,
+		CompletedHours:  sprint.CompletedHours,
+	}
+	if sprint.StartedAt != nil {
+		result.StartedAt = timestamppb.New(*sprint.StartedAt)
+	}
+	if sprint.ClosedAt != nil {
+		result.ClosedAt = timestamppb.New(*sprint.ClosedAt)
+	}
+	return result
+}
+
+func sprintsToProto(sprints []database.Sprint) []*pb.Sprint {
+	result := make([]*pb.Sprint, 0, len(sprints))
+	for _, sprint := range sprints {
+		result = append(result, sprintToProto(&sprint))
+	}
+	return result
+}
+
+// averageVelocity returns mean completed points and hours of the sprints.
+func averageVelocity(sprints []database.Sprint) (points, hours float64) {
+	if len(sprints) == 0 {
+		return 0, 0
+	}
+	for _, sprint := range sprints {
+		points += sprint.CompletedPoints
+		hours += sprint.CompletedHours
+	}
+	n := float64(len(sprints))
+	return points / n, hours / n
+}
+
+func (s *Server) CreateSprint(ctx context.Context, req *pb.CreateSprintRequest) (*pb.Sprint, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, invalidArgument("name is required")
+	}
+	start, err := time.Parse(database.DateLayout, req.StartDate)
+	if err != nil {
+		return nil, invalidArgument("start_date must be a date like 2026-01-31")
+	}
+	end := start.Add(defaultSprintLength)
+	if req.EndDate != "" {
+		end, err = time.Parse(database.DateLayout, req.EndDate)
+		if err != nil {
+			return nil, invalidArgument("end_date must be a date like 2026-01-31")
+		}
+	}
+	if !end.After(start) {
+		return nil, invalidArgument("end_date must be after start_date")
+	}
+
+	sprint, err := s.db.WithContext(ctx).CreateSprint(&database.Sprint{
+		Author:    req.Author,
+		Project:   req.Project,
+		Name:      name,
+		StartDate: start,
+		EndDate:   end,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return sprintToProto(sprint), nil
+}
+
+func (s *Server) GetSprints(ctx context.Context, req *pb.GetSprintsRequest) (*pb.GetSprintsResponse, error) {
+	sprints, err := s.db.WithContext(ctx).GetSprints(req.Project)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.GetSprintsResponse{Sprints: sprintsToProto(sprints)}, nil
+}
+
+func (s *Server) StartSprint(ctx context.Context, req *pb.StartSprintRequest) (*emptypb.Empty, error) {
+	if req.Id == 0 {
+		return nil, invalidArgument("id is required")
+	}
+	if err := s.db.WithContext(ctx).StartSprint(uint(req.Id), req.Author); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) CloseSprint(ctx context.Context, req *pb.CloseSprintRequest) (*pb.CloseSprintResponse, error) {
+	if req.Id == 0 {
+		return nil, invalidArgument("id is required")
+	}
+	if req.NextSprintId == req.Id {
+		return nil, invalidArgument("next_sprint_id must differ from id")
+	}
+	moved, err := s.db.WithContext(ctx).CloseSprint(uint(req.Id), req.Author, uint(req.NextSprintId))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.CloseSprintResponse{MovedTasks: uint32(moved)}, nil
+}
+
+func (s *Server) PlanTask(ctx context.Context, req *pb.PlanTaskRequest) (*emptypb.Empty, error) {
+	if req.TaskId == 0 {
+		return nil, invalidArgument("task_id is required")
+	}
+	if err := s.db.WithContext(ctx).PlanTask(uint(req.TaskId), req.Author, uint(req.SprintId)); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) GetVelocity(ctx context.Context, req *pb.GetVelocityRequest) (*pb.GetVelocityResponse, error) {
+	n := int(req.Sprints)
+	if n == 0 {
+		n = defaultVelocitySprints
+	}
+	sprints, err := s.db.WithContext(ctx).GetClosedSprints(req.Project, n)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	points, hours := averageVelocity(sprints)
+	return &pb.GetVelocityResponse{
+		Sprints:       sprintsToProto(sprints),
+		AveragePoints: points,
+		AverageHours:  hours,
+	}, nil
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	pb "tasksmanager/proto"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (s *Server) GetUserTaskIds(ctx context.Context, req *pb.UserDataRequest) (*pb.GetUserTaskIdsResponse, error) {
+	if req.Login == "" {
+		return nil, invalidArgument("login is required")
+	}
+	ids, err := s.db.WithContext(ctx).GetUserTaskIDs(req.Login)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.GetUserTaskIdsResponse{Ids: make([]uint32, 0, len(ids))}
+	for _, id := range ids {
+		resp.Ids = append(resp.Ids, uint32(id))
+	}
+	return resp, nil
+}
+
+func (s *Server) DeleteUserData(ctx context.Context, req *pb.UserDataRequest) (*emptypb.Empty, error) {
+	if req.Login == "" {
+		return nil, invalidArgument("login is required")
+	}
+	if err := s.db.WithContext(ctx).DeleteUserData(req.Login); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) AdminDeleteTask(ctx context.Context, req *pb.AdminTaskRequest) (*emptypb.Empty, error) {
+	if req.Id == 0 {
+		return nil, invalidArgument("id is required")
+	}
+	if err := s.db.WithContext(ctx).AdminDeleteTask(uint(req.Id)); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) AdminRestoreTask(ctx context.Context, req *pb.AdminTaskRequest) (*emptypb.Empty, error) {
+	if req.Id == 0 {
+		return nil, invalidArgument("id is required")
+	}
+	if err := s.db.WithContext(ctx).RestoreTask(uint(req.Id)); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
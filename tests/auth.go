@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// Client is a user of the service with its own cookies, for flows that
+// end sessions and so can not share the TaskManager's.
+type Client struct {
+	addr     string
+	login    string
+	password string
+	access   string
+	refresh  string
+	client   *http.Client
+}
+
+func NewClient(addr string, generator Generator) *Client {
+	c := &Client{
+		addr:     addr,
+		login:    generator.GenerateLogin(),
+		password: generator.GeneratePassword(),
+		client:   &http.Client{},
+	}
+	c.expect(c.do(http.MethodPost, "/register", RegisterRequest{Login: c.login, Password: c.password}), http.StatusCreated, "register")
+	c.Login()
+	return c
+}
+
+// Login starts a new session of the client.
+func (c *Client) Login() {
+	c.expect(c.do(http.MethodPost, "/login", RegisterRequest{Login: c.login, Password: c.password}), http.StatusOK, "login")
+}
+
+// NewSession logs the same user in on another client, like from a second
+// device.
+func (c *Client) NewSession() *Client {
+	other := &Client{addr: c.addr, login: c.login, password: c.password, client: &http.Client{}}
+	other.Login()
+	return other
+}
+
+type Response struct {
+	*http.Response
+	Body []byte
+}
+
+// do sends a JSON request with the client's cookies and keeps tokens the
+// service sets in the response.
+func (c *Client) do(method, path string, body any, headers ...string) Response {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.addr+path, reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	if req.Header.Get("Authorization") == "" && req.Header.Get("Cookie") == "" {
+		req.Header.Set("Cookie", fmt.Sprintf("jwt=%s; refresh_token=%s", c.access, c.refresh))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, cookie := range resp.Cookies() {
+		switch cookie.Name {
+		case "jwt":
+			c.access = cookie.Value
+		case "refresh_token":
+			c.refresh = cookie.Value
+		}
+	}
+	return Response{Response: resp, Body: data}
+}
+
+// withTokens sends a request with the given tokens instead of the current
+// ones, to check that old tokens are refused.
+func (c *Client) withTokens(access, refresh, method, path string, body any) Response {
+	current, currentRefresh := c.access, c.refresh
+	defer func() { c.access, c.refresh = current, currentRefresh }()
+	return c.do(method, path, body, "Cookie", fmt.Sprintf("jwt=%s; refresh_token=%s", access, refresh))
+}
+
+func (c *Client) expect(resp Response, status int, what string) {
+	if resp.StatusCode != status {
+		log.Fatalf("%s: expected %d, got %d: %s", what, status, resp.StatusCode, resp.Body)
+	}
+}
+
+type AccessTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type AccessTokenResponse struct {
+	Token string `json:"token"`
+}
+
+func (c *Client) createAccessToken(scopes ...string) string {
+	resp := c.do(http.MethodPost, "/tokens", AccessTokenRequest{Name: "tests", Scopes: scopes})
+	c.expect(resp, http.StatusCreated, "create access token")
+	var token AccessTokenResponse
+	if err := json.Unmarshal(resp.Body, &token); err != nil {
+		log.Fatal(err)
+	}
+	return token.Token
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// TestChangePasswordRevokesTokens checks that every credential issued
+// before a password change stops working, personal access tokens included.
+func TestChangePasswordRevokesTokens(addr string, generator Generator) {
+	c := NewClient(addr, generator)
+	pat := c.createAccessToken("tasks:read")
+	c.expect(c.do(http.MethodGet, "/tasks", nil, "Authorization", "Bearer "+pat), http.StatusOK, "tasks with access token")
+
+	oldAccess, oldRefresh := c.access, c.refresh
+	password := generator.GeneratePassword() + "X"
+	c.expect(c.do(http.MethodPost, "/password/change", ChangePasswordRequest{CurrentPassword: c.password, NewPassword: password}),
+		http.StatusOK, "change password")
+	c.password = password
+
+	c.expect(c.withTokens(oldAccess, "", http.MethodGet, "/info", nil), http.StatusUnauthorized, "info with old JWT")
+	c.expect(c.withTokens("", oldRefresh, http.MethodPost, "/refresh", nil), http.StatusUnauthorized, "refresh with old token")
+	c.expect(c.do(http.MethodGet, "/tasks", nil, "Authorization", "Bearer "+pat), http.StatusUnauthorized, "tasks with old access token")
+	c.expect(c.do(http.MethodGet, "/info", nil), http.StatusOK, "info with new JWT")
+}
+
+type RevokeRequest struct {
+	Token string `json:"token"`
+}
+
+// TestRevokeToken checks that a revoked access token is refused while the
+// session goes on with a fresh one.
+func TestRevokeToken(c *Client) {
+	c.Login()
+	old := c.access
+	c.expect(c.do(http.MethodPost, "/refresh", nil), http.StatusOK, "refresh")
+	c.expect(c.do(http.MethodPost, "/revoke", RevokeRequest{Token: old}), http.StatusNoContent, "revoke")
+
+	c.expect(c.withTokens(old, "", http.MethodGet, "/info", nil), http.StatusUnauthorized, "info with revoked JWT")
+	c.expect(c.do(http.MethodGet, "/info", nil), http.StatusOK, "info with new JWT")
+}
+
+// TestRefreshRotation checks that a refresh token works once and that
+// presenting a used one ends the whole session.
+func TestRefreshRotation(c *Client) {
+	c.Login()
+	first := c.refresh
+	c.expect(c.do(http.MethodPost, "/refresh", nil), http.StatusOK, "refresh")
+	if c.refresh == "" || c.refresh == first {
+		log.Fatal("refresh: expected a new refresh token")
+	}
+	access, second := c.access, c.refresh
+	c.expect(c.do(http.MethodGet, "/info", nil), http.StatusOK, "info after refresh")
+
+	c.expect(c.withTokens("", first, http.MethodPost, "/refresh", nil), http.StatusUnauthorized, "refresh with used token")
+	c.expect(c.withTokens("", second, http.MethodPost, "/refresh", nil), http.StatusUnauthorized, "refresh after reuse")
+	c.expect(c.withTokens(access, "", http.MethodGet, "/info", nil), http.StatusUnauthorized, "info after reuse")
+}
+
+// TestLogoutAll checks that logging out everywhere ends other sessions of
+// the user too.
+func TestLogoutAll(c *Client) {
+	c.Login()
+	other := c.NewSession()
+	access, refresh := c.access, c.refresh
+	c.expect(c.do(http.MethodPost, "/logout-all", nil), http.StatusNoContent, "logout everywhere")
+
+	c.expect(c.withTokens(access, "", http.MethodGet, "/info", nil), http.StatusUnauthorized, "info after logout everywhere")
+	c.expect(c.withTokens("", refresh, http.MethodPost, "/refresh", nil), http.StatusUnauthorized, "refresh after logout everywhere")
+	other.expect(other.do(http.MethodGet, "/info", nil), http.StatusUnauthorized, "info of other session")
+	other.expect(other.do(http.MethodPost, "/refresh", nil), http.StatusUnauthorized, "refresh of other session")
+}
+
+type SessionInfo struct {
+	ID      string `json:"id"`
+	Current bool   `json:"current"`
+}
+
+func (c *Client) sessions() []SessionInfo {
+	resp := c.do(http.MethodGet, "/sessions", nil)
+	c.expect(resp, http.StatusOK, "sessions")
+	var sessions []SessionInfo
+	if err := json.Unmarshal(resp.Body, &sessions); err != nil {
+		log.Fatal(err)
+	}
+	return sessions
+}
+
+// TestRevokeSession checks that revoking a session from another device
+// refuses its tokens at once and leaves the current session alone.
+func TestRevokeSession(c *Client) {
+	c.Login()
+	other := c.NewSession()
+
+	var id string
+	for _, session := range c.sessions() {
+		if !session.Current {
+			id = session.ID
+		}
+	}
+	if id == "" {
+		log.Fatal("sessions: expected the other session listed")
+	}
+	c.expect(c.do(http.MethodDelete, "/sessions?id="+id, nil), http.StatusNoContent, "revoke session")
+
+	other.expect(other.do(http.MethodGet, "/info", nil), http.StatusUnauthorized, "info of revoked session")
+	other.expect(other.do(http.MethodPost, "/refresh", nil), http.StatusUnauthorized, "refresh of revoked session")
+	c.expect(c.do(http.MethodGet, "/info", nil), http.StatusOK, "info of current session")
+	for _, session := range c.sessions() {
+		if session.ID == id {
+			log.Fatal("sessions: expected the revoked session gone")
+		}
+	}
+}
@@ -27,6 +27,15 @@ func main() {
 	manager.TestAddAndCheckTask()
 	manager.TestLikeAndCheckTask()
 
+	TestChangePasswordRevokesTokens(*addr, generator)
+
+	// One user for the session tests, registrations are throttled.
+	user := NewClient(*addr, generator)
+	TestRevokeToken(user)
+	TestRefreshRotation(user)
+	TestLogoutAll(user)
+	TestRevokeSession(user)
+
 	fmt.Println("All tests passed")
 }
 
@@ -0,0 +1,87 @@
+package main
+
+import (
+	statbroker "statistics/src/broker"
+	"statistics/src/database"
+	"sync"
+	"testing"
+	"time"
+	userbroker "userservice/src/broker"
+)
+
+// pipe carries messages of user service's in-memory publisher to statistics
+// service's in-memory subscriber, like the Stat topic does between them.
+func pipe(from *userbroker.ChannelPublisher, to *statbroker.ChannelSubscriber) {
+	defer to.Close()
+	for msg := range from.Messages() {
+		to.Publish(statbroker.Message(msg))
+	}
+}
+
+type statStore struct {
+	mu      sync.Mutex
+	likes   []database.Statistic
+	views   []database.Statistic
+	deleted []string
+}
+
+func (s *statStore) EnsureLike(stat database.Statistic) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.likes = append(s.likes, stat)
+	return nil
+}
+
+func (s *statStore) EnsureView(stat database.Statistic) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.views = append(s.views, stat)
+	return nil
+}
+
+func (s *statStore) DeleteUserStats(id, login string, taskIDs []uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func TestStatPipeline(t *testing.T) {
+	publisher := userbroker.NewChannelPublisher(10)
+	subscriber := statbroker.NewChannelSubscriber(10)
+	go pipe(publisher, subscriber)
+
+	store := &statStore{}
+	consumed := make(chan struct{})
+	go func() {
+		statbroker.New(subscriber).Consume(store)
+		close(consumed)
+	}()
+
+	sender := userbroker.New(publisher)
+	if err := sender.SendLike(userbroker.Statistic{Login: "kek", TaskID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.SendView(userbroker.Statistic{Login: "lol", TaskID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.SendUserDeletion(userbroker.UserDeletion{ID: "d1", Login: "kek", TaskIDs: []uint32{1}}); err != nil {
+		t.Fatal(err)
+	}
+	publisher.Close()
+
+	select {
+	case <-consumed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected all messages consumed")
+	}
+	if len(store.likes) != 1 || store.likes[0] != (database.Statistic{Login: "kek", TaskID: 1}) {
+		t.Errorf("unexpected likes: %#v", store.likes)
+	}
+	if len(store.views) != 1 || store.views[0] != (database.Statistic{Login: "lol", TaskID: 2}) {
+		t.Errorf("unexpected views: %#v", store.views)
+	}
+	if len(store.deleted) != 1 || store.deleted[0] != "d1" {
+		t.Errorf("unexpected deletions: %#v", store.deleted)
+	}
+}
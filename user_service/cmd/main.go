@@ -1,21 +1,146 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+	"userservice/src/auth"
+	"userservice/src/broker"
 	"userservice/src/database"
+	"userservice/src/mailer"
+	"userservice/src/middleware"
+	"userservice/src/migrate"
+	"userservice/src/oidc"
 	"userservice/src/server"
+	"userservice/src/throttle"
 )
 
 func main() {
 	port := flag.Int("port", 8080, "Port of user service's server.")
+	autoMigrate := flag.Bool("auto-migrate", true, "Apply pending database migrations on start.")
 	flag.Parse()
 
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
+	if flag.Arg(0) == "keygen" {
+		generateKey(flag.Args()[1:])
+		return
+	}
+
 	db := database.New()
-	server, close := server.New(db)
-	defer close()
+	migrator, err := db.SchemaMigrator()
+	if err != nil {
+		log.Fatalf("Can not load migrations: %v", err)
+	}
+	if flag.Arg(0) == "migrate" {
+		if err := migrate.Run(context.Background(), migrator, flag.Args()[1:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if *autoMigrate {
+		if err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+	}
+
+	if err := db.PromoteAdmins(adminsFromEnv()); err != nil {
+		log.Fatalf("Can not promote admins: %v", err)
+	}
+
+	b, closeBroker, err := broker.FromEnv()
+	if err != nil {
+		panic(err)
+	}
+	defer closeBroker()
+
+	keys, err := auth.KeysFromEnv()
+	if err != nil {
+		log.Fatalf("Can not load JWT keys: %v", err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go keys.Watch(time.Minute, stop)
+
+	mailSecret, err := auth.MailSecretFromEnv()
+	if err != nil {
+		log.Fatalf("Can not load mail token secret: %v", err)
+	}
+	mail, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatalf("Can not create mailer: %v", err)
+	}
+
+	var attempts throttle.Store
+	switch kind := os.Getenv("THROTTLE_STORE"); kind {
+	case "", "memory":
+		attempts = throttle.NewMemoryStore()
+	case "postgres":
+		attempts = db.AttemptStore()
+	default:
+		log.Fatalf("Unknown throttle store %q", kind)
+	}
+
+	configs, err := oidc.ConfigsFromEnv()
+	if err != nil {
+		log.Fatalf("Can not load OIDC providers: %v", err)
+	}
+	providers := make([]*oidc.Provider, 0, len(configs))
+	for _, config := range configs {
+		providers = append(providers, oidc.NewProvider(config, nil))
+	}
+
+	timeouts, err := middleware.TimeoutsFromEnv()
+	if err != nil {
+		log.Fatalf("Can not load request timeouts: %v", err)
+	}
+
+	proxies, err := middleware.ProxiesFromEnv()
+	if err != nil {
+		log.Fatalf("Can not load trusted proxies: %v", err)
+	}
+
+	server := server.New(db, b, keys, mailSecret, mail, attempts, providers, timeouts, proxies)
 	server.Register()
 
 	addr := fmt.Sprintf("0.0.0.0:%d", *port)
 	server.Listen(addr)
 }
+
+// generateKey handles "keygen <dir> [not-before]". The new key starts
+// signing tokens at not-before (RFC 3339), right away by default.
+func generateKey(args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		log.Fatal("Usage: keygen <dir> [not-before]")
+	}
+	notBefore := time.Now()
+	if len(args) == 2 {
+		var err error
+		notBefore, err = time.Parse(time.RFC3339, args[1])
+		if err != nil {
+			log.Fatalf("Bad not-before: %v", err)
+		}
+	}
+	id, err := auth.GenerateKey(args[0], notBefore)
+	if err != nil {
+		log.Fatalf("Can not generate key: %v", err)
+	}
+	fmt.Println(id)
+}
+
+// adminsFromEnv reads ADMIN_LOGINS, a comma separated list of users who
+// become administrators on start. Roles given through the API are kept.
+func adminsFromEnv() []string {
+	var admins []string
+	for _, login := range strings.Split(os.Getenv("ADMIN_LOGINS"), ",") {
+		if login = strings.TrimSpace(login); login != "" {
+			admins = append(admins, login)
+		}
+	}
+	return admins
+}
@@ -5,6 +5,7 @@ go 1.22.0
 require (
 	github.com/IBM/sarama v1.43.2
 	github.com/go-chi/chi/v5 v5.0.12
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	google.golang.org/grpc v1.62.1
 	google.golang.org/protobuf v1.34.2
 )
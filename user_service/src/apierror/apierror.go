@@ -0,0 +1,85 @@
+package apierror
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Code string
+
+const (
+	InvalidArgument    Code = "invalid_argument"
+	Unauthenticated    Code = "unauthenticated"
+	PermissionDenied   Code = "permission_denied"
+	NotFound           Code = "not_found"
+	AlreadyExists      Code = "already_exists"
+	FailedPrecondition Code = "failed_precondition"
+	Aborted            Code = "aborted"
+	ResourceExhausted  Code = "resource_exhausted"
+	Canceled           Code = "canceled"
+	DeadlineExceeded   Code = "deadline_exceeded"
+	Unimplemented      Code = "unimplemented"
+	Unavailable        Code = "unavailable"
+	Internal           Code = "internal"
+)
+
+type Body struct {
+	Error Detail `json:"error"`
+}
+
+type Detail struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+type mapping struct {
+	status int
+	code   Code
+}
+
+var grpcMapping = map[codes.Code]mapping{
+	codes.InvalidArgument:    {http.StatusBadRequest, InvalidArgument},
+	codes.OutOfRange:         {http.StatusBadRequest, InvalidArgument},
+	codes.FailedPrecondition: {http.StatusBadRequest, FailedPrecondition},
+	codes.Unauthenticated:    {http.StatusUnauthorized, Unauthenticated},
+	codes.PermissionDenied:   {http.StatusForbidden, PermissionDenied},
+	codes.NotFound:           {http.StatusNotFound, NotFound},
+	codes.AlreadyExists:      {http.StatusConflict, AlreadyExists},
+	codes.Aborted:            {http.StatusConflict, Aborted},
+	codes.ResourceExhausted:  {http.StatusTooManyRequests, ResourceExhausted},
+	codes.Canceled:           {499, Canceled},
+	codes.DeadlineExceeded:   {http.StatusGatewayTimeout, DeadlineExceeded},
+	codes.Unimplemented:      {http.StatusNotImplemented, Unimplemented},
+	codes.Unavailable:        {http.StatusServiceUnavailable, Unavailable},
+}
+
+func Write(w http.ResponseWriter, httpStatus int, code Code, format string, args ...any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(Body{Error: Detail{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}})
+}
+
+// WriteGRPC translates an error returned by a downstream gRPC service into
+// the matching HTTP status. Message prefixes the status message with what.
+func WriteGRPC(w http.ResponseWriter, err error, what string) {
+	st := status.Convert(err)
+	m, ok := grpcMapping[st.Code()]
+	if !ok {
+		m = mapping{http.StatusInternalServerError, Internal}
+	}
+	Write(w, m.status, m.code, "%s: %s", what, st.Message())
+}
+
+func HTTPStatus(err error) int {
+	if m, ok := grpcMapping[status.Code(err)]; ok {
+		return m.status
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,47 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWriteGRPC(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   Code
+	}{
+		{status.Error(codes.NotFound, "task not found"), http.StatusNotFound, NotFound},
+		{status.Error(codes.PermissionDenied, "permission denied"), http.StatusForbidden, PermissionDenied},
+		{status.Error(codes.InvalidArgument, "id is required"), http.StatusBadRequest, InvalidArgument},
+		{status.Error(codes.FailedPrecondition, "closed"), http.StatusBadRequest, FailedPrecondition},
+		{status.Error(codes.DeadlineExceeded, "slow"), http.StatusGatewayTimeout, DeadlineExceeded},
+		{status.Error(codes.Internal, "boom"), http.StatusInternalServerError, Internal},
+		{errors.New("plain"), http.StatusInternalServerError, Internal},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		WriteGRPC(w, test.err, "Can not get task")
+
+		if w.Code != test.status {
+			t.Errorf("%v: expected status %d, got %d", test.err, test.status, w.Code)
+		}
+		var body Body
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("%v: can not decode body: %v", test.err, err)
+		}
+		if body.Error.Code != test.code {
+			t.Errorf("%v: expected code %s, got %s", test.err, test.code, body.Error.Code)
+		}
+		if w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%v: expected json content type", test.err)
+		}
+	}
+}
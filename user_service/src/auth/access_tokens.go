@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"userservice/src/database"
+)
+
+// AccessTokenPrefix marks personal access tokens, so they are told apart
+// from JWTs in the Authorization header and are easy to find by secret
+// scanners.
+const AccessTokenPrefix = "tt_pat_"
+
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+	ScopeStatsRead  = "stats:read"
+	ScopeUsersRead  = "users:read"
+)
+
+var Scopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeStatsRead, ScopeUsersRead}
+
+var errNoScopes = errors.New("at least one scope is required")
+
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errNoScopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return fmt.Errorf("unknown scope %q, expected one of %v", scope, Scopes)
+		}
+	}
+	return nil
+}
+
+// hasScopes reports whether granted covers every required scope.
+func hasScopes(granted, required []string) bool {
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateAccessToken mints a personal access token. The token itself is
+// returned only here, the database keeps its hash.
+func (a *AuthService) CreateAccessToken(login, name string, scopes []string, expiresAt *time.Time) (string, database.AccessToken, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", database.AccessToken{}, err
+	}
+	token := AccessTokenPrefix + secret
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	info, err := a.db.CreateAccessToken(login, name, hashToken(token), slices.Compact(scopes), expiresAt)
+	if err != nil {
+		return "", database.AccessToken{}, err
+	}
+	return token, info, nil
+}
+
+func (a *AuthService) GetAccessTokens(login string) ([]database.AccessToken, error) {
+	return a.db.GetAccessTokens(login)
+}
+
+func (a *AuthService) DeleteAccessToken(login string, id uint) error {
+	return a.db.DeleteAccessToken(login, id)
+}
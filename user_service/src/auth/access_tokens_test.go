@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateScopes(t *testing.T) {
+	if err := ValidateScopes([]string{ScopeTasksRead, ScopeStatsRead}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := ValidateScopes(nil); err == nil {
+		t.Error("expected error for no scopes")
+	}
+	if err := ValidateScopes([]string{ScopeTasksRead, "admin"}); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+}
+
+func TestHasScopes(t *testing.T) {
+	granted := []string{ScopeTasksRead, ScopeStatsRead}
+	if !hasScopes(granted, []string{ScopeTasksRead}) {
+		t.Error("expected tasks:read to be granted")
+	}
+	if hasScopes(granted, []string{ScopeTasksRead, ScopeTasksWrite}) {
+		t.Error("expected tasks:write to be missing")
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		cookie string
+		token  string
+		status int
+	}{
+		{name: "bearer", header: "Bearer tt_pat_abc", token: "tt_pat_abc"},
+		{name: "lowercase scheme", header: "bearer abc", token: "abc"},
+		{name: "header wins", header: "Bearer abc", cookie: "def", token: "abc"},
+		{name: "cookie", cookie: "def", token: "def"},
+		{name: "basic", header: "Basic a2VrOmtlaw==", status: http.StatusUnauthorized},
+		{name: "empty bearer", header: "Bearer", status: http.StatusUnauthorized},
+		{name: "nothing", status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: AccessCookie, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+
+			token, ok := requestToken(w, r)
+			if tt.status != 0 {
+				if ok || w.Code != tt.status {
+					t.Errorf("expected status %d, got %d", tt.status, w.Code)
+				}
+				return
+			}
+			if !ok || token != tt.token {
+				t.Errorf("expected token %q, got %q", tt.token, token)
+			}
+		})
+	}
+}
+
+func TestCheckPublicIgnoresSessions(t *testing.T) {
+	as := &AuthService{}
+	for _, header := range []string{"", "Bearer not-a-jwt", "Basic a2VrOmtlaw=="} {
+		r := httptest.NewRequest(http.MethodGet, "/top-tasks", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		r.AddCookie(&http.Cookie{Name: AccessCookie, Value: "expired"})
+		w := httptest.NewRecorder()
+
+		if !as.CheckPublic(w, r, ScopeStatsRead) || w.Code != http.StatusOK {
+			t.Errorf("%q: expected the request let through, got %d", header, w.Code)
+		}
+	}
+}
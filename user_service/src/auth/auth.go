@@ -1,18 +1,21 @@
 package auth
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"strings"
 	"time"
+	"userservice/src/apierror"
 	"userservice/src/database"
+	"userservice/src/middleware"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/go-ozzo/ozzo-validation/is"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 const (
@@ -26,17 +29,17 @@ const (
 var tokenTTL time.Duration = 30 * time.Minute
 
 type AuthService struct {
-	db     *database.DataBase
-	secret *rsa.PrivateKey
+	db         *database.DataBase
+	keys       *KeySet
+	mailSecret []byte
 }
 
-func New(db *database.DataBase) *AuthService {
-	jwtPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		panic(err)
-	}
+func New(db *database.DataBase, keys *KeySet, mailSecret []byte) *AuthService {
+	return &AuthService{db: db, keys: keys, mailSecret: mailSecret}
+}
 
-	return &AuthService{db: db, secret: jwtPrivate}
+func (a *AuthService) Keys() *KeySet {
+	return a.keys
 }
 
 func (a *AuthService) ValidateLogin(login string) error {
@@ -129,12 +132,70 @@ func (a *AuthService) CheckPassword(login, password string) error {
 	return nil
 }
 
-func (a *AuthService) CreateToken(login string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
-		"login": login,
-		"exp":   time.Now().Add(tokenTTL).Unix(),
+// ChangePassword replaces the password after checking the current one. The
+// new password must be validated already. Sessions and access tokens issued
+// so far stop working.
+func (a *AuthService) ChangePassword(login, current, password string) error {
+	passwordHash, err := a.db.GetPasswordHash(login)
+	if err != nil {
+		return err
+	}
+	if len(passwordHash) == 0 {
+		return ErrNoPassword
+	}
+	if bcrypt.CompareHashAndPassword(passwordHash, []byte(current)) != nil {
+		return ErrWrongPassword
+	}
+
+	passwordHash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return a.db.ChangePassword(login, passwordHash)
+}
+
+// Claims of access tokens. Generation must match the user's token
+// generation, which is bumped to invalidate all issued tokens at once.
+// SessionID names the session the token belongs to; the session must not
+// be revoked.
+type Claims struct {
+	Login      string `json:"login"`
+	Generation int    `json:"gen"`
+	SessionID  string `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (a *AuthService) CreateToken(login, sessionID string) (string, error) {
+	generation, err := a.db.GetTokenGeneration(login)
+	if err != nil {
+		return "", err
+	}
+	return a.createAccessToken(login, sessionID, generation)
+}
+
+func (a *AuthService) createAccessToken(login, sessionID string, generation int) (string, error) {
+	now := time.Now()
+	kid, key, err := a.keys.Signing(now)
+	if err != nil {
+		return "", err
+	}
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, Claims{
+		Login:      login,
+		Generation: generation,
+		SessionID:  sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenTTL)),
+		},
 	})
-	tokenString, err := token.SignedString(a.secret)
+	token.Header["kid"] = kid
+	tokenString, err := token.SignedString(key)
 	if err != nil {
 		return "", err
 	}
@@ -142,48 +203,171 @@ func (a *AuthService) CreateToken(login string) (string, error) {
 	return tokenString, nil
 }
 
-func (a *AuthService) CheckAuth(w http.ResponseWriter, r *http.Request) (login string, authorized bool) {
-	cookie, err := r.Cookie("jwt")
+// parseToken verifies the signature with the key named in the kid header
+// and returns the token claims.
+func (a *AuthService) parseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return a.keys.Public(kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithExpirationRequired())
 	if err != nil {
-		if err == http.ErrNoCookie {
-			w.WriteHeader(http.StatusUnauthorized)
-			fmt.Fprintf(w, "No JWT token: %v", err)
-			return
+		return nil, err
+	}
+	if claims.ID == "" {
+		return nil, errors.New("token has no jti")
+	}
+	return claims, nil
+}
+
+// checkClaims makes sure the token was neither revoked one by one, with its
+// session nor by logging out everywhere.
+func (a *AuthService) checkClaims(claims *Claims) (int, error) {
+	generation, err := a.db.GetTokenGeneration(claims.Login)
+	if err == gorm.ErrRecordNotFound {
+		return http.StatusUnauthorized, errors.New("can not find user by JWT token")
+	} else if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("can not find user by JWT token: %w", err)
+	}
+	if claims.Generation != generation {
+		return http.StatusUnauthorized, errors.New("JWT token revoked")
+	}
+
+	if claims.SessionID != "" {
+		active, err := a.db.TouchSession(claims.SessionID, time.Now())
+		if err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("can not check session: %w", err)
+		}
+		if !active {
+			return http.StatusUnauthorized, errors.New("session revoked")
 		}
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse JWT cookie: %v", err)
-		return
 	}
 
-	token, err := jwt.Parse(cookie.Value, func(token *jwt.Token) (interface{}, error) {
-		return &a.secret.PublicKey, nil
-	})
+	revoked, err := a.db.IsTokenRevoked(claims.ID)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse JWT cookie: %v", err)
-		return
+		return http.StatusInternalServerError, fmt.Errorf("can not check JWT token: %w", err)
+	}
+	if revoked {
+		return http.StatusUnauthorized, errors.New("JWT token revoked")
+	}
+	return http.StatusOK, nil
+}
+
+// CheckAuth authenticates the request by the jwt cookie or an
+// Authorization: Bearer header carrying a JWT or a personal access token.
+// A session JWT may do anything; an access token must grant all scopes, and
+// handlers that pass no scopes, like account management, refuse it.
+func (a *AuthService) CheckAuth(w http.ResponseWriter, r *http.Request, scopes ...string) (login string, authorized bool) {
+	token, ok := requestToken(w, r)
+	if !ok {
+		return "", false
 	}
 
-	if !token.Valid {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, "JWT token not valid")
-		return
+	if strings.HasPrefix(token, AccessTokenPrefix) {
+		login, authorized = a.checkAccessToken(w, token, scopes)
+	} else if claims, ok := a.checkSession(w, token); ok {
+		login, authorized = claims.Login, true
+	}
+	if authorized {
+		middleware.SetUser(r.Context(), login)
 	}
+	return login, authorized
+}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
+// CheckPublic guards a handler open to anonymous users. Only a personal
+// access token is checked: it must be valid and grant all scopes. Cookies
+// and session JWTs are ignored, as the handler needs no user.
+func (a *AuthService) CheckPublic(w http.ResponseWriter, r *http.Request, scopes ...string) bool {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	token = strings.TrimSpace(token)
+	if !strings.EqualFold(scheme, "Bearer") || !strings.HasPrefix(token, AccessTokenPrefix) {
+		return true
+	}
+	login, ok := a.checkAccessToken(w, token, scopes)
+	if ok {
+		middleware.SetUser(r.Context(), login)
+	}
+	return ok
+}
+
+// CheckAuthClaims is CheckAuth for handlers that need the whole session
+// token, like logout.
+func (a *AuthService) CheckAuthClaims(w http.ResponseWriter, r *http.Request) (*Claims, bool) {
+	token, ok := requestToken(w, r)
 	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, "Can not cast JWT token's claims")
-		return
+		return nil, false
+	}
+	if strings.HasPrefix(token, AccessTokenPrefix) {
+		apierror.Write(w, http.StatusForbidden, apierror.PermissionDenied, "Personal access tokens can not be used here")
+		return nil, false
 	}
+	claims, ok := a.checkSession(w, token)
+	if ok {
+		middleware.SetUser(r.Context(), claims.Login)
+	}
+	return claims, ok
+}
 
-	login = claims["login"].(string)
-	ok, err = a.db.UserExist(login)
-	if !ok || err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, "Can not find user by JWT token")
-		return
+func requestToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "Authorization header must be \"Bearer <token>\"")
+			return "", false
+		}
+		return strings.TrimSpace(token), true
+	}
+
+	cookie, err := r.Cookie(AccessCookie)
+	if err != nil {
+		if err == http.ErrNoCookie {
+			apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "No JWT token: %v", err)
+			return "", false
+		}
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse JWT cookie: %v", err)
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+func (a *AuthService) checkSession(w http.ResponseWriter, token string) (*Claims, bool) {
+	claims, err := a.parseToken(token)
+	if err != nil {
+		apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "Can not parse JWT token: %v", err)
+		return nil, false
+	}
+
+	if status, err := a.checkClaims(claims); err != nil {
+		code := apierror.Unauthenticated
+		if status == http.StatusInternalServerError {
+			code = apierror.Internal
+		}
+		apierror.Write(w, status, code, "%s", err)
+		return nil, false
+	}
+	return claims, true
+}
+
+func (a *AuthService) checkAccessToken(w http.ResponseWriter, token string, scopes []string) (string, bool) {
+	login, granted, err := a.db.FindAccessToken(hashToken(token))
+	if err == database.ErrAccessTokenNotFound {
+		apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "Access token is invalid or expired")
+		return "", false
+	} else if err == database.ErrUserDisabled {
+		apierror.Write(w, http.StatusForbidden, apierror.PermissionDenied, "Account is disabled")
+		return "", false
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not check access token: %v", err)
+		return "", false
+	}
+
+	if len(scopes) == 0 {
+		apierror.Write(w, http.StatusForbidden, apierror.PermissionDenied, "Personal access tokens can not be used here")
+		return "", false
+	}
+	if !hasScopes(granted, scopes) {
+		apierror.Write(w, http.StatusForbidden, apierror.PermissionDenied, "Access token lacks scopes %v", scopes)
+		return "", false
 	}
-	authorized = true
-	return
+	return login, true
 }
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"errors"
+	"time"
+	"userservice/src/database"
+	"userservice/src/deletion"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrWrongPassword = errors.New("wrong password")
+	ErrNoPassword    = errors.New("no password is set, use password reset")
+)
+
+// DeleteAccount starts deleting the user. Users with a password must
+// confirm with it; users who only sign in through an identity provider have
+// none. The deletion ID is random, so it can be used to check the progress
+// without a session.
+func (a *AuthService) DeleteAccount(login, password string) (*database.AccountDeletion, error) {
+	passwordHash, err := a.db.GetPasswordHash(login)
+	if err != nil {
+		return nil, err
+	}
+	if len(passwordHash) > 0 {
+		if bcrypt.CompareHashAndPassword(passwordHash, []byte(password)) != nil {
+			return nil, ErrWrongPassword
+		}
+	}
+
+	id, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	started := &database.AccountDeletion{
+		ID:            id,
+		Login:         login,
+		Step:          deletion.StepCollectTasks,
+		NextAttemptAt: time.Now(),
+	}
+	if err := a.db.StartAccountDeletion(started); err != nil {
+		return nil, err
+	}
+	return started, nil
+}
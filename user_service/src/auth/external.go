@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"userservice/src/database"
+	"userservice/src/oidc"
+
+	"gorm.io/gorm"
+)
+
+var ErrSealInvalid = errors.New("sealed value is invalid or expired")
+
+// ExternalLogin returns the local user of an external identity. A known
+// identity logs its user in. A new one is linked to linkTo, the user who is
+// logged in already, or else gets a new user provisioned just in time.
+func (a *AuthService) ExternalLogin(identity *oidc.Identity, linkTo string) (login string, err error) {
+	login, err = a.db.GetExternalLogin(identity.Provider, identity.Subject)
+	if err == nil {
+		if linkTo != "" && linkTo != login {
+			return "", database.ErrIdentityLinked
+		}
+		return login, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	if linkTo != "" {
+		return linkTo, a.db.LinkExternalIdentity(identity.Provider, identity.Subject, linkTo, identity.Email)
+	}
+	return a.db.ProvisionExternalUser(loginCandidates(identity), identity.Provider, identity.Subject, identity.Email, identity.EmailVerified)
+}
+
+// loginCandidates derives local logins from the preferred username or the
+// mail, falling back to a hash of the subject, with numbered variants for
+// taken ones.
+func loginCandidates(identity *oidc.Identity) []string {
+	sum := sha256.Sum256([]byte(identity.Provider + "\x00" + identity.Subject))
+	fallback := "user" + hex.EncodeToString(sum[:])[:12]
+
+	base := sanitizeLogin(identity.Username)
+	if base == "" {
+		local, _, _ := strings.Cut(identity.Email, "@")
+		base = sanitizeLogin(local)
+	}
+	if base == "" {
+		return []string{fallback}
+	}
+
+	candidates := []string{base}
+	for i := 2; i < 10; i++ {
+		suffix := fmt.Sprint(i)
+		candidates = append(candidates, truncate(base, loginMaxLen-len(suffix))+suffix)
+	}
+	return append(candidates, fallback)
+}
+
+// sanitizeLogin keeps ASCII letters and digits, the only characters
+// ValidateLogin allows, and returns "" if too little is left.
+func sanitizeLogin(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	login := truncate(b.String(), loginMaxLen)
+	if len(login) < loginMinLen {
+		return ""
+	}
+	return login
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// Seal signs v for purpose, so it can be handed to the client and come back
+// untouched, like the state of a login flow kept in a cookie.
+func (a *AuthService) Seal(purpose string, v interface{}, ttl time.Duration) (string, error) {
+	payload, err := json.Marshal(struct {
+		Purpose   string      `json:"p"`
+		ExpiresAt int64       `json:"e"`
+		Value     interface{} `json:"v"`
+	}{purpose, time.Now().Add(ttl).Unix(), v})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(a.mailMAC("sealed."+encoded)), nil
+}
+
+// Open checks a value sealed for purpose and decodes it into v.
+func (a *AuthService) Open(purpose, sealed string, v interface{}) error {
+	encoded, signature, found := strings.Cut(sealed, ".")
+	if !found {
+		return ErrSealInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, a.mailMAC("sealed."+encoded)) {
+		return ErrSealInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrSealInvalid
+	}
+
+	var envelope struct {
+		Purpose   string          `json:"p"`
+		ExpiresAt int64           `json:"e"`
+		Value     json.RawMessage `json:"v"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return ErrSealInvalid
+	}
+	if envelope.Purpose != purpose || time.Now().Unix() >= envelope.ExpiresAt {
+		return ErrSealInvalid
+	}
+	if err := json.Unmarshal(envelope.Value, v); err != nil {
+		return ErrSealInvalid
+	}
+	return nil
+}
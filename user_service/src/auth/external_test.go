@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"userservice/src/oidc"
+)
+
+func TestLoginCandidates(t *testing.T) {
+	candidates := loginCandidates(&oidc.Identity{Provider: "corp", Subject: "42", Username: "kek.lol", Email: "other@corp.example"})
+	if len(candidates) != 10 || candidates[0] != "keklol" || candidates[1] != "keklol2" {
+		t.Errorf("unexpected candidates %v", candidates)
+	}
+
+	candidates = loginCandidates(&oidc.Identity{Provider: "corp", Subject: "42", Email: "ке.k@corp.example"})
+	if len(candidates) != 1 || len(candidates[0]) != 16 {
+		t.Errorf("expected only the fallback, got %v", candidates)
+	}
+
+	long := loginCandidates(&oidc.Identity{Provider: "corp", Subject: "42", Username: "abcdefghijklmnopqrstuvwxyz0123456789"})
+	for _, login := range long {
+		if len(login) > loginMaxLen {
+			t.Errorf("login %q is too long", login)
+		}
+	}
+}
+
+func TestSeal(t *testing.T) {
+	as := &AuthService{mailSecret: []byte("secret")}
+	type flow struct {
+		State string `json:"state"`
+	}
+
+	sealed, err := as.Seal("flow", flow{State: "abc"}, time.Minute)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var opened flow
+	if err := as.Open("flow", sealed, &opened); err != nil || !reflect.DeepEqual(opened, flow{State: "abc"}) {
+		t.Errorf("expected %#v, got %#v, %v", flow{State: "abc"}, opened, err)
+	}
+	if err := as.Open("other", sealed, &opened); err != ErrSealInvalid {
+		t.Errorf("expected ErrSealInvalid, got %v", err)
+	}
+
+	// A mail token must not open as a sealed value and the other way round.
+	token, _ := as.MailToken(PurposeVerifyMail, "kek", "kek@mail.ru")
+	if err := as.Open(PurposeVerifyMail, token, &opened); err != ErrSealInvalid {
+		t.Errorf("expected ErrSealInvalid, got %v", err)
+	}
+	if _, err := as.parseMailToken(sealed, "flow", time.Now()); err != ErrMailTokenInvalid {
+		t.Errorf("expected ErrMailTokenInvalid, got %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// notBeforeHeader is an optional PEM header with the time (RFC 3339) a key
+// starts signing tokens. It lets all replicas rotate at the same moment
+// while the new public key is already published in JWKS.
+const notBeforeHeader = "Not-Before"
+
+const keyBits = 2048
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+type signingKey struct {
+	id        string
+	private   *rsa.PrivateKey
+	notBefore time.Time
+}
+
+// KeySet holds RSA keys for signing and verifying tokens. Every key verifies
+// tokens, the one with the latest Not-Before in the past signs them.
+type KeySet struct {
+	dir string
+
+	mu   sync.RWMutex
+	keys []signingKey
+}
+
+// KeysFromEnv loads keys from the JWT_KEYS_DIR directory of <kid>.pem files
+// or from a single JWT_PRIVATE_KEY PEM. An empty directory gets a new key on
+// the first start. Without both variables a key is generated in memory, so
+// tokens do not survive a restart.
+func KeysFromEnv() (*KeySet, error) {
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			id, err := GenerateKey(dir, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			slog.Info("Generated JWT signing key.", slog.String("key_id", id), slog.String("dir", dir))
+		}
+		return LoadKeys(dir)
+	}
+	if data := os.Getenv("JWT_PRIVATE_KEY"); data != "" {
+		key, err := parseKey("", []byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY: %w", err)
+		}
+		return &KeySet{keys: []signingKey{key}}, nil
+	}
+
+	slog.Warn("JWT_KEYS_DIR is not set, using a temporary signing key.")
+	private, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySet(private), nil
+}
+
+// NewKeySet returns a key set with a single key active right away.
+func NewKeySet(private *rsa.PrivateKey) *KeySet {
+	return &KeySet{keys: []signingKey{{id: thumbprint(&private.PublicKey), private: private}}}
+}
+
+func LoadKeys(dir string) (*KeySet, error) {
+	ks := &KeySet{dir: dir}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload rereads the key directory, picking up added and removed keys.
+func (ks *KeySet) Reload() error {
+	if ks.dir == "" {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make([]signingKey, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		key, err := parseKey(strings.TrimSuffix(filepath.Base(path), ".pem"), data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no keys in %s", ks.dir)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].notBefore.Before(keys[j].notBefore)
+	})
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the key directory every interval until stop is closed.
+func (ks *KeySet) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := ks.Reload(); err != nil {
+				slog.Error("Can not reload JWT keys.", slog.String("error", err.Error()))
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Signing returns the key id and the key tokens are signed with now.
+func (ks *KeySet) Signing(now time.Time) (string, *rsa.PrivateKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	for i := len(ks.keys) - 1; i >= 0; i-- {
+		if !ks.keys[i].notBefore.After(now) {
+			return ks.keys[i].id, ks.keys[i].private, nil
+		}
+	}
+	return "", nil, errors.New("no active signing key")
+}
+
+func (ks *KeySet) Public(kid string) (*rsa.PublicKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	for _, key := range ks.keys {
+		if key.id == kid {
+			return &key.private.PublicKey, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns public keys including not yet active ones, so verifiers
+// already know a key when it starts signing.
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		set.Keys = append(set.Keys, JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: key.id,
+			N:   base64.RawURLEncoding.EncodeToString(key.private.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.private.E)).Bytes()),
+		})
+	}
+	return set
+}
+
+// GenerateKey writes a new key to dir that starts signing at notBefore and
+// returns its id.
+func GenerateKey(dir string, notBefore time.Time) (string, error) {
+	private, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+	block := &pem.Block{
+		Type:    "PRIVATE KEY",
+		Headers: map[string]string{notBeforeHeader: notBefore.UTC().Format(time.RFC3339)},
+		Bytes:   der,
+	}
+
+	id := thumbprint(&private.PublicKey)
+	path := filepath.Join(dir, id+".pem")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return id, pem.Encode(file, block)
+}
+
+func parseKey(id string, data []byte) (signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return signingKey{}, errors.New("no PEM data")
+	}
+
+	var private *rsa.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return signingKey{}, err
+		}
+		private = key
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return signingKey{}, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return signingKey{}, errors.New("not an RSA key")
+		}
+		private = rsaKey
+	default:
+		return signingKey{}, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+
+	key := signingKey{id: id, private: private}
+	if key.id == "" {
+		key.id = thumbprint(&private.PublicKey)
+	}
+	if raw := block.Headers[notBeforeHeader]; raw != "" {
+		notBefore, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return signingKey{}, fmt.Errorf("bad %s header: %w", notBeforeHeader, err)
+		}
+		key.notBefore = notBefore
+	}
+	return key, nil
+}
+
+// thumbprint is the RFC 7638 JWK thumbprint of the key, used as its id.
+func thumbprint(public *rsa.PublicKey) string {
+	jwk, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+	})
+	sum := sha256.Sum256(jwk)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	oldID, err := GenerateKey(dir, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	newID, err := GenerateKey(dir, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	keys, err := LoadKeys(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if kid, _, _ := keys.Signing(now); kid != oldID {
+		t.Errorf("expected %s to sign now, got %s", oldID, kid)
+	}
+	if kid, _, _ := keys.Signing(now.Add(2 * time.Hour)); kid != newID {
+		t.Errorf("expected %s to sign after rotation, got %s", newID, kid)
+	}
+	if _, _, err := keys.Signing(now.Add(-2 * time.Hour)); err == nil {
+		t.Error("expected no active key before both keys")
+	}
+
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != oldID || jwks.Keys[1].Kid != newID {
+		t.Errorf("expected both keys published, got %+v", jwks.Keys)
+	}
+	if jwks.Keys[0].E != "AQAB" || jwks.Keys[0].Alg != "RS256" {
+		t.Errorf("unexpected JWK: %+v", jwks.Keys[0])
+	}
+}
+
+func TestTokenVerification(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := GenerateKey(dir, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadKeys(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	as := &AuthService{keys: keys}
+
+	token, err := as.createAccessToken("kek", "s1", 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// A key added later must not break tokens signed with the previous one.
+	if _, err := GenerateKey(dir, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	claims, err := as.parseToken(token)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if claims.Login != "kek" || claims.SessionID != "s1" {
+		t.Errorf("expected login kek of session s1, got %v %v", claims.Login, claims.SessionID)
+	}
+
+	private, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := &AuthService{keys: NewKeySet(private)}
+	if _, err := other.parseToken(token); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+}
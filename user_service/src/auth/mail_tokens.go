@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+	"userservice/src/database"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PurposeVerifyMail    = "verify_mail"
+	PurposeResetPassword = "reset_password"
+)
+
+var mailTokenTTL = map[string]time.Duration{
+	PurposeVerifyMail:    24 * time.Hour,
+	PurposeResetPassword: time.Hour,
+}
+
+var (
+	ErrMailTokenInvalid = errors.New("token is invalid or expired")
+	ErrNoMail           = errors.New("user has no mail")
+)
+
+// mailToken is sent by mail to prove control over the mailbox; the pending
+// second factor of a login uses it too. It is signed with HMAC rather than
+// the JWT keys, so it can never pass for an access token. Nonce makes it
+// single-use.
+type mailToken struct {
+	Purpose   string `json:"p"`
+	Login     string `json:"l"`
+	Mail      string `json:"m"`
+	Nonce     string `json:"n"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// MailSecretFromEnv returns MAIL_TOKEN_SECRET or, without it, a random
+// secret, so mailed links do not survive a restart.
+func MailSecretFromEnv() ([]byte, error) {
+	if secret := os.Getenv("MAIL_TOKEN_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+	slog.Warn("MAIL_TOKEN_SECRET is not set, using a temporary secret.")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// MailToken signs a token of purpose for login and mail.
+func (a *AuthService) MailToken(purpose, login, mail string) (string, error) {
+	nonce, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	return a.signMailToken(mailToken{
+		Purpose:   purpose,
+		Login:     login,
+		Mail:      mail,
+		Nonce:     nonce,
+		ExpiresAt: time.Now().Add(mailTokenTTL[purpose]).Unix(),
+	})
+}
+
+func (a *AuthService) signMailToken(token mailToken) (string, error) {
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(a.mailMAC(encoded)), nil
+}
+
+func (a *AuthService) parseMailToken(token, purpose string, now time.Time) (*mailToken, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrMailTokenInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, a.mailMAC(encoded)) {
+		return nil, ErrMailTokenInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrMailTokenInvalid
+	}
+
+	var result mailToken
+	if err := json.Unmarshal(payload, &result); err != nil {
+		return nil, ErrMailTokenInvalid
+	}
+	if result.Purpose != purpose || result.Nonce == "" || now.Unix() >= result.ExpiresAt {
+		return nil, ErrMailTokenInvalid
+	}
+	return &result, nil
+}
+
+func (a *AuthService) mailMAC(payload string) []byte {
+	mac := hmac.New(sha256.New, a.mailSecret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// VerificationToken returns the mail of login and a token to verify it.
+func (a *AuthService) VerificationToken(login string) (mail, token string, err error) {
+	mail, _, err = a.db.GetMail(login)
+	if err != nil {
+		return "", "", err
+	}
+	if mail == "" {
+		return "", "", ErrNoMail
+	}
+	token, err = a.MailToken(PurposeVerifyMail, login, mail)
+	return mail, token, err
+}
+
+func (a *AuthService) VerifyMail(token string) error {
+	claims, err := a.parseMailToken(token, PurposeVerifyMail, time.Now())
+	if err != nil {
+		return err
+	}
+	return a.db.VerifyMail(claims.Nonce, time.Unix(claims.ExpiresAt, 0), claims.Login, claims.Mail)
+}
+
+// ResetToken looks the user up by login or mail and returns their mail
+// and a password reset token.
+func (a *AuthService) ResetToken(loginOrMail string) (login, mail, token string, err error) {
+	login = loginOrMail
+	if strings.Contains(loginOrMail, "@") {
+		login, err = a.db.GetLoginByMail(loginOrMail)
+		if err != nil {
+			return "", "", "", err
+		}
+	}
+	mail, _, err = a.db.GetMail(login)
+	if err != nil {
+		return "", "", "", err
+	}
+	if mail == "" {
+		return "", "", "", ErrNoMail
+	}
+	token, err = a.MailToken(PurposeResetPassword, login, mail)
+	return login, mail, token, err
+}
+
+// ResetPassword sets a new, already validated password by a reset token.
+// The token is bound to the mail it was sent to, so changing the mail
+// cancels pending resets.
+func (a *AuthService) ResetPassword(token, password string) error {
+	claims, err := a.parseMailToken(token, PurposeResetPassword, time.Now())
+	if err != nil {
+		return err
+	}
+	mail, _, err := a.db.GetMail(claims.Login)
+	if err != nil {
+		return err
+	}
+	if mail != claims.Mail {
+		return database.ErrMailChanged
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return a.db.ResetPassword(claims.Nonce, time.Unix(claims.ExpiresAt, 0), claims.Login, passwordHash)
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMailToken(t *testing.T) {
+	as := &AuthService{mailSecret: []byte("secret")}
+	now := time.Now()
+
+	token, err := as.MailToken(PurposeResetPassword, "kek", "kek@mail.ru")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	claims, err := as.parseMailToken(token, PurposeResetPassword, now)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if claims.Login != "kek" || claims.Mail != "kek@mail.ru" || claims.Nonce == "" {
+		t.Errorf("unexpected claims %#v", claims)
+	}
+
+	t.Run("Wrong purpose", func(t *testing.T) {
+		if _, err := as.parseMailToken(token, PurposeVerifyMail, now); err != ErrMailTokenInvalid {
+			t.Errorf("expected ErrMailTokenInvalid, got %v", err)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		if _, err := as.parseMailToken(token, PurposeResetPassword, now.Add(2*time.Hour)); err != ErrMailTokenInvalid {
+			t.Errorf("expected ErrMailTokenInvalid, got %v", err)
+		}
+	})
+
+	t.Run("Other secret", func(t *testing.T) {
+		other := &AuthService{mailSecret: []byte("other")}
+		if _, err := other.parseMailToken(token, PurposeResetPassword, now); err != ErrMailTokenInvalid {
+			t.Errorf("expected ErrMailTokenInvalid, got %v", err)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		payload, signature, _ := strings.Cut(token, ".")
+		forged, err := as.signMailToken(mailToken{Purpose: PurposeResetPassword, Login: "lol", Nonce: "x", ExpiresAt: now.Add(time.Hour).Unix()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		forgedPayload, _, _ := strings.Cut(forged, ".")
+		if _, err := as.parseMailToken(forgedPayload+"."+signature, PurposeResetPassword, now); err != ErrMailTokenInvalid {
+			t.Errorf("expected ErrMailTokenInvalid, got %v", err)
+		}
+		if _, err := as.parseMailToken(payload, PurposeResetPassword, now); err != ErrMailTokenInvalid {
+			t.Errorf("expected ErrMailTokenInvalid, got %v", err)
+		}
+	})
+}
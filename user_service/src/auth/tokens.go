@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"time"
+	"userservice/src/database"
+)
+
+const (
+	AccessCookie  = "jwt"
+	RefreshCookie = "refresh_token"
+)
+
+var refreshTokenTTL = 30 * 24 * time.Hour
+
+var ErrForeignToken = errors.New("token belongs to another user")
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// IssueTokens starts a new session on the client: an access token and a
+// refresh token of a new family. Disabled users get
+// database.ErrUserDisabled.
+func (a *AuthService) IssueTokens(login string, client Client) (*TokenPair, error) {
+	disabled, err := a.db.UserDisabled(login)
+	if err != nil {
+		return nil, err
+	}
+	if disabled {
+		return nil, database.ErrUserDisabled
+	}
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := &database.Session{
+		ID:         familyID,
+		Login:      login,
+		Device:     DeviceName(client.UserAgent),
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(refreshTokenTTL),
+	}
+	if err := a.db.StartSession(session, hashToken(refresh)); err != nil {
+		return nil, err
+	}
+	return a.tokenPair(login, familyID, refresh)
+}
+
+// Refresh exchanges a refresh token for a new pair. Every refresh token
+// works once; presenting a used one revokes the whole family, because
+// either the client or an attacker holds a stolen copy.
+func (a *AuthService) Refresh(refresh string, client Client) (*TokenPair, error) {
+	next, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	login, familyID, err := a.db.RotateRefreshToken(hashToken(refresh), hashToken(next), time.Now().Add(refreshTokenTTL), client.IP, client.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	return a.tokenPair(login, familyID, next)
+}
+
+// Logout revokes the access token and, if given, the refresh token family
+// of the session.
+func (a *AuthService) Logout(claims *Claims, refresh string) error {
+	if err := a.db.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+	if refresh == "" {
+		return nil
+	}
+	return a.db.RevokeRefreshToken(claims.Login, hashToken(refresh))
+}
+
+func (a *AuthService) LogoutAll(login string) error {
+	return a.db.LogoutAll(login)
+}
+
+// RevokeToken puts an access token of login on the revocation list, for
+// example one that leaked. Expired tokens need no revocation.
+func (a *AuthService) RevokeToken(login, token string) error {
+	claims, err := a.parseToken(token)
+	if err != nil {
+		return err
+	}
+	if claims.Login != login {
+		return ErrForeignToken
+	}
+	return a.db.RevokeToken(claims.ID, claims.ExpiresAt.Time)
+}
+
+// PurgeExpired periodically drops expired refresh tokens and revocation
+// entries until stop is closed.
+func (a *AuthService) PurgeExpired(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := a.db.PurgeExpiredTokens(now); err != nil {
+				slog.Error("Can not purge expired tokens.", slog.String("error", err.Error()))
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (a *AuthService) tokenPair(login, sessionID, refresh string) (*TokenPair, error) {
+	access, err := a.CreateToken(login, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(tokenTTL.Seconds()),
+	}, nil
+}
+
+func randomToken(size int) (string, error) {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// hashToken is enough for refresh tokens: they are random, so there is
+// nothing to brute force.
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
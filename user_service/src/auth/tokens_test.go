@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRandomToken(t *testing.T) {
+	first, err := randomToken(32)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	second, err := randomToken(32)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(first) != 43 {
+		t.Errorf("expected 43 characters, got %d", len(first))
+	}
+	if first == second {
+		t.Error("expected different tokens")
+	}
+
+	if !bytes.Equal(hashToken(first), hashToken(first)) {
+		t.Error("expected stable hash")
+	}
+	if bytes.Equal(hashToken(first), hashToken(second)) {
+		t.Error("expected different hashes")
+	}
+}
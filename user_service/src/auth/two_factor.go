@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+	"userservice/src/database"
+	"userservice/src/totp"
+
+	qrcode "github.com/skip2/go-qrcode"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// PurposeLogin2FA marks the token a user gets after the password when
+	// the second factor is still pending.
+	PurposeLogin2FA = "login_2fa"
+
+	totpIssuer        = "task-tracker"
+	recoveryCodeCount = 10
+	qrSize            = 256
+)
+
+var ErrTwoFactorCode = errors.New("invalid two-factor code")
+
+// PendingLoginTTL is how long the user has to enter the second factor.
+var PendingLoginTTL = 5 * time.Minute
+
+func init() {
+	mailTokenTTL[PurposeLogin2FA] = PendingLoginTTL
+}
+
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+	QRCode []byte `json:"qr_png"`
+}
+
+// EnrollTOTP generates a new secret. Two-factor authentication stays off
+// until EnableTOTP confirms the user can produce codes.
+func (a *AuthService) EnrollTOTP(login string) (*Enrollment, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.db.SetTOTPSecret(login, secret); err != nil {
+		return nil, err
+	}
+
+	uri := totp.URI(secret, totpIssuer, login)
+	png, err := qrcode.Encode(uri, qrcode.Medium, qrSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Enrollment{Secret: totp.EncodeSecret(secret), URI: uri, QRCode: png}, nil
+}
+
+// EnableTOTP checks the first code and returns recovery codes, which are
+// shown only once.
+func (a *AuthService) EnableTOTP(login, code string) ([]string, error) {
+	twoFactor, err := a.db.GetTwoFactor(login)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor.Enabled {
+		return nil, database.ErrTwoFactorEnabled
+	}
+	if twoFactor.Secret == nil {
+		return nil, database.ErrTwoFactorNotEnrolled
+	}
+	step, ok := totp.Validate(twoFactor.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorCode
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([][]byte, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := recoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(code))
+	}
+	if err := a.db.EnableTwoFactor(login, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (a *AuthService) DisableTOTP(login, password string) error {
+	err := a.CheckPassword(login, password)
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) || errors.Is(err, bcrypt.ErrHashTooShort) {
+		return ErrWrongPassword
+	} else if err != nil {
+		return err
+	}
+	return a.db.DisableTwoFactor(login)
+}
+
+func (a *AuthService) TwoFactorEnabled(login string) (bool, error) {
+	twoFactor, err := a.db.GetTwoFactor(login)
+	return twoFactor.Enabled, err
+}
+
+// PendingLoginToken is issued instead of tokens when the password is right
+// but the second factor is still to be checked.
+func (a *AuthService) PendingLoginToken(login string) (string, error) {
+	return a.MailToken(PurposeLogin2FA, login, "")
+}
+
+// PendingLogin returns the login and the ID of a pending login token
+// without spending it, so attempts can be throttled before the code is
+// checked. Spent tokens give database.ErrMailTokenUsed.
+func (a *AuthService) PendingLogin(token string) (login, id string, err error) {
+	claims, err := a.pendingLogin(token)
+	if err != nil {
+		return "", "", err
+	}
+	return claims.Login, claims.Nonce, nil
+}
+
+func (a *AuthService) pendingLogin(token string) (*mailToken, error) {
+	claims, err := a.parseMailToken(token, PurposeLogin2FA, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	used, err := a.db.TokenUsed(claims.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if used {
+		return nil, database.ErrMailTokenUsed
+	}
+	return claims, nil
+}
+
+// CancelPendingLogin spends the token, so the password has to be entered
+// again, like after too many wrong codes.
+func (a *AuthService) CancelPendingLogin(token string) error {
+	claims, err := a.parseMailToken(token, PurposeLogin2FA, time.Now())
+	if err != nil {
+		return err
+	}
+	err = a.db.UseToken(claims.Nonce, time.Unix(claims.ExpiresAt, 0))
+	if errors.Is(err, database.ErrMailTokenUsed) {
+		return nil
+	}
+	return err
+}
+
+// CompleteLogin checks a TOTP or a recovery code against the pending login
+// token and returns the login. A wrong code does not spend the token; the
+// login is returned with the error then, so the failure can be recorded.
+func (a *AuthService) CompleteLogin(token, code, recovery string) (string, error) {
+	claims, err := a.pendingLogin(token)
+	if err != nil {
+		return "", err
+	}
+
+	if recovery != "" {
+		err = a.db.UseRecoveryCode(claims.Login, hashToken(normalizeRecoveryCode(recovery)))
+	} else {
+		err = a.checkTOTP(claims.Login, code)
+	}
+	if err != nil {
+		return claims.Login, err
+	}
+
+	if err := a.db.UseToken(claims.Nonce, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		return "", err
+	}
+	return claims.Login, nil
+}
+
+func (a *AuthService) checkTOTP(login, code string) error {
+	twoFactor, err := a.db.GetTwoFactor(login)
+	if err != nil {
+		return err
+	}
+	if !twoFactor.Enabled {
+		return database.ErrTwoFactorNotEnrolled
+	}
+	step, ok := totp.Validate(twoFactor.Secret, code, time.Now())
+	if !ok {
+		return ErrTwoFactorCode
+	}
+	accepted, err := a.db.UseTOTPStep(login, step)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return ErrTwoFactorCode
+	}
+	return nil
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// recoveryCode returns 80 random bits as xxxx-xxxx-xxxx-xxxx, enough to be
+// stored with a plain hash.
+func recoveryCode() (string, error) {
+	data := make([]byte, 10)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryEncoding.EncodeToString(data))
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != 16 {
+		return code
+	}
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16]
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecoveryCode(t *testing.T) {
+	code, err := recoveryCode()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !regexp.MustCompile(`^[a-z2-7]{4}(-[a-z2-7]{4}){3}$`).MatchString(code) {
+		t.Errorf("unexpected format %q", code)
+	}
+
+	for _, typed := range []string{code, " " + code + " ", strings.ReplaceAll(code, "-", ""), strings.ToUpper(code)} {
+		if normalized := normalizeRecoveryCode(typed); normalized != code {
+			t.Errorf("%q: expected %q, got %q", typed, code, normalized)
+		}
+	}
+}
+
+func TestPendingLoginToken(t *testing.T) {
+	as := &AuthService{mailSecret: []byte("secret")}
+
+	token, err := as.PendingLoginToken("kek")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := as.parseMailToken(token, PurposeLogin2FA, time.Now()); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if _, err := as.parseMailToken(token, PurposeLogin2FA, time.Now().Add(PendingLoginTTL)); err != ErrMailTokenInvalid {
+		t.Errorf("expected ErrMailTokenInvalid, got %v", err)
+	}
+	if _, err := as.parseMailToken(token, PurposeResetPassword, time.Now()); err != ErrMailTokenInvalid {
+		t.Errorf("expected ErrMailTokenInvalid, got %v", err)
+	}
+}
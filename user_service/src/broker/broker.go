@@ -2,41 +2,90 @@ package broker
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"time"
-
-	"github.com/IBM/sarama"
+	"log/slog"
+	"os"
+	"strings"
 )
 
+const statTopic = "Stat"
+
+var ErrClosed = errors.New("broker is closed")
+
+// ErrNoConsumer refuses account deletions of a service run alone with
+// BROKER=memory: statistics service would never confirm them.
+var ErrNoConsumer = errors.New("in-memory broker has no consumer, account deletion needs Kafka")
+
+type Message struct {
+	Topic string
+	// Key picks the partition. Stat messages are keyed by login, so the
+	// messages of one user are handled in the order they were sent.
+	Key string
+	// Type tells the consumer how to read Value. Kafka carries it in the
+	// "type" header.
+	Type  string
+	Value []byte
+}
+
+type Publisher interface {
+	Publish(msg Message) error
+	Close() error
+}
+
 type Broker struct {
-	producer sarama.SyncProducer
+	publisher Publisher
+	// drops is set when nothing consumes the messages.
+	drops bool
+}
+
+func New(publisher Publisher) *Broker {
+	return &Broker{publisher: publisher}
+}
+
+// Drops reports whether published messages are dropped for lack of a
+// consumer.
+func (b *Broker) Drops() bool {
+	return b.drops
 }
 
-func New() (*Broker, func()) {
-	config := sarama.NewConfig()
-	config.Producer.RequiredAcks = sarama.WaitForAll // ?
-	config.Producer.Return.Successes = true
-
-	// TODO: use env
-	brokers := []string{"kafka:29092"}
-	var producer sarama.SyncProducer
-	var err error
-	for {
-		producer, err = sarama.NewSyncProducer(brokers, config)
+// FromEnv picks the publisher by BROKER ("kafka" by default or "memory").
+// Messages of the in-memory publisher are meant for a consumer in the same
+// process, like statistics service's ChannelSubscriber in tests. Run alone,
+// the service drops likes and views and refuses account deletions with
+// ErrNoConsumer, as they would wait for statistics service forever.
+func FromEnv() (*Broker, func(), error) {
+	var publisher Publisher
+	var drops bool
+	switch kind := os.Getenv("BROKER"); kind {
+	case "", "kafka":
+		brokers := []string{"kafka:29092"}
+		if addrs := os.Getenv("KAFKA_BROKERS"); addrs != "" {
+			brokers = strings.Split(addrs, ",")
+		}
+		kafka, err := NewKafkaPublisher(brokers, kafkaConnectAttempts)
 		if err != nil {
-			fmt.Println("Wait for Kafka")
-			time.Sleep(5 * time.Second)
-			continue
+			return nil, nil, err
 		}
-		fmt.Println("Kafka is ready")
-		break
+		publisher = kafka
+	case "memory":
+		channel := NewChannelPublisher(100)
+		slog.Warn("In-memory broker has no consumer: likes and views are dropped and account deletion is refused.")
+		go func() {
+			for range channel.Messages() {
+			}
+		}()
+		publisher, drops = channel, true
+	default:
+		return nil, nil, fmt.Errorf("unknown broker %q", kind)
 	}
 
 	close := func() {
-		producer.Close()
+		publisher.Close()
 	}
-
-	return &Broker{producer: producer}, close
+	b := New(publisher)
+	b.drops = drops
+	return b, close, nil
 }
 
 type Statistic struct {
@@ -44,19 +93,18 @@ type Statistic struct {
 	TaskID uint   `json:"task_id"`
 }
 
-func (b *Broker) sendStat(stat Statistic, key string) error {
+func (b *Broker) sendStat(stat Statistic, typ string) error {
 	messageBytes, err := json.Marshal(stat)
 	if err != nil {
 		return err
 	}
 
-	msg := &sarama.ProducerMessage{
-		Topic: "Stat",
-		Key: sarama.StringEncoder(key),
-		Value: sarama.ByteEncoder(messageBytes),
-	}
-	_, _, err = b.producer.SendMessage(msg)
-	return err
+	return b.publisher.Publish(Message{
+		Topic: statTopic,
+		Key:   stat.Login,
+		Type:  typ,
+		Value: messageBytes,
+	})
 }
 
 func (b *Broker) SendLike(stat Statistic) error {
@@ -66,3 +114,29 @@ func (b *Broker) SendLike(stat Statistic) error {
 func (b *Broker) SendView(stat Statistic) error {
 	return b.sendStat(stat, "View")
 }
+
+// UserDeletion asks statistics service to forget a deleted account. It goes
+// through the same topic and partition as likes and views of the user, so
+// everything the user sent before is handled first.
+type UserDeletion struct {
+	ID      string   `json:"id"`
+	Login   string   `json:"login"`
+	TaskIDs []uint32 `json:"task_ids"`
+}
+
+func (b *Broker) SendUserDeletion(deletion UserDeletion) error {
+	if b.drops {
+		return ErrNoConsumer
+	}
+	messageBytes, err := json.Marshal(deletion)
+	if err != nil {
+		return err
+	}
+
+	return b.publisher.Publish(Message{
+		Topic: statTopic,
+		Key:   deletion.Login,
+		Type:  "DeleteUser",
+		Value: messageBytes,
+	})
+}
@@ -0,0 +1,64 @@
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSendStat(t *testing.T) {
+	publisher := NewChannelPublisher(2)
+	b := New(publisher)
+
+	if err := b.SendLike(Statistic{Login: "kek", TaskID: 1}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := b.SendView(Statistic{Login: "lol", TaskID: 2}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := b.SendView(Statistic{Login: "lol", TaskID: 3}); err != ErrBufferFull {
+		t.Fatalf("expected ErrBufferFull, got %v", err)
+	}
+	publisher.Close()
+
+	target := []struct {
+		typ  string
+		stat Statistic
+	}{
+		{"Like", Statistic{Login: "kek", TaskID: 1}},
+		{"View", Statistic{Login: "lol", TaskID: 2}},
+	}
+	i := 0
+	for msg := range publisher.Messages() {
+		var stat Statistic
+		if err := json.Unmarshal(msg.Value, &stat); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Topic != statTopic || msg.Type != target[i].typ || msg.Key != stat.Login || stat != target[i].stat {
+			t.Errorf("expected: %#v; got: %s %s %#v", target[i], msg.Type, msg.Key, stat)
+		}
+		i++
+	}
+	if i != len(target) {
+		t.Errorf("expected %d messages, got %d", len(target), i)
+	}
+}
+
+func TestMemoryBrokerAlone(t *testing.T) {
+	t.Setenv("BROKER", "memory")
+	b, closeBroker, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeBroker()
+
+	if !b.Drops() {
+		t.Error("expected the broker to drop messages")
+	}
+	if err := b.SendLike(Statistic{Login: "kek", TaskID: 1}); err != nil {
+		t.Errorf("expected the like dropped, got %v", err)
+	}
+	if err := b.SendUserDeletion(UserDeletion{ID: "1", Login: "kek"}); !errors.Is(err, ErrNoConsumer) {
+		t.Errorf("expected ErrNoConsumer, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package broker
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	kafkaConnectAttempts = 12
+	kafkaConnectDelay    = 5 * time.Second
+)
+
+type KafkaPublisher struct {
+	producer sarama.SyncProducer
+}
+
+func NewKafkaPublisher(brokers []string, attempts int) (*KafkaPublisher, error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Return.Successes = true
+
+	var producer sarama.SyncProducer
+	var err error
+	for i := 0; i < attempts; i++ {
+		producer, err = sarama.NewSyncProducer(brokers, config)
+		if err == nil {
+			slog.Info("Kafka is ready")
+			return &KafkaPublisher{producer: producer}, nil
+		}
+		slog.Info("Waiting for Kafka", "error", err)
+		time.Sleep(kafkaConnectDelay)
+	}
+	return nil, fmt.Errorf("can not connect to kafka: %w", err)
+}
+
+func (p *KafkaPublisher) Publish(msg Message) error {
+	_, _, err := p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:   msg.Topic,
+		Key:     sarama.StringEncoder(msg.Key),
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: []sarama.RecordHeader{{Key: []byte("type"), Value: []byte(msg.Type)}},
+	})
+	return err
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.producer.Close()
+}
@@ -0,0 +1,49 @@
+package broker
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrBufferFull = errors.New("broker buffer is full")
+
+// ChannelPublisher is an in-process stand-in for Kafka. Published messages
+// are delivered to Messages() in order.
+type ChannelPublisher struct {
+	mu       sync.RWMutex
+	closed   bool
+	messages chan Message
+}
+
+func NewChannelPublisher(size int) *ChannelPublisher {
+	return &ChannelPublisher{messages: make(chan Message, size)}
+}
+
+func (p *ChannelPublisher) Publish(msg Message) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+
+	select {
+	case p.messages <- msg:
+		return nil
+	default:
+		return ErrBufferFull
+	}
+}
+
+func (p *ChannelPublisher) Messages() <-chan Message {
+	return p.messages
+}
+
+func (p *ChannelPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.messages)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAccessTokenExists   = errors.New("access token with this name already exists")
+	ErrAccessTokenNotFound = errors.New("access token not found")
+)
+
+// accessToken is a personal access token. Like refresh tokens it is stored
+// as a hash only; scopes are kept space separated.
+type accessToken struct {
+	ID         uint
+	CreatedAt  time.Time
+	Login      string
+	Name       string
+	TokenHash  []byte
+	Scopes     string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+type AccessToken struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func (t *accessToken) data() AccessToken {
+	return AccessToken{
+		ID:         t.ID,
+		Name:       t.Name,
+		Scopes:     strings.Fields(t.Scopes),
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+	}
+}
+
+func (db *DataBase) CreateAccessToken(login, name string, hash []byte, scopes []string, expiresAt *time.Time) (AccessToken, error) {
+	token := &accessToken{
+		Login:     login,
+		Name:      name,
+		TokenHash: hash,
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: expiresAt,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&accessToken{}).Where("login = ? AND name = ?", login, name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAccessTokenExists
+		}
+		return tx.Create(token).Error
+	})
+	return token.data(), err
+}
+
+func (db *DataBase) GetAccessTokens(login string) ([]AccessToken, error) {
+	var tokens []accessToken
+	if err := db.Where("login = ?", login).Order("id").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	result := make([]AccessToken, 0, len(tokens))
+	for i := range tokens {
+		result = append(result, tokens[i].data())
+	}
+	return result, nil
+}
+
+func (db *DataBase) DeleteAccessToken(login string, id uint) error {
+	result := db.Where("id = ? AND login = ?", id, login).Delete(&accessToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// FindAccessToken returns the owner and the scopes of a valid token and
+// records its use. Tokens of disabled users give ErrUserDisabled.
+func (db *DataBase) FindAccessToken(hash []byte) (login string, scopes []string, err error) {
+	var token accessToken
+	result := db.Limit(1).Find(&token, "token_hash = ?", hash)
+	if result.Error != nil {
+		return "", nil, result.Error
+	}
+	if result.RowsAffected == 0 || (token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt)) {
+		return "", nil, ErrAccessTokenNotFound
+	}
+	disabled, err := db.UserDisabled(token.Login)
+	if err != nil {
+		return "", nil, err
+	}
+	if disabled {
+		return "", nil, ErrUserDisabled
+	}
+	if err := db.Model(&token).Update("last_used_at", time.Now()).Error; err != nil {
+		return "", nil, err
+	}
+	return token.Login, strings.Fields(token.Scopes), nil
+}
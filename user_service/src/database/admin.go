@@ -0,0 +1,221 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+var Roles = []string{RoleUser, RoleAdmin}
+
+var ErrUserDisabled = errors.New("account is disabled")
+
+// UserSummary is what administrators see in the user list.
+type UserSummary struct {
+	Login        string     `json:"login"`
+	Name         string     `json:"name,omitempty"`
+	Surname      string     `json:"surname,omitempty"`
+	Mail         string     `json:"mail,omitempty"`
+	MailVerified bool       `json:"mail_verified"`
+	Role         string     `json:"role"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+}
+
+// LoginEvent is a login attempt of a known user. Method is "password",
+// "2fa" or "oidc:<provider>".
+type LoginEvent struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Login     string    `json:"login"`
+	Method    string    `json:"method"`
+	Success   bool      `json:"success"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+// Outcomes of audited actions done by other services.
+const (
+	AuditPending = "pending"
+	AuditDone    = "done"
+	AuditFailed  = "failed"
+)
+
+// AuditEntry records an action of an administrator. Target is the login or
+// the task the action was applied to. Outcome is only set for actions done
+// by other services: their entry is written before the call, so it stays
+// pending if the outcome never gets recorded.
+type AuditEntry struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	Outcome   string    `json:"outcome,omitempty"`
+}
+
+type AuditFilter struct {
+	Actor  string
+	Target string
+	Action string
+}
+
+// PromoteAdmins makes the users administrators. It is how the first
+// administrators appear, the rest can be appointed through the API.
+func (db *DataBase) PromoteAdmins(logins []string) error {
+	if len(logins) == 0 {
+		return nil
+	}
+	return db.Model(&userInfo{}).
+		Where("login IN ? AND role <> ?", logins, RoleAdmin).
+		Update("role", RoleAdmin).Error
+}
+
+func (db *DataBase) GetRole(login string) (string, error) {
+	var info userInfo
+	result := db.Select("role").First(&info, "login = ?", login)
+	return info.Role, result.Error
+}
+
+func (db *DataBase) UserDisabled(login string) (bool, error) {
+	var info userInfo
+	result := db.Select("disabled_at").First(&info, "login = ?", login)
+	return info.DisabledAt != nil, result.Error
+}
+
+// SearchUsers returns users whose login, name, surname or mail contain
+// query, ordered by login. An empty query lists everyone.
+func (db *DataBase) SearchUsers(query string, offset, limit int) ([]UserSummary, error) {
+	tx := db.Model(&userInfo{}).Order("login").Offset(offset).Limit(limit)
+	if query != "" {
+		pattern := "%" + escapeLike(query) + "%"
+		tx = tx.Where("login ILIKE ? OR name ILIKE ? OR surname ILIKE ? OR mail ILIKE ?",
+			pattern, pattern, pattern, pattern)
+	}
+	var infos []userInfo
+	if err := tx.Find(&infos).Error; err != nil {
+		return nil, err
+	}
+	users := make([]UserSummary, 0, len(infos))
+	for _, info := range infos {
+		users = append(users, UserSummary{
+			Login:        info.Login,
+			Name:         info.Name,
+			Surname:      info.Surname,
+			Mail:         info.Mail,
+			MailVerified: info.MailVerified,
+			Role:         info.Role,
+			CreatedAt:    info.CreatedAt,
+			DisabledAt:   info.DisabledAt,
+		})
+	}
+	return users, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SetUserDisabled disables or enables the user. Disabling also ends all
+// sessions, like LogoutAll; access tokens stay but are refused while the
+// user is disabled.
+func (db *DataBase) SetUserDisabled(login string, disabled bool, entry *AuditEntry) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var disabledAt *time.Time
+		if disabled {
+			now := time.Now()
+			disabledAt = &now
+		}
+		if err := updateUser(tx, login, "disabled_at", disabledAt); err != nil {
+			return err
+		}
+		if disabled {
+			if err := logoutAll(tx, login); err != nil {
+				return err
+			}
+		}
+		return tx.Create(entry).Error
+	})
+}
+
+func (db *DataBase) SetUserRole(login, role string, entry *AuditEntry) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := updateUser(tx, login, "role", role); err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+}
+
+// ForcePasswordReset drops the password and ends all sessions. The user
+// can only sign in again after setting a new password by a reset mail or
+// through an identity provider.
+func (db *DataBase) ForcePasswordReset(login string, entry *AuditEntry) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := updateUser(tx, login, "password_hash", nil); err != nil {
+			return err
+		}
+		if err := logoutAll(tx, login); err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+}
+
+func updateUser(tx *gorm.DB, login, column string, value any) error {
+	result := tx.Model(&userInfo{}).Where("login = ?", login).Update(column, value)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (db *DataBase) AddAuditEntry(entry *AuditEntry) error {
+	return db.Create(entry).Error
+}
+
+func (db *DataBase) SetAuditOutcome(id uint, outcome, details string) error {
+	return db.Model(&AuditEntry{}).Where("id = ?", id).Updates(map[string]any{
+		"outcome": outcome,
+		"details": details,
+	}).Error
+}
+
+// GetAuditEntries returns entries matching the filter, newest first.
+func (db *DataBase) GetAuditEntries(filter AuditFilter, offset, limit int) ([]AuditEntry, error) {
+	tx := db.Model(&AuditEntry{}).Order("id DESC").Offset(offset).Limit(limit)
+	if filter.Actor != "" {
+		tx = tx.Where("actor = ?", filter.Actor)
+	}
+	if filter.Target != "" {
+		tx = tx.Where("target = ?", filter.Target)
+	}
+	if filter.Action != "" {
+		tx = tx.Where("action = ?", filter.Action)
+	}
+	entries := []AuditEntry{}
+	return entries, tx.Find(&entries).Error
+}
+
+func (db *DataBase) AddLoginEvent(event *LoginEvent) error {
+	return db.Create(event).Error
+}
+
+// GetLoginEvents returns login attempts of the user, newest first.
+func (db *DataBase) GetLoginEvents(login string, offset, limit int) ([]LoginEvent, error) {
+	events := []LoginEvent{}
+	result := db.Where("login = ?", login).Order("id DESC").Offset(offset).Limit(limit).Find(&events)
+	return events, result.Error
+}
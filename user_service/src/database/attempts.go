@@ -0,0 +1,81 @@
+package database
+
+import (
+	"time"
+	"userservice/src/throttle"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type loginAttempt struct {
+	Key          string `gorm:"primaryKey"`
+	Failures     int
+	LastFailure  time.Time
+	BlockedUntil time.Time
+}
+
+// AttemptStore keeps throttle states in Postgres, so replicas share them.
+type AttemptStore struct {
+	db *DataBase
+}
+
+func (db *DataBase) AttemptStore() *AttemptStore {
+	return &AttemptStore{db: db}
+}
+
+func (s *AttemptStore) Get(key string) (throttle.State, error) {
+	var attempt loginAttempt
+	result := s.db.Limit(1).Find(&attempt, "key = ?", key)
+	return attempt.state(), result.Error
+}
+
+func (s *AttemptStore) Update(key string, fn func(*throttle.State)) (throttle.State, error) {
+	var state throttle.State
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var attempt loginAttempt
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(&attempt, "key = ?", key)
+		if result.Error != nil {
+			return result.Error
+		}
+		state = attempt.state()
+		fn(&state)
+
+		updated := loginAttempt{
+			Key:          key,
+			Failures:     state.Failures,
+			LastFailure:  state.LastFailure,
+			BlockedUntil: state.BlockedUntil,
+		}
+		if result.RowsAffected > 0 {
+			return tx.Select("*").Save(&updated).Error
+		}
+		// Two replicas may insert the same new key at once; the upsert
+		// keeps the worse state instead of failing.
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":      gorm.Expr("GREATEST(login_attempts.failures, EXCLUDED.failures)"),
+				"last_failure":  gorm.Expr("EXCLUDED.last_failure"),
+				"blocked_until": gorm.Expr("GREATEST(login_attempts.blocked_until, EXCLUDED.blocked_until)"),
+			}),
+		}).Create(&updated).Error
+	})
+	return state, err
+}
+
+func (s *AttemptStore) Delete(key string) error {
+	return s.db.Where("key = ?", key).Delete(&loginAttempt{}).Error
+}
+
+func (s *AttemptStore) Purge(before time.Time) error {
+	return s.db.Where("last_failure < ?", before).Delete(&loginAttempt{}).Error
+}
+
+func (a *loginAttempt) state() throttle.State {
+	return throttle.State{
+		Failures:     a.Failures,
+		LastFailure:  a.LastFailure,
+		BlockedUntil: a.BlockedUntil,
+	}
+}
@@ -1,12 +1,21 @@
 package database
 
 import (
+	"embed"
+	"io/fs"
+	"time"
+	"userservice/src/migrate"
 
-	//_ "github.com/lib/pq"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// Advisory lock key ("user" in ASCII) taken while migrating.
+const migrationLockID = 0x75736572
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
 type DataBase struct {
 	*gorm.DB
 }
@@ -20,6 +29,22 @@ type userInfo struct {
 	BirthDay     string
 	Mail         string
 	PhoneNumber  string
+	MailVerified bool
+
+	TokenGeneration int
+
+	TotpSecret   []byte
+	TotpEnabled  bool
+	TotpLastStep int64
+
+	Role       string `gorm:"default:user"`
+	DisabledAt *time.Time
+
+	PublicName        bool `gorm:"default:true"`
+	PublicSurname     bool `gorm:"default:true"`
+	PublicBirthDay    bool
+	PublicMail        bool
+	PublicPhoneNumber bool
 }
 
 type UserData struct {
@@ -28,6 +53,10 @@ type UserData struct {
 	BirthDay    string `json:"bith_day"`
 	Mail        string `json:"mail"`
 	PhoneNumber string `json:"phone_number"`
+
+	// MailVerified is read only, it is set by following the link from the
+	// verification mail.
+	MailVerified bool `json:"mail_verified"`
 }
 
 func New() *DataBase {
@@ -38,14 +67,26 @@ func New() *DataBase {
 		panic("failed to connect database: " + err.Error())
 	}
 
-	db.AutoMigrate(&userInfo{})
-
 	return &DataBase{db}
 }
 
+func (db *DataBase) SchemaMigrator() (*migrate.Migrator, error) {
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, fsys, migrationLockID)
+}
+
+// UserExist also reports users whose deletion is in progress, so their
+// login is not taken over while their data is still around.
 func (db *DataBase) UserExist(login string) (bool, error) {
 	var info userInfo
-	result := db.First(&info, "login = ?", login)
+	result := db.Unscoped().First(&info, "login = ?", login)
 	if result.Error == gorm.ErrRecordNotFound {
 		return false, nil
 	} else if result.Error != nil {
@@ -67,25 +108,51 @@ func (db *DataBase) GetPasswordHash(login string) ([]byte, error) {
 	return info.PasswordHash, result.Error
 }
 
-func (db *DataBase) UpdateUserData(login string, data *UserData) error {
-	result := db.Model(&userInfo{}).Where("login = ?", login).Updates(userInfo{
-		Name:        data.Name,
-		Surname:     data.Surname,
-		BirthDay:    data.BirthDay,
-		Mail:        data.Mail,
-		PhoneNumber: data.PhoneNumber,
+// ChangePassword sets a new password hash and logs the user out
+// everywhere, like ResetPassword does.
+func (db *DataBase) ChangePassword(login string, passwordHash []byte) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := updateUser(tx, login, "password_hash", passwordHash); err != nil {
+			return err
+		}
+		return logoutAll(tx, login)
 	})
-	return result.Error
+}
+
+// UpdateUserData updates non-empty fields. A new mail needs verification
+// again; mailChanged reports that.
+func (db *DataBase) UpdateUserData(login string, data *UserData) (mailChanged bool, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var info userInfo
+		if err := tx.Select("mail").First(&info, "login = ?", login).Error; err != nil {
+			return err
+		}
+		mailChanged = data.Mail != "" && data.Mail != info.Mail
+
+		result := tx.Model(&userInfo{}).Where("login = ?", login).Updates(userInfo{
+			Name:        data.Name,
+			Surname:     data.Surname,
+			BirthDay:    data.BirthDay,
+			Mail:        data.Mail,
+			PhoneNumber: data.PhoneNumber,
+		})
+		if result.Error != nil || !mailChanged {
+			return result.Error
+		}
+		return tx.Model(&userInfo{}).Where("login = ?", login).Update("mail_verified", false).Error
+	})
+	return mailChanged, err
 }
 
 func (db *DataBase) GetUserData(login string) (*UserData, error) {
 	info := &userInfo{Login: login}
 	result := db.First(&info, "login = ?", login)
 	return &UserData{
-		Name:        info.Name,
-		Surname:     info.Surname,
-		BirthDay:    info.BirthDay,
-		Mail:        info.Mail,
-		PhoneNumber: info.PhoneNumber,
+		Name:         info.Name,
+		Surname:      info.Surname,
+		BirthDay:     info.BirthDay,
+		Mail:         info.Mail,
+		PhoneNumber:  info.PhoneNumber,
+		MailVerified: info.MailVerified,
 	}, result.Error
 }
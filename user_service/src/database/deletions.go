@@ -0,0 +1,124 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrDeletionNotFound = errors.New("account deletion not found")
+
+// AccountDeletion is the state of an account deletion saga. Login and task
+// IDs are cleared once it completes.
+type AccountDeletion struct {
+	ID               string `gorm:"primaryKey"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Login            string
+	Step             string
+	TaskIDs          []uint32 `gorm:"serializer:json"`
+	StatsRequestedAt *time.Time
+	Attempts         int
+	LastError        string
+	NextAttemptAt    time.Time
+	CompletedAt      *time.Time
+}
+
+// StartAccountDeletion records a new deletion and locks the user out right
+// away: sessions and access tokens are revoked, external accounts unlinked
+// and the user is soft deleted, so it can not be found by login anymore.
+// The login stays taken until DeleteUserData removes the user for good.
+func (db *DataBase) StartAccountDeletion(deletion *AccountDeletion) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(deletion).Error; err != nil {
+			return err
+		}
+		if err := logoutAll(tx, deletion.Login); err != nil {
+			return err
+		}
+		for _, model := range []any{&accessToken{}, &externalIdentity{}} {
+			if err := tx.Where("login = ?", deletion.Login).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		result := tx.Where("login = ?", deletion.Login).Delete(&userInfo{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (db *DataBase) GetAccountDeletion(id string) (*AccountDeletion, error) {
+	var deletion AccountDeletion
+	result := db.Limit(1).Find(&deletion, "id = ?", id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDeletionNotFound
+	}
+	return &deletion, nil
+}
+
+// ClaimAccountDeletions returns up to limit unfinished deletions that are
+// due and postpones them by lease, so other instances of the service skip
+// them meanwhile.
+func (db *DataBase) ClaimAccountDeletions(now time.Time, lease time.Duration, limit int) ([]AccountDeletion, error) {
+	var deletions []AccountDeletion
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("completed_at IS NULL AND next_attempt_at <= ?", now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deletions)
+		if result.Error != nil || len(deletions) == 0 {
+			return result.Error
+		}
+		ids := make([]string, 0, len(deletions))
+		for _, deletion := range deletions {
+			ids = append(ids, deletion.ID)
+		}
+		return tx.Model(&AccountDeletion{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return deletions, err
+}
+
+func (db *DataBase) SaveAccountDeletion(deletion *AccountDeletion) error {
+	return db.Save(deletion).Error
+}
+
+// DeleteUserData removes the user and everything kept by login in user_db.
+// Calling it again is safe.
+func (db *DataBase) DeleteUserData(login string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		models := []struct {
+			model  any
+			column string
+		}{
+			{&savedView{}, "owner"},
+			{&savedViewShare{}, "login"},
+			{&defaultView{}, "login"},
+			{&refreshToken{}, "login"},
+			{&Session{}, "login"},
+			{&accessToken{}, "login"},
+			{&recoveryCode{}, "login"},
+			{&externalIdentity{}, "login"},
+			{&AccountExport{}, "login"},
+			{&LoginEvent{}, "login"},
+		}
+		for _, m := range models {
+			if err := tx.Where(m.column+" = ?", login).Delete(m.model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("login = ?", login).Delete(&userInfo{}).Error
+	})
+}
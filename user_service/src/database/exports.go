@@ -0,0 +1,117 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+var ErrExportNotFound = errors.New("export not found")
+
+// AccountExport is a personal data export job. The archive is kept in the
+// database until ExpiresAt, so any instance can serve the download.
+type AccountExport struct {
+	ID            string `gorm:"primaryKey"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Login         string
+	Status        string
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	Archive       []byte
+	ExpiresAt     *time.Time
+}
+
+// StartAccountExport creates export unless the user already has a pending
+// one, which is returned instead.
+func (db *DataBase) StartAccountExport(export *AccountExport) (*AccountExport, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var pending AccountExport
+		result := tx.Omit("archive").Limit(1).Find(&pending, "login = ? AND status = ?", export.Login, ExportPending)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			export = &pending
+			return nil
+		}
+		return tx.Create(export).Error
+	})
+	return export, err
+}
+
+// GetAccountExport returns an export of login without the archive. An empty
+// id means the latest one.
+func (db *DataBase) GetAccountExport(login, id string) (*AccountExport, error) {
+	query := db.Omit("archive").Where("login = ?", login)
+	if id != "" {
+		query = query.Where("id = ?", id)
+	}
+	var export AccountExport
+	result := query.Order("created_at DESC").Limit(1).Find(&export)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrExportNotFound
+	}
+	return &export, nil
+}
+
+// GetExportArchive returns the archive of a ready export of login.
+func (db *DataBase) GetExportArchive(login, id string, now time.Time) ([]byte, error) {
+	var export AccountExport
+	result := db.Select("archive").Limit(1).
+		Find(&export, "id = ? AND login = ? AND status = ? AND expires_at > ?", id, login, ExportReady, now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrExportNotFound
+	}
+	return export.Archive, nil
+}
+
+// ClaimAccountExports returns up to limit pending exports that are due and
+// postpones them by lease, so other instances of the service skip them
+// meanwhile.
+func (db *DataBase) ClaimAccountExports(now time.Time, lease time.Duration, limit int) ([]AccountExport, error) {
+	var exports []AccountExport
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Omit("archive").
+			Where("status = ? AND next_attempt_at <= ?", ExportPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&exports)
+		if result.Error != nil || len(exports) == 0 {
+			return result.Error
+		}
+		ids := make([]string, 0, len(exports))
+		for _, export := range exports {
+			ids = append(ids, export.ID)
+		}
+		return tx.Model(&AccountExport{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return exports, err
+}
+
+func (db *DataBase) SaveAccountExport(export *AccountExport) error {
+	return db.Save(export).Error
+}
+
+// DeleteExpiredExports drops archives nobody may download anymore.
+func (db *DataBase) DeleteExpiredExports(now time.Time) error {
+	return db.Where("expires_at < ?", now).Delete(&AccountExport{}).Error
+}
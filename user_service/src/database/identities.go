@@ -0,0 +1,105 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrIdentityLinked = errors.New("external account is linked to another user")
+	ErrNoFreeLogin    = errors.New("can not find a free login")
+)
+
+// externalIdentity links an account at an OpenID Connect provider to a
+// local user.
+type externalIdentity struct {
+	Provider  string `gorm:"primaryKey"`
+	Subject   string `gorm:"primaryKey"`
+	Login     string
+	Email     string
+	CreatedAt time.Time
+}
+
+type ExternalIdentity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (db *DataBase) GetExternalIdentities(login string) ([]ExternalIdentity, error) {
+	var identities []externalIdentity
+	result := db.Order("created_at").Find(&identities, "login = ?", login)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	data := make([]ExternalIdentity, 0, len(identities))
+	for _, identity := range identities {
+		data = append(data, ExternalIdentity{
+			Provider:  identity.Provider,
+			Subject:   identity.Subject,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt,
+		})
+	}
+	return data, nil
+}
+
+// GetExternalLogin returns the user linked to the external account or
+// gorm.ErrRecordNotFound.
+func (db *DataBase) GetExternalLogin(provider, subject string) (string, error) {
+	var identity externalIdentity
+	result := db.First(&identity, "provider = ? AND subject = ?", provider, subject)
+	return identity.Login, result.Error
+}
+
+func (db *DataBase) LinkExternalIdentity(provider, subject, login, email string) error {
+	return linkIdentity(db.DB, provider, subject, login, email)
+}
+
+// ProvisionExternalUser creates a user without a password under the first
+// free login of candidates and links the external account to it.
+func (db *DataBase) ProvisionExternalUser(candidates []string, provider, subject, email string, emailVerified bool) (string, error) {
+	var login string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, candidate := range candidates {
+			var count int64
+			if err := tx.Unscoped().Model(&userInfo{}).Where("login = ?", candidate).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				login = candidate
+				break
+			}
+		}
+		if login == "" {
+			return ErrNoFreeLogin
+		}
+
+		info := &userInfo{Login: login, Mail: email, MailVerified: email != "" && emailVerified}
+		if err := tx.Create(info).Error; err != nil {
+			return err
+		}
+		return linkIdentity(tx, provider, subject, login, email)
+	})
+	return login, err
+}
+
+func linkIdentity(tx *gorm.DB, provider, subject, login, email string) error {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&externalIdentity{
+		Provider: provider,
+		Subject:  subject,
+		Login:    login,
+		Email:    email,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdentityLinked
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrMailTokenUsed = errors.New("token has already been used")
+	ErrMailChanged   = errors.New("mail has changed since the token was issued")
+)
+
+// usedMailToken makes signed mail tokens single-use. Entries are kept until
+// the token would expire anyway.
+type usedMailToken struct {
+	Nonce     string `gorm:"primaryKey"`
+	ExpiresAt time.Time
+}
+
+// GetMail returns the mail of the user and whether it is verified.
+func (db *DataBase) GetMail(login string) (string, bool, error) {
+	var info userInfo
+	result := db.Select("mail", "mail_verified").First(&info, "login = ?", login)
+	return info.Mail, info.MailVerified, result.Error
+}
+
+// GetLoginByMail finds the user with the mail, preferring the one who
+// verified it. It returns gorm.ErrRecordNotFound if there is none.
+func (db *DataBase) GetLoginByMail(mail string) (string, error) {
+	var info userInfo
+	result := db.Select("login").Order("mail_verified DESC, id").First(&info, "mail = ?", mail)
+	return info.Login, result.Error
+}
+
+// VerifyMail marks the mail of login verified if it is still the one the
+// token was issued for.
+func (db *DataBase) VerifyMail(nonce string, expiresAt time.Time, login, mail string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := useMailToken(tx, nonce, expiresAt); err != nil {
+			return err
+		}
+		result := tx.Model(&userInfo{}).
+			Where("login = ? AND mail = ?", login, mail).
+			Update("mail_verified", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMailChanged
+		}
+		return nil
+	})
+}
+
+// ResetPassword sets a new password hash and logs the user out everywhere.
+func (db *DataBase) ResetPassword(nonce string, expiresAt time.Time, login string, passwordHash []byte) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := useMailToken(tx, nonce, expiresAt); err != nil {
+			return err
+		}
+		result := tx.Model(&userInfo{}).Where("login = ?", login).Update("password_hash", passwordHash)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return logoutAll(tx, login)
+	})
+}
+
+func useMailToken(tx *gorm.DB, nonce string, expiresAt time.Time) error {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&usedMailToken{Nonce: nonce, ExpiresAt: expiresAt})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMailTokenUsed
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS user_infos (
+    id            BIGSERIAL PRIMARY KEY,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    deleted_at    TIMESTAMPTZ,
+    login         TEXT,
+    password_hash BYTEA,
+    name          TEXT,
+    surname       TEXT,
+    birth_day     TEXT,
+    mail          TEXT,
+    phone_number  TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_infos_deleted_at ON user_infos (deleted_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS default_views;
+DROP TABLE IF EXISTS saved_view_shares;
+DROP TABLE IF EXISTS saved_views;
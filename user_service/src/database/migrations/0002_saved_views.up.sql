@@ -0,0 +1,23 @@
+CREATE TABLE saved_views (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    owner      TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    query      TEXT NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX idx_saved_views_owner_name ON saved_views (owner, name);
+
+CREATE TABLE saved_view_shares (
+    view_id BIGINT NOT NULL REFERENCES saved_views (id) ON DELETE CASCADE,
+    login   TEXT NOT NULL,
+    PRIMARY KEY (view_id, login)
+);
+
+CREATE INDEX idx_saved_view_shares_login ON saved_view_shares (login);
+
+CREATE TABLE default_views (
+    login   TEXT PRIMARY KEY,
+    view_id BIGINT NOT NULL REFERENCES saved_views (id) ON DELETE CASCADE
+);
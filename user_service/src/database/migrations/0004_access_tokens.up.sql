@@ -0,0 +1,13 @@
+CREATE TABLE access_tokens (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    login        TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    token_hash   BYTEA NOT NULL,
+    scopes       TEXT NOT NULL,
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_access_tokens_token_hash ON access_tokens (token_hash);
+CREATE UNIQUE INDEX idx_access_tokens_login_name ON access_tokens (login, name);
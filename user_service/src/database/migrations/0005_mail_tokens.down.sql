@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS used_mail_tokens;
+DROP INDEX IF EXISTS idx_user_infos_mail;
+ALTER TABLE user_infos DROP COLUMN IF EXISTS mail_verified;
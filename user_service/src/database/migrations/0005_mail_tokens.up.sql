@@ -0,0 +1,8 @@
+ALTER TABLE user_infos ADD COLUMN IF NOT EXISTS mail_verified BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX idx_user_infos_mail ON user_infos (mail);
+
+CREATE TABLE used_mail_tokens (
+    nonce      TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS recovery_codes;
+ALTER TABLE user_infos DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE user_infos DROP COLUMN IF EXISTS totp_enabled;
+ALTER TABLE user_infos DROP COLUMN IF EXISTS totp_secret;
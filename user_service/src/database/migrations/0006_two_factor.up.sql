@@ -0,0 +1,12 @@
+ALTER TABLE user_infos ADD COLUMN IF NOT EXISTS totp_secret BYTEA;
+ALTER TABLE user_infos ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE user_infos ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE recovery_codes (
+    id        BIGSERIAL PRIMARY KEY,
+    login     TEXT NOT NULL,
+    code_hash BYTEA NOT NULL,
+    used_at   TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_recovery_codes_login_code_hash ON recovery_codes (login, code_hash);
@@ -0,0 +1,8 @@
+CREATE TABLE login_attempts (
+    key           TEXT PRIMARY KEY,
+    failures      INTEGER NOT NULL DEFAULT 0,
+    last_failure  TIMESTAMPTZ NOT NULL,
+    blocked_until TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_login_attempts_last_failure ON login_attempts (last_failure);
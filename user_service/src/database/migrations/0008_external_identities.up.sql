@@ -0,0 +1,10 @@
+CREATE TABLE external_identities (
+    provider   TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    login      TEXT NOT NULL,
+    email      TEXT,
+    created_at TIMESTAMPTZ,
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX idx_external_identities_login ON external_identities (login);
@@ -0,0 +1,15 @@
+CREATE TABLE account_deletions (
+    id                 TEXT PRIMARY KEY,
+    created_at         TIMESTAMPTZ,
+    updated_at         TIMESTAMPTZ,
+    login              TEXT NOT NULL,
+    step               TEXT NOT NULL,
+    task_ids           TEXT,
+    stats_requested_at TIMESTAMPTZ,
+    attempts           INTEGER NOT NULL DEFAULT 0,
+    last_error         TEXT NOT NULL DEFAULT '',
+    next_attempt_at    TIMESTAMPTZ NOT NULL,
+    completed_at       TIMESTAMPTZ
+);
+
+CREATE INDEX idx_account_deletions_next_attempt_at ON account_deletions (next_attempt_at) WHERE completed_at IS NULL;
@@ -0,0 +1,15 @@
+CREATE TABLE account_exports (
+    id              TEXT PRIMARY KEY,
+    created_at      TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ,
+    login           TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    archive         BYTEA,
+    expires_at      TIMESTAMPTZ
+);
+
+CREATE INDEX idx_account_exports_login ON account_exports (login, created_at);
+CREATE INDEX idx_account_exports_next_attempt_at ON account_exports (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS audit_entries;
+DROP TABLE IF EXISTS login_events;
+ALTER TABLE user_infos DROP COLUMN IF EXISTS disabled_at;
+ALTER TABLE user_infos DROP COLUMN IF EXISTS role;
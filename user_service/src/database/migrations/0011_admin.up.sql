@@ -0,0 +1,27 @@
+ALTER TABLE user_infos ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
+ALTER TABLE user_infos ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
+
+CREATE TABLE login_events (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    login      TEXT NOT NULL,
+    method     TEXT NOT NULL,
+    success    BOOLEAN NOT NULL,
+    ip         TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_login_events_login ON login_events (login, created_at);
+
+CREATE TABLE audit_entries (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    actor      TEXT NOT NULL,
+    action     TEXT NOT NULL,
+    target     TEXT NOT NULL DEFAULT '',
+    details    TEXT NOT NULL DEFAULT '',
+    ip         TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_audit_entries_created_at ON audit_entries (created_at);
+CREATE INDEX idx_audit_entries_target ON audit_entries (target, created_at);
@@ -0,0 +1,5 @@
+ALTER TABLE user_infos DROP COLUMN IF EXISTS public_phone_number;
+ALTER TABLE user_infos DROP COLUMN IF EXISTS public_mail;
+ALTER TABLE user_infos DROP COLUMN IF EXISTS public_birth_day;
+ALTER TABLE user_infos DROP COLUMN IF EXISTS public_surname;
+ALTER TABLE user_infos DROP COLUMN IF EXISTS public_name;
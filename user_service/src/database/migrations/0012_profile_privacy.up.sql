@@ -0,0 +1,5 @@
+ALTER TABLE user_infos ADD COLUMN IF NOT EXISTS public_name BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE user_infos ADD COLUMN IF NOT EXISTS public_surname BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE user_infos ADD COLUMN IF NOT EXISTS public_birth_day BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE user_infos ADD COLUMN IF NOT EXISTS public_mail BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE user_infos ADD COLUMN IF NOT EXISTS public_phone_number BOOLEAN NOT NULL DEFAULT false;
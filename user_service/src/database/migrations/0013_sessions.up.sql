@@ -0,0 +1,21 @@
+CREATE TABLE sessions (
+    id           TEXT PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    login        TEXT NOT NULL,
+    device       TEXT NOT NULL DEFAULT '',
+    ip           TEXT NOT NULL DEFAULT '',
+    user_agent   TEXT NOT NULL DEFAULT '',
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX idx_sessions_login ON sessions (login);
+
+-- Refresh token families issued before sessions were tracked become
+-- sessions of an unknown device.
+INSERT INTO sessions (id, created_at, login, last_seen_at, expires_at)
+SELECT family_id, MIN(created_at), MIN(login), MAX(created_at), MAX(expires_at)
+FROM refresh_tokens
+WHERE revoked_at IS NULL
+GROUP BY family_id;
@@ -0,0 +1,11 @@
+CREATE TABLE stats_moves (
+    id              BIGSERIAL PRIMARY KEY,
+    created_at      TIMESTAMPTZ,
+    from_id         BIGINT NOT NULL,
+    to_id           BIGINT NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_stats_moves_next_attempt_at ON stats_moves (next_attempt_at);
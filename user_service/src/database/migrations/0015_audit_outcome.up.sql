@@ -0,0 +1 @@
+ALTER TABLE audit_entries ADD COLUMN IF NOT EXISTS outcome TEXT NOT NULL DEFAULT '';
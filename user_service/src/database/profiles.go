@@ -0,0 +1,121 @@
+package database
+
+import "time"
+
+// Privacy tells which fields of the profile other users see. Name and
+// surname are public by default, the rest is hidden.
+type Privacy struct {
+	Name        bool `json:"name"`
+	Surname     bool `json:"surname"`
+	BirthDay    bool `json:"birth_day"`
+	Mail        bool `json:"mail"`
+	PhoneNumber bool `json:"phone_number"`
+}
+
+// PrivacyUpdate changes only the fields that are set.
+type PrivacyUpdate struct {
+	Name        *bool `json:"name"`
+	Surname     *bool `json:"surname"`
+	BirthDay    *bool `json:"birth_day"`
+	Mail        *bool `json:"mail"`
+	PhoneNumber *bool `json:"phone_number"`
+}
+
+// Profile is what other users see about a user. Hidden fields are empty.
+type Profile struct {
+	Login       string    `json:"login"`
+	Name        string    `json:"name,omitempty"`
+	Surname     string    `json:"surname,omitempty"`
+	BirthDay    string    `json:"birth_day,omitempty"`
+	Mail        string    `json:"mail,omitempty"`
+	PhoneNumber string    `json:"phone_number,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (info *userInfo) privacy() Privacy {
+	return Privacy{
+		Name:        info.PublicName,
+		Surname:     info.PublicSurname,
+		BirthDay:    info.PublicBirthDay,
+		Mail:        info.PublicMail,
+		PhoneNumber: info.PublicPhoneNumber,
+	}
+}
+
+func (info *userInfo) profile() Profile {
+	profile := Profile{Login: info.Login, CreatedAt: info.CreatedAt}
+	if info.PublicName {
+		profile.Name = info.Name
+	}
+	if info.PublicSurname {
+		profile.Surname = info.Surname
+	}
+	if info.PublicBirthDay {
+		profile.BirthDay = info.BirthDay
+	}
+	if info.PublicMail {
+		profile.Mail = info.Mail
+	}
+	if info.PublicPhoneNumber {
+		profile.PhoneNumber = info.PhoneNumber
+	}
+	return profile
+}
+
+func (db *DataBase) GetPrivacy(login string) (Privacy, error) {
+	var info userInfo
+	result := db.First(&info, "login = ?", login)
+	return info.privacy(), result.Error
+}
+
+func (db *DataBase) UpdatePrivacy(login string, update *PrivacyUpdate) error {
+	columns := make(map[string]any)
+	for column, value := range map[string]*bool{
+		"public_name":         update.Name,
+		"public_surname":      update.Surname,
+		"public_birth_day":    update.BirthDay,
+		"public_mail":         update.Mail,
+		"public_phone_number": update.PhoneNumber,
+	} {
+		if value != nil {
+			columns[column] = *value
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	return db.Model(&userInfo{}).Where("login = ?", login).Updates(columns).Error
+}
+
+// GetProfile returns the public profile of the user or
+// gorm.ErrRecordNotFound. Disabled users have none.
+func (db *DataBase) GetProfile(login string) (*Profile, error) {
+	var info userInfo
+	if err := db.First(&info, "login = ? AND disabled_at IS NULL", login).Error; err != nil {
+		return nil, err
+	}
+	profile := info.profile()
+	return &profile, nil
+}
+
+// SearchProfiles is the user directory: users whose login, or public name
+// or surname, contain query, ordered by login. Hidden fields are not
+// searched, so a search can not reveal them.
+func (db *DataBase) SearchProfiles(query string, offset, limit int) ([]Profile, error) {
+	tx := db.Where("disabled_at IS NULL").Order("login").Offset(offset).Limit(limit)
+	if query != "" {
+		pattern := "%" + escapeLike(query) + "%"
+		tx = tx.Where(db.Where("login ILIKE ?", pattern).
+			Or("public_name AND name ILIKE ?", pattern).
+			Or("public_surname AND surname ILIKE ?", pattern))
+	}
+	var infos []userInfo
+	if err := tx.Find(&infos).Error; err != nil {
+		return nil, err
+	}
+	profiles := make([]Profile, 0, len(infos))
+	for i := range infos {
+		profiles = append(profiles, infos[i].profile())
+	}
+	return profiles, nil
+}
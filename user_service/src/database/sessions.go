@@ -0,0 +1,87 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// lastSeenPrecision keeps TouchSession from writing on every request.
+const lastSeenPrecision = time.Minute
+
+// Session is a login on one device. Its ID is the family of its refresh
+// tokens and goes into access tokens as the sid claim.
+type Session struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Login      string     `json:"-"`
+	Device     string     `json:"device,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+// StartSession stores a new session with its first refresh token.
+func (db *DataBase) StartSession(session *Session, hash []byte) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		return tx.Create(&refreshToken{
+			Login:     session.Login,
+			FamilyID:  session.ID,
+			TokenHash: hash,
+			ExpiresAt: session.ExpiresAt,
+		}).Error
+	})
+}
+
+// GetSessions returns sessions of the user that are neither revoked nor
+// expired, the most recently used first.
+func (db *DataBase) GetSessions(login string, now time.Time) ([]Session, error) {
+	sessions := []Session{}
+	result := db.Where("login = ? AND revoked_at IS NULL AND expires_at > ?", login, now).
+		Order("last_seen_at DESC").
+		Find(&sessions)
+	return sessions, result.Error
+}
+
+// RevokeSession ends a session of login: its refresh tokens stop working
+// and its access tokens are refused by TouchSession.
+func (db *DataBase) RevokeSession(login, id string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&Session{}).
+			Where("id = ? AND login = ? AND revoked_at IS NULL", id, login).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrSessionNotFound
+		}
+		return revokeFamily(tx, id)
+	})
+}
+
+// TouchSession reports whether the session is still active and records it
+// was seen.
+func (db *DataBase) TouchSession(id string, now time.Time) (bool, error) {
+	var session Session
+	result := db.Limit(1).Find(&session, "id = ?", id)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 || session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+		return false, nil
+	}
+	if now.Sub(session.LastSeenAt) < lastSeenPrecision {
+		return true, nil
+	}
+	return true, db.Model(&session).Update("last_seen_at", now).Error
+}
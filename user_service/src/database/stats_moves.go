@@ -0,0 +1,56 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StatsMove is a pending move of likes and views of a merged duplicate onto
+// the canonical task. It is deleted once statistics service has done it.
+type StatsMove struct {
+	ID            uint `gorm:"primaryKey"`
+	CreatedAt     time.Time
+	FromID        uint32
+	ToID          uint32
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+}
+
+func (db *DataBase) AddStatsMove(move *StatsMove) error {
+	return db.Create(move).Error
+}
+
+// ClaimStatsMoves returns up to limit due moves and postpones them by
+// lease, so other instances of the service skip them meanwhile.
+func (db *DataBase) ClaimStatsMoves(now time.Time, lease time.Duration, limit int) ([]StatsMove, error) {
+	var moves []StatsMove
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("next_attempt_at <= ?", now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&moves)
+		if result.Error != nil || len(moves) == 0 {
+			return result.Error
+		}
+		ids := make([]uint, 0, len(moves))
+		for _, move := range moves {
+			ids = append(ids, move.ID)
+		}
+		return tx.Model(&StatsMove{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return moves, err
+}
+
+func (db *DataBase) SaveStatsMove(move *StatsMove) error {
+	return db.Save(move).Error
+}
+
+func (db *DataBase) DeleteStatsMove(id uint) error {
+	return db.Delete(&StatsMove{}, id).Error
+}
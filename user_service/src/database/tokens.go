@@ -0,0 +1,165 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrTokenReused  = errors.New("refresh token reuse detected, all tokens of the session are revoked")
+)
+
+// refreshToken is stored as a hash only. Tokens issued one from another
+// share a family, which is revoked as a whole once a used token shows up
+// again.
+type refreshToken struct {
+	ID        uint
+	CreatedAt time.Time
+	Login     string
+	FamilyID  string
+	TokenHash []byte
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+type revokedToken struct {
+	Jti       string `gorm:"primaryKey"`
+	ExpiresAt time.Time
+}
+
+func (db *DataBase) GetTokenGeneration(login string) (int, error) {
+	var info userInfo
+	result := db.Select("token_generation").First(&info, "login = ?", login)
+	return info.TokenGeneration, result.Error
+}
+
+// RotateRefreshToken marks the token with oldHash used and stores newHash in
+// the same family. The session of the family is prolonged and remembers
+// the client's ip and user agent. It returns the owner and the family of
+// the token.
+func (db *DataBase) RotateRefreshToken(oldHash, newHash []byte, expiresAt time.Time, ip, userAgent string) (login, familyID string, err error) {
+	reused := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var token refreshToken
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(&token, "token_hash = ?", oldHash)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 || token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+			return ErrTokenInvalid
+		}
+		if token.UsedAt != nil {
+			reused = true
+			return revokeFamily(tx, token.FamilyID)
+		}
+
+		login, familyID = token.Login, token.FamilyID
+		now := time.Now()
+		if err := tx.Model(&token).Update("used_at", now).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&Session{}).Where("id = ?", token.FamilyID).Updates(map[string]any{
+			"ip":           ip,
+			"user_agent":   userAgent,
+			"last_seen_at": now,
+			"expires_at":   expiresAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&refreshToken{
+			Login:     token.Login,
+			FamilyID:  token.FamilyID,
+			TokenHash: newHash,
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if err == nil && reused {
+		err = ErrTokenReused
+	}
+	return login, familyID, err
+}
+
+// RevokeRefreshToken revokes the family of the token if it belongs to login.
+func (db *DataBase) RevokeRefreshToken(login string, hash []byte) error {
+	var token refreshToken
+	result := db.Limit(1).Find(&token, "token_hash = ? AND login = ?", hash, login)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return revokeFamily(db.DB, token.FamilyID)
+}
+
+// LogoutAll revokes all refresh tokens and sessions of the user, deletes
+// personal access tokens and invalidates JWTs issued so far by bumping the
+// token generation.
+func (db *DataBase) LogoutAll(login string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return logoutAll(tx, login)
+	})
+}
+
+// logoutAll makes every credential issued to the user so far stop working,
+// so it also runs when the password changes or the account is disabled.
+func logoutAll(tx *gorm.DB, login string) error {
+	for _, model := range []any{&refreshToken{}, &Session{}} {
+		result := tx.Model(model).
+			Where("login = ? AND revoked_at IS NULL", login).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+	if err := tx.Where("login = ?", login).Delete(&accessToken{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&userInfo{}).
+		Where("login = ?", login).
+		Update("token_generation", gorm.Expr("token_generation + 1")).Error
+}
+
+// RevokeToken puts an access token on the revocation list until it expires.
+func (db *DataBase) RevokeToken(jti string, expiresAt time.Time) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&revokedToken{Jti: jti, ExpiresAt: expiresAt}).Error
+}
+
+func (db *DataBase) IsTokenRevoked(jti string) (bool, error) {
+	var count int64
+	result := db.Model(&revokedToken{}).Where("jti = ?", jti).Count(&count)
+	return count > 0, result.Error
+}
+
+// PurgeExpiredTokens drops revocation entries, used mail tokens, refresh
+// tokens and sessions that have expired anyway.
+func (db *DataBase) PurgeExpiredTokens(now time.Time) error {
+	if err := db.Where("expires_at < ?", now).Delete(&revokedToken{}).Error; err != nil {
+		return err
+	}
+	if err := db.Where("expires_at < ?", now).Delete(&usedMailToken{}).Error; err != nil {
+		return err
+	}
+	if err := db.Where("expires_at < ?", now).Delete(&refreshToken{}).Error; err != nil {
+		return err
+	}
+	return db.Where("expires_at < ?", now).Delete(&Session{}).Error
+}
+
+// revokeFamily also revokes the session of the family, so its access
+// tokens stop working too.
+func revokeFamily(tx *gorm.DB, familyID string) error {
+	now := time.Now()
+	err := tx.Model(&refreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&Session{}).
+		Where("id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
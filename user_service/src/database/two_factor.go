@@ -0,0 +1,126 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication is not enrolled")
+	ErrRecoveryCodeInvalid  = errors.New("recovery code is invalid or used")
+)
+
+// recoveryCode replaces a TOTP code once, for users who lost their device.
+type recoveryCode struct {
+	ID       uint
+	Login    string
+	CodeHash []byte
+	UsedAt   *time.Time
+}
+
+type TwoFactor struct {
+	Secret   []byte
+	Enabled  bool
+	LastStep int64
+}
+
+func (db *DataBase) GetTwoFactor(login string) (TwoFactor, error) {
+	var info userInfo
+	result := db.Select("totp_secret", "totp_enabled", "totp_last_step").First(&info, "login = ?", login)
+	return TwoFactor{
+		Secret:   info.TotpSecret,
+		Enabled:  info.TotpEnabled,
+		LastStep: info.TotpLastStep,
+	}, result.Error
+}
+
+// SetTOTPSecret stores a new secret for enrollment. It takes effect only
+// after EnableTwoFactor.
+func (db *DataBase) SetTOTPSecret(login string, secret []byte) error {
+	result := db.Model(&userInfo{}).
+		Where("login = ? AND NOT totp_enabled", login).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorEnabled
+	}
+	return nil
+}
+
+// EnableTwoFactor turns the enrolled secret on, remembers the step of the
+// confirming code and replaces the recovery codes.
+func (db *DataBase) EnableTwoFactor(login string, step int64, codeHashes [][]byte) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&userInfo{}).
+			Where("login = ? AND NOT totp_enabled AND totp_secret IS NOT NULL", login).
+			Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTwoFactorNotEnrolled
+		}
+		return setRecoveryCodes(tx, login, codeHashes)
+	})
+}
+
+func (db *DataBase) DisableTwoFactor(login string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&userInfo{}).
+			Where("login = ?", login).
+			Updates(map[string]interface{}{"totp_secret": nil, "totp_enabled": false, "totp_last_step": 0}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("login = ?", login).Delete(&recoveryCode{}).Error
+	})
+}
+
+// UseTOTPStep accepts a code of step only if no code of this or a later
+// step was accepted before, so a code can not be replayed.
+func (db *DataBase) UseTOTPStep(login string, step int64) (bool, error) {
+	result := db.Model(&userInfo{}).
+		Where("login = ? AND totp_enabled AND totp_last_step < ?", login, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (db *DataBase) UseRecoveryCode(login string, hash []byte) error {
+	result := db.Model(&recoveryCode{}).
+		Where("login = ? AND code_hash = ? AND used_at IS NULL", login, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecoveryCodeInvalid
+	}
+	return nil
+}
+
+// UseToken marks a single-use signed token as used until it expires.
+func (db *DataBase) UseToken(nonce string, expiresAt time.Time) error {
+	return useMailToken(db.DB, nonce, expiresAt)
+}
+
+func (db *DataBase) TokenUsed(nonce string) (bool, error) {
+	var count int64
+	result := db.Model(&usedMailToken{}).Where("nonce = ?", nonce).Count(&count)
+	return count > 0, result.Error
+}
+
+func setRecoveryCodes(tx *gorm.DB, login string, codeHashes [][]byte) error {
+	if err := tx.Where("login = ?", login).Delete(&recoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]recoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, recoveryCode{Login: login, CodeHash: hash})
+	}
+	return tx.Create(&codes).Error
+}
@@ -0,0 +1,241 @@
+package database
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrViewExists       = errors.New("view with this name already exists")
+	ErrViewNotFound     = errors.New("view not found")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+type savedView struct {
+	ID        uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Owner     string
+	Name      string
+	Query     string
+}
+
+type savedViewShare struct {
+	ViewID uint   `gorm:"primaryKey"`
+	Login  string `gorm:"primaryKey"`
+}
+
+type defaultView struct {
+	Login  string `gorm:"primaryKey"`
+	ViewID uint
+}
+
+// View is a named task query. The owner may share it with other users,
+// who can use it but not change it.
+type View struct {
+	ID         uint     `json:"id,omitempty"`
+	Owner      string   `json:"owner,omitempty"`
+	Name       string   `json:"name"`
+	Query      string   `json:"query"`
+	SharedWith []string `json:"shared_with"`
+	Default    bool     `json:"default"`
+}
+
+func (db *DataBase) CreateView(view *View) (uint, error) {
+	info := &savedView{Owner: view.Owner, Name: view.Name, Query: view.Query}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := nameTaken(tx, view.Owner, view.Name, 0); err != nil {
+			return err
+		}
+		if err := tx.Create(info).Error; err != nil {
+			return err
+		}
+		if err := setShares(tx, info.ID, view.SharedWith); err != nil {
+			return err
+		}
+		if view.Default {
+			return setDefault(tx, view.Owner, info.ID)
+		}
+		return nil
+	})
+	return info.ID, err
+}
+
+// UpdateView replaces name, query and the share list of a view. Only the
+// owner may update it.
+func (db *DataBase) UpdateView(view *View) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		info, err := getView(tx, view.ID)
+		if err != nil {
+			return err
+		}
+		if info.Owner != view.Owner {
+			return ErrPermissionDenied
+		}
+		if err := nameTaken(tx, view.Owner, view.Name, view.ID); err != nil {
+			return err
+		}
+		result := tx.Model(info).Updates(map[string]any{"name": view.Name, "query": view.Query})
+		if result.Error != nil {
+			return result.Error
+		}
+		return setShares(tx, view.ID, view.SharedWith)
+	})
+}
+
+func (db *DataBase) DeleteView(id uint, login string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		info, err := getView(tx, id)
+		if err != nil {
+			return err
+		}
+		if info.Owner != login {
+			return ErrPermissionDenied
+		}
+		return tx.Delete(info).Error
+	})
+}
+
+// GetView returns a view owned by or shared with login.
+func (db *DataBase) GetView(id uint, login string) (*View, error) {
+	info, err := getView(db.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	views, err := db.toViews([]savedView{*info}, login)
+	if err != nil {
+		return nil, err
+	}
+	view := &views[0]
+	if view.Owner != login && !slices.Contains(view.SharedWith, login) {
+		return nil, ErrPermissionDenied
+	}
+	return view, nil
+}
+
+// GetViews returns views owned by or shared with login, own views first.
+func (db *DataBase) GetViews(login string) ([]View, error) {
+	var infos []savedView
+	result := db.Where("owner = ?", login).
+		Or("id IN (?)", db.Model(&savedViewShare{}).Select("view_id").Where("login = ?", login)).
+		Order(clause.Expr{SQL: "owner <> ?, name, id", Vars: []any{login}}).
+		Find(&infos)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return db.toViews(infos, login)
+}
+
+// SetDefaultView makes the view default for login. Zero id clears it.
+func (db *DataBase) SetDefaultView(login string, id uint) error {
+	if id == 0 {
+		return db.Delete(&defaultView{Login: login}).Error
+	}
+	if _, err := db.GetView(id, login); err != nil {
+		return err
+	}
+	return setDefault(db.DB, login, id)
+}
+
+// GetDefaultView returns nil if login has no default view or lost access to
+// it.
+func (db *DataBase) GetDefaultView(login string) (*View, error) {
+	var def defaultView
+	result := db.Limit(1).Find(&def, "login = ?", login)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	view, err := db.GetView(def.ViewID, login)
+	if err == ErrViewNotFound || err == ErrPermissionDenied {
+		return nil, nil
+	}
+	return view, err
+}
+
+func getView(tx *gorm.DB, id uint) (*savedView, error) {
+	var info savedView
+	result := tx.First(&info, id)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, ErrViewNotFound
+	}
+	return &info, result.Error
+}
+
+func nameTaken(tx *gorm.DB, owner, name string, except uint) error {
+	var count int64
+	result := tx.Model(&savedView{}).Where("owner = ? AND name = ? AND id <> ?", owner, name, except).Count(&count)
+	if result.Error != nil {
+		return result.Error
+	}
+	if count > 0 {
+		return ErrViewExists
+	}
+	return nil
+}
+
+func setShares(tx *gorm.DB, id uint, logins []string) error {
+	if err := tx.Where("view_id = ?", id).Delete(&savedViewShare{}).Error; err != nil {
+		return err
+	}
+	if len(logins) == 0 {
+		return nil
+	}
+	shares := make([]savedViewShare, 0, len(logins))
+	for _, login := range logins {
+		shares = append(shares, savedViewShare{ViewID: id, Login: login})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&shares).Error
+}
+
+func setDefault(tx *gorm.DB, login string, id uint) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "login"}},
+		DoUpdates: clause.AssignmentColumns([]string{"view_id"}),
+	}).Create(&defaultView{Login: login, ViewID: id}).Error
+}
+
+func (db *DataBase) toViews(infos []savedView, login string) ([]View, error) {
+	if len(infos) == 0 {
+		return []View{}, nil
+	}
+
+	ids := make([]uint, 0, len(infos))
+	for _, info := range infos {
+		ids = append(ids, info.ID)
+	}
+
+	var shares []savedViewShare
+	if err := db.Where("view_id IN ?", ids).Order("login").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	sharedWith := make(map[uint][]string, len(infos))
+	for _, share := range shares {
+		sharedWith[share.ViewID] = append(sharedWith[share.ViewID], share.Login)
+	}
+
+	var def defaultView
+	if err := db.Limit(1).Find(&def, "login = ?", login).Error; err != nil {
+		return nil, err
+	}
+
+	views := make([]View, 0, len(infos))
+	for _, info := range infos {
+		shared := sharedWith[info.ID]
+		if shared == nil {
+			shared = []string{}
+		}
+		views = append(views, View{
+			ID:         info.ID,
+			Owner:      info.Owner,
+			Name:       info.Name,
+			Query:      info.Query,
+			SharedWith: shared,
+			Default:    info.ID == def.ViewID,
+		})
+	}
+	return views, nil
+}
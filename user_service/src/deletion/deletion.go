@@ -0,0 +1,208 @@
+// Package deletion drives account deletion across services. A deletion is a
+// saga of steps, each of them safe to repeat: collect the user's task IDs,
+// delete the tasks in tasks manager, drop statistics through the Stat topic
+// and finally remove the profile from user_db. The state is kept in user_db,
+// so a deletion survives restarts, and a failed step is retried with backoff
+// until it succeeds. There are no compensations: once the user is locked
+// out, the only way is forward.
+package deletion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	pb "userservice/proto"
+	statpb "userservice/proto/statistic"
+	"userservice/src/broker"
+	"userservice/src/database"
+)
+
+const (
+	StepCollectTasks = "collect_tasks"
+	StepTasks        = "tasks"
+	StepStatistics   = "statistics"
+	StepProfile      = "profile"
+	StepDone         = "done"
+)
+
+const (
+	baseDelay = 5 * time.Second
+	maxDelay  = 10 * time.Minute
+
+	// lease keeps a claimed deletion from other instances while it runs.
+	lease       = time.Minute
+	batchSize   = 10
+	callTimeout = 10 * time.Second
+
+	// statsResend is how long to wait for statistics service before
+	// publishing the request again, in case it was not consuming.
+	statsResend = time.Minute
+)
+
+var errStatsPending = errors.New("waiting for statistics service")
+
+type Store interface {
+	ClaimAccountDeletions(now time.Time, lease time.Duration, limit int) ([]database.AccountDeletion, error)
+	SaveAccountDeletion(deletion *database.AccountDeletion) error
+	DeleteUserData(login string) error
+}
+
+type Publisher interface {
+	SendUserDeletion(deletion broker.UserDeletion) error
+}
+
+type Worker struct {
+	store     Store
+	tasks     pb.TaskServiceClient
+	stats     statpb.StatisticsServiceClient
+	publisher Publisher
+	kick      chan struct{}
+}
+
+func NewWorker(store Store, tasks pb.TaskServiceClient, stats statpb.StatisticsServiceClient, publisher Publisher) *Worker {
+	return &Worker{
+		store:     store,
+		tasks:     tasks,
+		stats:     stats,
+		publisher: publisher,
+		kick:      make(chan struct{}, 1),
+	}
+}
+
+// Backoff is the delay before the next attempt after attempts failures.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	delay := baseDelay
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// Run processes due deletions every interval and whenever kicked, until
+// stop is closed.
+func (w *Worker) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case now = <-ticker.C:
+		case <-w.kick:
+			now = time.Now()
+		case <-stop:
+			return
+		}
+		if err := w.Process(now); err != nil {
+			slog.Error("Can not process account deletions.", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// Kick makes Run look for due deletions right away.
+func (w *Worker) Kick() {
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Process advances every due deletion as far as it goes.
+func (w *Worker) Process(now time.Time) error {
+	deletions, err := w.store.ClaimAccountDeletions(now, lease, batchSize)
+	if err != nil {
+		return err
+	}
+	for i := range deletions {
+		w.advance(&deletions[i], now)
+	}
+	return nil
+}
+
+func (w *Worker) advance(deletion *database.AccountDeletion, now time.Time) {
+	for deletion.Step != StepDone {
+		if err := w.step(deletion, now); err != nil {
+			deletion.Attempts++
+			deletion.LastError = err.Error()
+			deletion.NextAttemptAt = now.Add(Backoff(deletion.Attempts))
+			if err != errStatsPending {
+				slog.Error("Account deletion failed.", slog.String("deletion_id", deletion.ID), slog.String("login", deletion.Login),
+					slog.String("step", string(deletion.Step)), slog.Int("attempts", deletion.Attempts), slog.String("error", err.Error()))
+			}
+			w.save(deletion)
+			return
+		}
+		deletion.Attempts = 0
+		deletion.LastError = ""
+		deletion.NextAttemptAt = now
+		if deletion.Step == StepDone {
+			deletion.CompletedAt = &now
+			deletion.Login = ""
+			deletion.TaskIDs = nil
+		}
+		w.save(deletion)
+	}
+}
+
+func (w *Worker) save(deletion *database.AccountDeletion) {
+	if err := w.store.SaveAccountDeletion(deletion); err != nil {
+		slog.Error("Can not save account deletion.", slog.String("deletion_id", deletion.ID), slog.String("error", err.Error()))
+	}
+}
+
+// step runs the current step and moves the deletion to the next one.
+func (w *Worker) step(deletion *database.AccountDeletion, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	switch deletion.Step {
+	case StepCollectTasks:
+		resp, err := w.tasks.GetUserTaskIds(ctx, &pb.UserDataRequest{Login: deletion.Login})
+		if err != nil {
+			return fmt.Errorf("can not get tasks: %w", err)
+		}
+		deletion.TaskIDs = resp.Ids
+		deletion.Step = StepTasks
+
+	case StepTasks:
+		if _, err := w.tasks.DeleteUserData(ctx, &pb.UserDataRequest{Login: deletion.Login}); err != nil {
+			return fmt.Errorf("can not delete tasks: %w", err)
+		}
+		deletion.Step = StepStatistics
+
+	case StepStatistics:
+		if deletion.StatsRequestedAt == nil || now.Sub(*deletion.StatsRequestedAt) >= statsResend {
+			err := w.publisher.SendUserDeletion(broker.UserDeletion{
+				ID:      deletion.ID,
+				Login:   deletion.Login,
+				TaskIDs: deletion.TaskIDs,
+			})
+			if err != nil {
+				return fmt.Errorf("can not send deletion to statistics service: %w", err)
+			}
+			deletion.StatsRequestedAt = &now
+		}
+		resp, err := w.stats.GetUserDeletion(ctx, &statpb.GetUserDeletionRequest{Id: deletion.ID})
+		if err != nil {
+			return fmt.Errorf("can not check statistics deletion: %w", err)
+		}
+		if !resp.Done {
+			return errStatsPending
+		}
+		deletion.Step = StepProfile
+
+	case StepProfile:
+		if err := w.store.DeleteUserData(deletion.Login); err != nil {
+			return fmt.Errorf("can not delete profile: %w", err)
+		}
+		deletion.Step = StepDone
+
+	default:
+		return fmt.Errorf("unknown step %q", deletion.Step)
+	}
+	return nil
+}
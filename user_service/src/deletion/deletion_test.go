@@ -0,0 +1,181 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	pb "userservice/proto"
+	statpb "userservice/proto/statistic"
+	"userservice/src/broker"
+	"userservice/src/database"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type fakeStore struct {
+	deletions map[string]*database.AccountDeletion
+	deleted   []string
+}
+
+func (s *fakeStore) ClaimAccountDeletions(now time.Time, lease time.Duration, limit int) ([]database.AccountDeletion, error) {
+	var due []database.AccountDeletion
+	for _, deletion := range s.deletions {
+		if deletion.CompletedAt == nil && !deletion.NextAttemptAt.After(now) {
+			due = append(due, *deletion)
+			deletion.NextAttemptAt = now.Add(lease)
+		}
+	}
+	return due, nil
+}
+
+func (s *fakeStore) SaveAccountDeletion(deletion *database.AccountDeletion) error {
+	saved := *deletion
+	s.deletions[deletion.ID] = &saved
+	return nil
+}
+
+func (s *fakeStore) DeleteUserData(login string) error {
+	s.deleted = append(s.deleted, login)
+	return nil
+}
+
+type fakeTasks struct {
+	pb.TaskServiceClient
+	failures int
+	deleted  []string
+}
+
+func (t *fakeTasks) GetUserTaskIds(ctx context.Context, req *pb.UserDataRequest, opts ...grpc.CallOption) (*pb.GetUserTaskIdsResponse, error) {
+	return &pb.GetUserTaskIdsResponse{Ids: []uint32{3, 7}}, nil
+}
+
+func (t *fakeTasks) DeleteUserData(ctx context.Context, req *pb.UserDataRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	if t.failures > 0 {
+		t.failures--
+		return nil, errors.New("tasks manager is down")
+	}
+	t.deleted = append(t.deleted, req.Login)
+	return &emptypb.Empty{}, nil
+}
+
+// fakeStats handles a deletion once it was published, like statistics
+// service consuming the Stat topic.
+type fakeStats struct {
+	statpb.StatisticsServiceClient
+	sent []broker.UserDeletion
+	done map[string]bool
+}
+
+func (s *fakeStats) SendUserDeletion(deletion broker.UserDeletion) error {
+	s.sent = append(s.sent, deletion)
+	return nil
+}
+
+func (s *fakeStats) GetUserDeletion(ctx context.Context, req *statpb.GetUserDeletionRequest, opts ...grpc.CallOption) (*statpb.GetUserDeletionResponse, error) {
+	return &statpb.GetUserDeletionResponse{Done: s.done[req.Id]}, nil
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{0, 0},
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{8, 10 * time.Minute},
+		{1000, 10 * time.Minute},
+	}
+	for _, test := range tests {
+		if delay := Backoff(test.attempts); delay != test.delay {
+			t.Errorf("%d attempts: expected %v, got %v", test.attempts, test.delay, delay)
+		}
+	}
+}
+
+func TestProcess(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := &fakeStore{deletions: map[string]*database.AccountDeletion{
+		"d1": {ID: "d1", Login: "kek", Step: StepCollectTasks, NextAttemptAt: now},
+	}}
+	tasks := &fakeTasks{failures: 1}
+	stats := &fakeStats{done: map[string]bool{}}
+	w := NewWorker(store, tasks, stats, stats)
+
+	// Tasks manager fails: the deletion waits for the next attempt.
+	if err := w.Process(now); err != nil {
+		t.Fatal(err)
+	}
+	deletion := store.deletions["d1"]
+	if deletion.Step != StepTasks || deletion.Attempts != 1 || deletion.LastError == "" {
+		t.Fatalf("expected a failed tasks step, got %#v", deletion)
+	}
+	if len(deletion.TaskIDs) != 2 {
+		t.Errorf("expected collected task IDs, got %v", deletion.TaskIDs)
+	}
+	if !deletion.NextAttemptAt.Equal(now.Add(5 * time.Second)) {
+		t.Errorf("expected the next attempt in 5s, got %v", deletion.NextAttemptAt)
+	}
+
+	if err := w.Process(now.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks.deleted) != 0 {
+		t.Fatal("expected no retry before the backoff passes")
+	}
+
+	// Statistics service has not handled the message yet.
+	now = now.Add(5 * time.Second)
+	if err := w.Process(now); err != nil {
+		t.Fatal(err)
+	}
+	deletion = store.deletions["d1"]
+	if len(tasks.deleted) != 1 || deletion.Step != StepStatistics || deletion.LastError != errStatsPending.Error() {
+		t.Fatalf("expected to wait for statistics, got %#v", deletion)
+	}
+	if len(stats.sent) != 1 || stats.sent[0].Login != "kek" || len(stats.sent[0].TaskIDs) != 2 {
+		t.Errorf("unexpected deletion messages: %#v", stats.sent)
+	}
+
+	stats.done["d1"] = true
+	now = now.Add(5 * time.Second)
+	if err := w.Process(now); err != nil {
+		t.Fatal(err)
+	}
+	deletion = store.deletions["d1"]
+	if deletion.Step != StepDone || deletion.CompletedAt == nil || deletion.Attempts != 0 {
+		t.Fatalf("expected a completed deletion, got %#v", deletion)
+	}
+	if deletion.Login != "" || deletion.TaskIDs != nil {
+		t.Errorf("expected the login to be forgotten, got %#v", deletion)
+	}
+	if len(store.deleted) != 1 || store.deleted[0] != "kek" {
+		t.Errorf("expected the profile to be deleted, got %v", store.deleted)
+	}
+	if len(stats.sent) != 1 {
+		t.Errorf("expected no resend, got %d messages", len(stats.sent))
+	}
+}
+
+func TestStatsResend(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	requested := now.Add(-statsResend)
+	store := &fakeStore{deletions: map[string]*database.AccountDeletion{
+		"d1": {ID: "d1", Login: "kek", Step: StepStatistics, StatsRequestedAt: &requested, NextAttemptAt: now},
+	}}
+	stats := &fakeStats{done: map[string]bool{}}
+	w := NewWorker(store, &fakeTasks{}, stats, stats)
+
+	if err := w.Process(now); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.sent) != 1 {
+		t.Errorf("expected the request to be sent again, got %d messages", len(stats.sent))
+	}
+	if got := store.deletions["d1"].StatsRequestedAt; got == nil || !got.Equal(now) {
+		t.Errorf("expected the request time to be updated, got %v", got)
+	}
+}
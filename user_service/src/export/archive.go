@@ -0,0 +1,127 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	pb "userservice/proto"
+	"userservice/src/database"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type Profile struct {
+	Login string `json:"login"`
+	database.UserData
+	Privacy            database.Privacy            `json:"privacy"`
+	TwoFactorEnabled   bool                        `json:"two_factor_enabled"`
+	AccessTokens       []database.AccessToken      `json:"access_tokens"`
+	ExternalIdentities []database.ExternalIdentity `json:"external_identities"`
+	SavedViews         []database.View             `json:"saved_views"`
+}
+
+type Reaction struct {
+	TaskID uint32    `json:"task_id"`
+	Time   time.Time `json:"time"`
+}
+
+type TaskCounts struct {
+	TaskID uint32 `json:"task_id"`
+	Likes  int32  `json:"likes"`
+	Views  int32  `json:"views"`
+}
+
+type Statistics struct {
+	LikesGiven []Reaction `json:"likes_given"`
+	ViewsGiven []Reaction `json:"views_given"`
+	// Received counts likes and views of the user's tasks. Who left them is
+	// personal data of other users and is not exported.
+	Received []TaskCounts `json:"received"`
+}
+
+// Data is everything the service keeps about a user.
+type Data struct {
+	CreatedAt  time.Time
+	Profile    Profile
+	Tasks      []*pb.Task
+	Statistics Statistics
+}
+
+// WriteArchive writes data as a ZIP of JSON files with a README.
+func WriteArchive(w io.Writer, data *Data) error {
+	tasks, err := tasksJSON(data.Tasks)
+	if err != nil {
+		return err
+	}
+	profile, err := json.MarshalIndent(data.Profile, "", "    ")
+	if err != nil {
+		return err
+	}
+	statistics, err := json.MarshalIndent(data.Statistics, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		name string
+		body []byte
+	}{
+		{"README.txt", []byte(readme(data))},
+		{"profile.json", profile},
+		{"tasks.json", tasks},
+		{"statistics.json", statistics},
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: data.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(file.body); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// tasksJSON keeps field names of the proto messages, the same as in the
+// HTTP API.
+func tasksJSON(tasks []*pb.Task) ([]byte, error) {
+	options := protojson.MarshalOptions{UseProtoNames: true}
+	raw := make([]json.RawMessage, 0, len(tasks))
+	for _, task := range tasks {
+		data, err := options.Marshal(task)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, data)
+	}
+	return json.MarshalIndent(raw, "", "    ")
+}
+
+func readme(data *Data) string {
+	var likesReceived, viewsReceived int32
+	for _, counts := range data.Statistics.Received {
+		likesReceived += counts.Likes
+		viewsReceived += counts.Views
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Выгрузка данных пользователя %s\n", data.Profile.Login)
+	fmt.Fprintf(&b, "Создана %s\n\n", data.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "profile.json — профиль: имя, почта, телефон, настройки видимости профиля, персональные токены (%d), привязанные внешние аккаунты (%d) и сохранённые представления (%d). Пароль, секрет TOTP и коды восстановления не выгружаются.\n",
+		len(data.Profile.AccessTokens), len(data.Profile.ExternalIdentities), len(data.Profile.SavedViews))
+	fmt.Fprintf(&b, "tasks.json — задачи (%d) с полями, чек-листами и связями. История изменений задач не хранится, есть только время создания и последнего изменения. Комментариев к задачам в сервисе нет.\n",
+		len(data.Tasks))
+	fmt.Fprintf(&b, "statistics.json — поставленные лайки (%d) и просмотры (%d) со временем, а также лайки (%d) и просмотры (%d) ваших задач по задачам, без имён поставивших их пользователей.\n",
+		len(data.Statistics.LikesGiven), len(data.Statistics.ViewsGiven), likesReceived, viewsReceived)
+	return b.String()
+}
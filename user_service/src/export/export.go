@@ -0,0 +1,269 @@
+// Package export builds personal data archives in the background. A job
+// collects the profile from user_db, tasks from tasks manager and likes and
+// views from statistics service, and stores a ZIP for download.
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+	pb "userservice/proto"
+	statpb "userservice/proto/statistic"
+	"userservice/src/database"
+)
+
+const (
+	// TTL is how long an archive can be downloaded.
+	TTL = 24 * time.Hour
+
+	maxAttempts = 5
+	retryDelay  = 30 * time.Second
+
+	// lease keeps a claimed job from other instances while it runs.
+	lease       = 5 * time.Minute
+	batchSize   = 5
+	callTimeout = time.Minute
+)
+
+type Store interface {
+	ClaimAccountExports(now time.Time, lease time.Duration, limit int) ([]database.AccountExport, error)
+	SaveAccountExport(export *database.AccountExport) error
+	DeleteExpiredExports(now time.Time) error
+
+	GetUserData(login string) (*database.UserData, error)
+	GetPrivacy(login string) (database.Privacy, error)
+	GetTwoFactor(login string) (database.TwoFactor, error)
+	GetAccessTokens(login string) ([]database.AccessToken, error)
+	GetExternalIdentities(login string) ([]database.ExternalIdentity, error)
+	GetViews(login string) ([]database.View, error)
+}
+
+type Worker struct {
+	store Store
+	tasks pb.TaskServiceClient
+	stats statpb.StatisticsServiceClient
+	kick  chan struct{}
+}
+
+func NewWorker(store Store, tasks pb.TaskServiceClient, stats statpb.StatisticsServiceClient) *Worker {
+	return &Worker{
+		store: store,
+		tasks: tasks,
+		stats: stats,
+		kick:  make(chan struct{}, 1),
+	}
+}
+
+// Run processes due jobs every interval and whenever kicked, until stop is
+// closed.
+func (w *Worker) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case now = <-ticker.C:
+		case <-w.kick:
+			now = time.Now()
+		case <-stop:
+			return
+		}
+		if err := w.Process(now); err != nil {
+			slog.Error("Can not process exports.", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// Kick makes Run look for due jobs right away.
+func (w *Worker) Kick() {
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Process builds archives of due jobs and drops expired ones.
+func (w *Worker) Process(now time.Time) error {
+	if err := w.store.DeleteExpiredExports(now); err != nil {
+		return err
+	}
+	exports, err := w.store.ClaimAccountExports(now, lease, batchSize)
+	if err != nil {
+		return err
+	}
+	for i := range exports {
+		w.build(&exports[i], now)
+	}
+	return nil
+}
+
+// build gives up after maxAttempts, the user can start a new export then.
+func (w *Worker) build(export *database.AccountExport, now time.Time) {
+	data, err := w.collect(export.Login, now)
+	var archive bytes.Buffer
+	if err == nil {
+		err = WriteArchive(&archive, data)
+	}
+
+	if err != nil {
+		export.Attempts++
+		export.LastError = err.Error()
+		export.NextAttemptAt = now.Add(time.Duration(export.Attempts) * retryDelay)
+		if export.Attempts >= maxAttempts {
+			export.Status = database.ExportFailed
+		}
+		slog.Error("Export failed.", slog.String("export_id", export.ID), slog.String("login", export.Login),
+			slog.Int("attempts", export.Attempts), slog.String("error", err.Error()))
+	} else {
+		expiresAt := now.Add(TTL)
+		export.Status = database.ExportReady
+		export.LastError = ""
+		export.Archive = archive.Bytes()
+		export.ExpiresAt = &expiresAt
+	}
+	if err := w.store.SaveAccountExport(export); err != nil {
+		slog.Error("Can not save export.", slog.String("export_id", export.ID), slog.String("error", err.Error()))
+	}
+}
+
+func (w *Worker) collect(login string, now time.Time) (*Data, error) {
+	profile, err := w.profile(login)
+	if err != nil {
+		return nil, fmt.Errorf("can not get profile: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	tasks, err := w.userTasks(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("can not get tasks: %w", err)
+	}
+	taskIDs := make([]uint32, 0, len(tasks))
+	for _, task := range tasks {
+		taskIDs = append(taskIDs, task.Id)
+	}
+	activity, err := w.stats.GetUserActivity(ctx, &statpb.GetUserActivityRequest{Login: login, TaskIds: taskIDs})
+	if err != nil {
+		return nil, fmt.Errorf("can not get statistics: %w", err)
+	}
+
+	return &Data{
+		CreatedAt:  now,
+		Profile:    *profile,
+		Tasks:      tasks,
+		Statistics: statistics(activity),
+	}, nil
+}
+
+func (w *Worker) profile(login string) (*Profile, error) {
+	data, err := w.store.GetUserData(login)
+	if err != nil {
+		return nil, err
+	}
+	privacy, err := w.store.GetPrivacy(login)
+	if err != nil {
+		return nil, err
+	}
+	twoFactor, err := w.store.GetTwoFactor(login)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := w.store.GetAccessTokens(login)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := w.store.GetExternalIdentities(login)
+	if err != nil {
+		return nil, err
+	}
+	views, err := w.store.GetViews(login)
+	if err != nil {
+		return nil, err
+	}
+	return &Profile{
+		Login:              login,
+		UserData:           *data,
+		Privacy:            privacy,
+		TwoFactorEnabled:   twoFactor.Enabled,
+		AccessTokens:       tokens,
+		ExternalIdentities: identities,
+		SavedViews:         views,
+	}, nil
+}
+
+// userTasks returns full tasks of the user, with checklists and links that
+// only GetTask fills in.
+func (w *Worker) userTasks(ctx context.Context, login string) ([]*pb.Task, error) {
+	resp, err := w.tasks.GetTasks(ctx, &pb.GetTasksRequest{Author: login, BatchSize: -1})
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]*pb.Task, 0, len(resp.Tasks))
+	for _, task := range resp.Tasks {
+		full, err := w.tasks.GetTask(ctx, &pb.GetTaskRequest{Id: task.Id, Author: login})
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, full)
+	}
+	return tasks, nil
+}
+
+func statistics(activity *statpb.GetUserActivityResponse) Statistics {
+	counts := make(map[uint32]*TaskCounts)
+	countsOf := func(id uint32) *TaskCounts {
+		if counts[id] == nil {
+			counts[id] = &TaskCounts{TaskID: id}
+		}
+		return counts[id]
+	}
+	for id, likes := range activity.LikesReceived {
+		countsOf(id).Likes = likes
+	}
+	for id, views := range activity.ViewsReceived {
+		countsOf(id).Views = views
+	}
+
+	received := make([]TaskCounts, 0, len(counts))
+	for _, c := range counts {
+		received = append(received, *c)
+	}
+	sort.Slice(received, func(i, j int) bool {
+		return received[i].TaskID < received[j].TaskID
+	})
+
+	return Statistics{
+		LikesGiven: reactions(activity.Likes),
+		ViewsGiven: reactions(activity.Views),
+		Received:   received,
+	}
+}
+
+func reactions(in []*statpb.Reaction) []Reaction {
+	out := make([]Reaction, 0, len(in))
+	for _, reaction := range in {
+		out = append(out, Reaction{TaskID: reaction.TaskId, Time: reaction.Time.AsTime()})
+	}
+	return out
+}
+
+// NewJob returns a pending export of login. The random ID goes into the
+// download link.
+func NewJob(login string, now time.Time) (*database.AccountExport, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &database.AccountExport{
+		ID:            base64.RawURLEncoding.EncodeToString(id),
+		Login:         login,
+		Status:        database.ExportPending,
+		NextAttemptAt: now,
+	}, nil
+}
@@ -0,0 +1,191 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+	pb "userservice/proto"
+	statpb "userservice/proto/statistic"
+	"userservice/src/database"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type fakeStore struct {
+	exports map[string]*database.AccountExport
+}
+
+func (s *fakeStore) ClaimAccountExports(now time.Time, lease time.Duration, limit int) ([]database.AccountExport, error) {
+	var due []database.AccountExport
+	for _, export := range s.exports {
+		if export.Status == database.ExportPending && !export.NextAttemptAt.After(now) {
+			due = append(due, *export)
+			export.NextAttemptAt = now.Add(lease)
+		}
+	}
+	return due, nil
+}
+
+func (s *fakeStore) SaveAccountExport(export *database.AccountExport) error {
+	saved := *export
+	s.exports[export.ID] = &saved
+	return nil
+}
+
+func (s *fakeStore) DeleteExpiredExports(now time.Time) error {
+	return nil
+}
+
+func (s *fakeStore) GetUserData(login string) (*database.UserData, error) {
+	return &database.UserData{Name: "Kek", Mail: "kek@mail.ru"}, nil
+}
+
+func (s *fakeStore) GetPrivacy(login string) (database.Privacy, error) {
+	return database.Privacy{Name: true}, nil
+}
+
+func (s *fakeStore) GetTwoFactor(login string) (database.TwoFactor, error) {
+	return database.TwoFactor{Secret: []byte("secret"), Enabled: true}, nil
+}
+
+func (s *fakeStore) GetAccessTokens(login string) ([]database.AccessToken, error) {
+	return []database.AccessToken{{ID: 1, Name: "ci"}}, nil
+}
+
+func (s *fakeStore) GetExternalIdentities(login string) ([]database.ExternalIdentity, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) GetViews(login string) ([]database.View, error) {
+	return nil, nil
+}
+
+type fakeTasks struct {
+	pb.TaskServiceClient
+}
+
+func (t *fakeTasks) GetTasks(ctx context.Context, req *pb.GetTasksRequest, opts ...grpc.CallOption) (*pb.GetTasksReponse, error) {
+	return &pb.GetTasksReponse{Tasks: []*pb.Task{{Id: 3}, {Id: 7}}}, nil
+}
+
+func (t *fakeTasks) GetTask(ctx context.Context, req *pb.GetTaskRequest, opts ...grpc.CallOption) (*pb.Task, error) {
+	return &pb.Task{
+		Id:        req.Id,
+		Author:    req.Author,
+		Title:     "Task",
+		Checklist: []*pb.ChecklistItem{{Id: 1, Text: "write"}},
+	}, nil
+}
+
+type fakeStats struct {
+	statpb.StatisticsServiceClient
+	failures int
+}
+
+func (s *fakeStats) GetUserActivity(ctx context.Context, req *statpb.GetUserActivityRequest, opts ...grpc.CallOption) (*statpb.GetUserActivityResponse, error) {
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("statistics service is down")
+	}
+	return &statpb.GetUserActivityResponse{
+		Likes:         []*statpb.Reaction{{TaskId: 9, Time: timestamppb.New(time.Unix(1, 0))}},
+		LikesReceived: map[uint32]int32{7: 2, 3: 1},
+		ViewsReceived: map[uint32]int32{7: 5},
+	}, nil
+}
+
+func readArchive(t *testing.T, archive []byte) map[string][]byte {
+	r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return files
+}
+
+func TestProcess(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := &fakeStore{exports: map[string]*database.AccountExport{
+		"e1": {ID: "e1", Login: "kek", Status: database.ExportPending, NextAttemptAt: now},
+	}}
+	w := NewWorker(store, &fakeTasks{}, &fakeStats{failures: 1})
+
+	if err := w.Process(now); err != nil {
+		t.Fatal(err)
+	}
+	export := store.exports["e1"]
+	if export.Status != database.ExportPending || export.Attempts != 1 || export.LastError == "" {
+		t.Fatalf("expected a failed attempt, got %#v", export)
+	}
+
+	now = now.Add(retryDelay)
+	if err := w.Process(now); err != nil {
+		t.Fatal(err)
+	}
+	export = store.exports["e1"]
+	if export.Status != database.ExportReady || export.ExpiresAt == nil || !export.ExpiresAt.Equal(now.Add(TTL)) {
+		t.Fatalf("expected a ready export, got %#v", export)
+	}
+
+	files := readArchive(t, export.Archive)
+	for _, name := range []string{"README.txt", "profile.json", "tasks.json", "statistics.json"} {
+		if len(files[name]) == 0 {
+			t.Errorf("expected %s in the archive", name)
+		}
+	}
+	if bytes.Contains(files["profile.json"], []byte("secret")) {
+		t.Error("expected no TOTP secret in the profile")
+	}
+
+	var tasks []struct {
+		ID        uint32 `json:"id"`
+		Checklist []any  `json:"checklist"`
+	}
+	if err := json.Unmarshal(files["tasks.json"], &tasks); err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[1].ID != 7 || len(tasks[1].Checklist) != 1 {
+		t.Errorf("unexpected tasks: %s", files["tasks.json"])
+	}
+
+	var stats Statistics
+	if err := json.Unmarshal(files["statistics.json"], &stats); err != nil {
+		t.Fatal(err)
+	}
+	target := []TaskCounts{{TaskID: 3, Likes: 1}, {TaskID: 7, Likes: 2, Views: 5}}
+	if len(stats.LikesGiven) != 1 || len(stats.Received) != 2 || stats.Received[0] != target[0] || stats.Received[1] != target[1] {
+		t.Errorf("unexpected statistics: %s", files["statistics.json"])
+	}
+}
+
+func TestProcessGivesUp(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := &fakeStore{exports: map[string]*database.AccountExport{
+		"e1": {ID: "e1", Login: "kek", Status: database.ExportPending, Attempts: maxAttempts - 1, NextAttemptAt: now},
+	}}
+	w := NewWorker(store, &fakeTasks{}, &fakeStats{failures: 1})
+
+	if err := w.Process(now); err != nil {
+		t.Fatal(err)
+	}
+	if export := store.exports["e1"]; export.Status != database.ExportFailed {
+		t.Errorf("expected a failed export, got %#v", export)
+	}
+}
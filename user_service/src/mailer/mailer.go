@@ -0,0 +1,71 @@
+package mailer
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// FromEnv picks the mailer by MAILER: "log" (default) prints messages,
+// "file" appends them to MAIL_FILE and "smtp" sends them through
+// MAIL_SMTP_ADDR. Local development needs no mail server this way.
+func FromEnv() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	switch kind := os.Getenv("MAILER"); kind {
+	case "", "log":
+		return NewWriterMailer(os.Stdout, from), nil
+	case "file":
+		path := os.Getenv("MAIL_FILE")
+		if path == "" {
+			return nil, fmt.Errorf("MAIL_FILE is required for the file mailer")
+		}
+		return NewFileMailer(path, from), nil
+	case "smtp":
+		addr := os.Getenv("MAIL_SMTP_ADDR")
+		if addr == "" {
+			return nil, fmt.Errorf("MAIL_SMTP_ADDR is required for the smtp mailer")
+		}
+		return NewSMTPMailer(addr, os.Getenv("MAIL_SMTP_USER"), os.Getenv("MAIL_SMTP_PASSWORD"), from), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", kind)
+	}
+}
+
+// format renders msg as an RFC 5322 message.
+func format(from string, msg Message, now time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// checkHeaders rejects line breaks that would inject headers.
+func checkHeaders(msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("line break in mail headers")
+	}
+	return nil
+}
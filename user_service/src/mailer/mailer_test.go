@@ -0,0 +1,80 @@
+package mailer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriterMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewWriterMailer(&buf, "no-reply@example.com")
+
+	err := m.Send(Message{To: "kek@mail.ru", Subject: "Подтверждение почты", Body: "line 1\nline 2"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"From: no-reply@example.com\r\n",
+		"To: kek@mail.ru\r\n",
+		"Subject: =?utf-8?q?",
+		"\r\n\r\nline 1\r\nline 2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in %q", want, out)
+		}
+	}
+}
+
+func TestHeaderInjection(t *testing.T) {
+	m := NewWriterMailer(&bytes.Buffer{}, "no-reply@example.com")
+	if err := m.Send(Message{To: "kek@mail.ru\r\nBcc: lol@mail.ru", Subject: "hi"}); err == nil {
+		t.Error("expected error")
+	}
+	if err := m.Send(Message{To: "kek@mail.ru", Subject: "hi\nBcc: lol@mail.ru"}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.txt")
+	m := NewFileMailer(path, "no-reply@example.com")
+
+	for _, to := range []string{"kek@mail.ru", "lol@mail.ru"} {
+		if err := m.Send(Message{To: to, Subject: "hi", Body: "body"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "To: kek@mail.ru") || !strings.Contains(string(data), "To: lol@mail.ru") {
+		t.Errorf("expected both messages, got %q", data)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("MAILER", "")
+	if m, err := FromEnv(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	} else if _, ok := m.(*WriterMailer); !ok {
+		t.Errorf("expected log mailer by default, got %T", m)
+	}
+
+	t.Setenv("MAILER", "smtp")
+	t.Setenv("MAIL_SMTP_ADDR", "")
+	if _, err := FromEnv(); err == nil {
+		t.Error("expected error without MAIL_SMTP_ADDR")
+	}
+
+	t.Setenv("MAILER", "pigeon")
+	if _, err := FromEnv(); err == nil {
+		t.Error("expected error for unknown mailer")
+	}
+}
@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"net"
+	"net/smtp"
+	"time"
+)
+
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer authenticates with PLAIN when user is set. net/smtp uses
+// STARTTLS when the server offers it and refuses PLAIN over plain text
+// except to localhost.
+func NewSMTPMailer(addr, user, password, from string) *SMTPMailer {
+	m := &SMTPMailer{addr: addr, from: from}
+	if user != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		m.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	if err := checkHeaders(msg); err != nil {
+		return err
+	}
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, format(m.from, msg, time.Now()))
+}
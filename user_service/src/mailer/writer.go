@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// WriterMailer writes messages to w instead of sending them.
+type WriterMailer struct {
+	from string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterMailer(w io.Writer, from string) *WriterMailer {
+	return &WriterMailer{w: w, from: from}
+}
+
+func (m *WriterMailer) Send(msg Message) error {
+	if err := checkHeaders(msg); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := fmt.Fprintf(m.w, "%s\r\n\r\n", format(m.from, msg, time.Now()))
+	return err
+}
+
+// FileMailer appends messages to a file, one after another.
+type FileMailer struct {
+	path string
+	from string
+
+	mu sync.Mutex
+}
+
+func NewFileMailer(path, from string) *FileMailer {
+	return &FileMailer{path: path, from: from}
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	if err := checkHeaders(msg); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(file, "%s\r\n\r\n", format(m.from, msg, time.Now())); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,106 @@
+// Package merge finishes task merges in statistics service. Tasks manager
+// merges the tasks, then the move of likes and views is recorded in user_db
+// and done by the Worker, which retries it with backoff until statistics
+// service accepts it, so statistics do not stay split when the service is
+// down at merge time. Both steps are safe to repeat.
+package merge
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	statpb "userservice/proto/statistic"
+	"userservice/src/database"
+	"userservice/src/deletion"
+)
+
+const (
+	// lease keeps a claimed move from other instances while it runs.
+	lease       = time.Minute
+	batchSize   = 10
+	callTimeout = 10 * time.Second
+)
+
+type Store interface {
+	AddStatsMove(move *database.StatsMove) error
+	ClaimStatsMoves(now time.Time, lease time.Duration, limit int) ([]database.StatsMove, error)
+	SaveStatsMove(move *database.StatsMove) error
+	DeleteStatsMove(id uint) error
+}
+
+type Worker struct {
+	store Store
+	stats statpb.StatisticsServiceClient
+}
+
+func NewWorker(store Store, stats statpb.StatisticsServiceClient) *Worker {
+	return &Worker{
+		store: store,
+		stats: stats,
+	}
+}
+
+// Start records a move of statistics from a merged duplicate and tries it
+// right away. An error means the move was not recorded; a failed attempt
+// is only logged and left to Run.
+func (w *Worker) Start(ctx context.Context, from, to uint32, now time.Time) error {
+	// Run skips the move while the first attempt may still be running.
+	move := &database.StatsMove{FromID: from, ToID: to, NextAttemptAt: now.Add(lease)}
+	if err := w.store.AddStatsMove(move); err != nil {
+		return err
+	}
+	w.try(ctx, move, now)
+	return nil
+}
+
+// Run retries due moves every interval until stop is closed.
+func (w *Worker) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := w.Process(now); err != nil {
+				slog.Error("Can not process statistics moves.", slog.String("error", err.Error()))
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Process tries every due move once.
+func (w *Worker) Process(now time.Time) error {
+	moves, err := w.store.ClaimStatsMoves(now, lease, batchSize)
+	if err != nil {
+		return err
+	}
+	for i := range moves {
+		ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+		w.try(ctx, &moves[i], now)
+		cancel()
+	}
+	return nil
+}
+
+// try moves the statistics and forgets the move, or schedules the next
+// attempt with the same backoff as account deletions.
+func (w *Worker) try(ctx context.Context, move *database.StatsMove, now time.Time) {
+	_, err := w.stats.MoveTaskStats(ctx, &statpb.MoveTaskStatsRequest{FromId: move.FromID, ToId: move.ToID})
+	if err == nil {
+		if err := w.store.DeleteStatsMove(move.ID); err != nil {
+			slog.Error("Can not delete statistics move.", slog.Uint64("move_id", uint64(move.ID)), slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	err = fmt.Errorf("can not move statistics of task %d to %d: %w", move.FromID, move.ToID, err)
+	move.Attempts++
+	slog.Error("Statistics move failed.", slog.Uint64("move_id", uint64(move.ID)), slog.Int("attempts", move.Attempts), slog.String("error", err.Error()))
+	move.LastError = err.Error()
+	move.NextAttemptAt = now.Add(deletion.Backoff(move.Attempts))
+	if err := w.store.SaveStatsMove(move); err != nil {
+		slog.Error("Can not save statistics move.", slog.Uint64("move_id", uint64(move.ID)), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,113 @@
+package merge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	statpb "userservice/proto/statistic"
+	"userservice/src/database"
+
+	"google.golang.org/grpc"
+)
+
+type fakeStore struct {
+	moves  map[uint]*database.StatsMove
+	nextID uint
+}
+
+func (s *fakeStore) AddStatsMove(move *database.StatsMove) error {
+	s.nextID++
+	move.ID = s.nextID
+	saved := *move
+	s.moves[move.ID] = &saved
+	return nil
+}
+
+func (s *fakeStore) ClaimStatsMoves(now time.Time, lease time.Duration, limit int) ([]database.StatsMove, error) {
+	var due []database.StatsMove
+	for _, move := range s.moves {
+		if !move.NextAttemptAt.After(now) {
+			due = append(due, *move)
+			move.NextAttemptAt = now.Add(lease)
+		}
+	}
+	return due, nil
+}
+
+func (s *fakeStore) SaveStatsMove(move *database.StatsMove) error {
+	saved := *move
+	s.moves[move.ID] = &saved
+	return nil
+}
+
+func (s *fakeStore) DeleteStatsMove(id uint) error {
+	delete(s.moves, id)
+	return nil
+}
+
+type fakeStats struct {
+	statpb.StatisticsServiceClient
+	failures int
+	moved    [][2]uint32
+}
+
+func (s *fakeStats) MoveTaskStats(ctx context.Context, req *statpb.MoveTaskStatsRequest, opts ...grpc.CallOption) (*statpb.MoveTaskStatsResponse, error) {
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("statistics service is down")
+	}
+	s.moved = append(s.moved, [2]uint32{req.FromId, req.ToId})
+	return &statpb.MoveTaskStatsResponse{}, nil
+}
+
+func TestStartMovesRightAway(t *testing.T) {
+	store := &fakeStore{moves: map[uint]*database.StatsMove{}}
+	stats := &fakeStats{}
+	w := NewWorker(store, stats)
+
+	if err := w.Start(context.Background(), 3, 7, time.Unix(1000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.moved) != 1 || stats.moved[0] != [2]uint32{3, 7} {
+		t.Errorf("expected the move done, got %v", stats.moved)
+	}
+	if len(store.moves) != 0 {
+		t.Errorf("expected the move forgotten, got %v", store.moves)
+	}
+}
+
+func TestRetryMove(t *testing.T) {
+	store := &fakeStore{moves: map[uint]*database.StatsMove{}}
+	stats := &fakeStats{failures: 2}
+	w := NewWorker(store, stats)
+	now := time.Unix(1000, 0)
+
+	if err := w.Start(context.Background(), 3, 7, now); err != nil {
+		t.Fatal(err)
+	}
+	move := store.moves[1]
+	if move == nil || move.Attempts != 1 || move.LastError == "" {
+		t.Fatalf("expected a failed attempt recorded, got %+v", move)
+	}
+	if !move.NextAttemptAt.Equal(now.Add(5 * time.Second)) {
+		t.Errorf("expected backoff, next attempt at %v", move.NextAttemptAt)
+	}
+
+	// Not due yet.
+	w.Process(now.Add(time.Second))
+	if store.moves[1].Attempts != 1 {
+		t.Fatal("expected no attempt before the backoff")
+	}
+
+	now = now.Add(5 * time.Second)
+	w.Process(now)
+	if move := store.moves[1]; move == nil || move.Attempts != 2 || !move.NextAttemptAt.Equal(now.Add(10*time.Second)) {
+		t.Fatalf("expected a second failed attempt, got %+v", move)
+	}
+
+	w.Process(now.Add(10 * time.Second))
+	if len(store.moves) != 0 || len(stats.moved) != 1 {
+		t.Errorf("expected the move done, got %v and %v", store.moves, stats.moved)
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Timeouts limit how long a request may wait for other services. Routes
+// are chi patterns like "/users/{login}"; zero means no deadline.
+type Timeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+func (t Timeouts) For(route string) time.Duration {
+	if timeout, ok := t.Routes[route]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
+// TimeoutsFromEnv reads REQUEST_TIMEOUT, the deadline of every request
+// (10s by default), and REQUEST_TIMEOUTS, a comma separated list of
+// "route=duration" overrides, e.g. "/top-users=3s,/account/export=30s".
+func TimeoutsFromEnv() (Timeouts, error) {
+	timeouts := Timeouts{Default: defaultTimeout, Routes: map[string]time.Duration{}}
+	if raw := os.Getenv("REQUEST_TIMEOUT"); raw != "" {
+		timeout, err := parseTimeout(raw)
+		if err != nil {
+			return Timeouts{}, fmt.Errorf("bad REQUEST_TIMEOUT: %w", err)
+		}
+		timeouts.Default = timeout
+	}
+	for _, entry := range strings.Split(os.Getenv("REQUEST_TIMEOUTS"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		sep := strings.LastIndex(entry, "=")
+		if sep <= 0 {
+			return Timeouts{}, fmt.Errorf("bad REQUEST_TIMEOUTS entry %q, expected route=duration", entry)
+		}
+		timeout, err := parseTimeout(entry[sep+1:])
+		if err != nil {
+			return Timeouts{}, fmt.Errorf("bad REQUEST_TIMEOUTS entry %q: %w", entry, err)
+		}
+		timeouts.Routes[strings.TrimSpace(entry[:sep])] = timeout
+	}
+	return timeouts, nil
+}
+
+func parseTimeout(raw string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, err
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("negative timeout %v", timeout)
+	}
+	return timeout, nil
+}
+
+// Deadline sets the deadline of the route on the request context. gRPC
+// passes it on to the services, so their queries stop with the request.
+// Middleware runs before routing, so the route is looked up in routes.
+func Deadline(routes chi.Routes, timeouts Timeouts) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := timeouts.Default
+			if rctx := chi.NewRouteContext(); routes.Match(rctx, r.Method, r.URL.Path) {
+				timeout = timeouts.For(rctx.RoutePattern())
+			}
+			if timeout > 0 {
+				ctx, cancel := context.WithTimeout(r.Context(), timeout)
+				defer cancel()
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestTimeoutsFromEnv(t *testing.T) {
+	t.Setenv("REQUEST_TIMEOUT", "")
+	t.Setenv("REQUEST_TIMEOUTS", "")
+	timeouts, err := TimeoutsFromEnv()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if timeouts.Default != defaultTimeout || len(timeouts.Routes) != 0 {
+		t.Errorf("expected defaults, got %+v", timeouts)
+	}
+
+	t.Setenv("REQUEST_TIMEOUT", "5s")
+	t.Setenv("REQUEST_TIMEOUTS", "/top-users=3s, /users/{login}=0")
+	timeouts, err = TimeoutsFromEnv()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if timeouts.Default != 5*time.Second || timeouts.For("/top-users") != 3*time.Second ||
+		timeouts.For("/users/{login}") != 0 || timeouts.For("/tasks") != 5*time.Second {
+		t.Errorf("unexpected timeouts %+v", timeouts)
+	}
+
+	for _, bad := range []string{"/tasks", "/tasks=soon", "/tasks=-1s", "=1s"} {
+		t.Setenv("REQUEST_TIMEOUTS", bad)
+		if _, err := TimeoutsFromEnv(); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	mux := chi.NewRouter()
+	mux.Use(Deadline(mux, Timeouts{
+		Default: time.Minute,
+		Routes:  map[string]time.Duration{"/users/{login}": time.Second, "/jwks": 0},
+	}))
+	var left time.Duration
+	var hasDeadline bool
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var deadline time.Time
+		deadline, hasDeadline = r.Context().Deadline()
+		left = time.Until(deadline)
+	}
+	mux.Get("/users/{login}", handler)
+	mux.Get("/tasks", handler)
+	mux.Get("/jwks", handler)
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/kek", nil))
+	if !hasDeadline || left > time.Second {
+		t.Errorf("expected the route deadline, got %v %v", hasDeadline, left)
+	}
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/tasks", nil))
+	if !hasDeadline || left <= time.Second || left > time.Minute {
+		t.Errorf("expected the default deadline, got %v %v", hasDeadline, left)
+	}
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/jwks", nil))
+	if hasDeadline {
+		t.Errorf("expected no deadline, got %v", left)
+	}
+}
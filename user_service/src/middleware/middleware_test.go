@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func newRouter(logs *bytes.Buffer) *chi.Mux {
+	logger := slog.New(slog.NewJSONHandler(logs, nil))
+	mux := chi.NewRouter()
+	mux.Use(RequestID, AccessLog(logger), Recover(logger))
+	mux.Get("/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		SetUser(r.Context(), "kek")
+		w.Header().Set("X-Seen-ID", GetRequestID(r.Context()))
+		w.WriteHeader(http.StatusTeapot)
+	})
+	mux.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	return mux
+}
+
+func TestRequestID(t *testing.T) {
+	mux := newRouter(&bytes.Buffer{})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tasks/1", nil))
+	id := w.Header().Get(RequestIDHeader)
+	if len(id) != 32 || w.Header().Get("X-Seen-ID") != id {
+		t.Errorf("expected a new ID passed to the handler, got %q and %q", id, w.Header().Get("X-Seen-ID"))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/tasks/1", nil)
+	r.Header.Set(RequestIDHeader, "client-id")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if id := w.Header().Get(RequestIDHeader); id != "client-id" {
+		t.Errorf("expected the client ID kept, got %q", id)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/tasks/1", nil)
+	r.Header.Set(RequestIDHeader, "bad\tid")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if id := w.Header().Get(RequestIDHeader); id == "bad\tid" || id == "" {
+		t.Errorf("expected a bad ID replaced, got %q", id)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+	mux := newRouter(&logs)
+
+	r := httptest.NewRequest(http.MethodGet, "/tasks/1", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+
+	var line struct {
+		RequestID string `json:"request_id"`
+		Method    string `json:"method"`
+		Route     string `json:"route"`
+		Status    int    `json:"status"`
+		User      string `json:"user"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", logs.String(), err)
+	}
+	if line.RequestID != "req-1" || line.Method != http.MethodGet || line.Route != "/tasks/{id}" ||
+		line.Status != http.StatusTeapot || line.User != "kek" {
+		t.Errorf("unexpected access log %+v", line)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var logs bytes.Buffer
+	mux := newRouter(&logs)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+	if !bytes.Contains(logs.Bytes(), []byte(`"error":"boom"`)) || !bytes.Contains(logs.Bytes(), []byte(`"status":500`)) {
+		t.Errorf("expected the panic and the 500 logged, got %s", logs.String())
+	}
+}
+
+func TestForwardRequestID(t *testing.T) {
+	ctx := context.WithValue(context.Background(), requestIDKey, "req-1")
+	var got []string
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		got = md.Get(RequestIDMetadata)
+		return nil
+	}
+	if err := ForwardRequestID(ctx, "/Service/Method", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "req-1" {
+		t.Errorf("expected req-1 in metadata, got %v", got)
+	}
+}
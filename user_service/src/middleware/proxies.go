@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// Proxies are the load balancers and reverse proxies in front of the
+// service. Only they are believed about the client address.
+type Proxies []netip.Prefix
+
+// ProxiesFromEnv reads TRUSTED_PROXIES, a comma separated list of
+// addresses and networks, e.g. "10.0.0.0/8,192.168.1.5". Empty means the
+// service is reached directly.
+func ProxiesFromEnv() (Proxies, error) {
+	var proxies Proxies
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("bad TRUSTED_PROXIES entry %q: %w", entry, err)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("bad TRUSTED_PROXIES entry %q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
+func (p Proxies) trusted(addr netip.Addr) bool {
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// RealIP replaces RemoteAddr of a request that came through a trusted
+// proxy with the client address from X-Forwarded-For. The header is read
+// from the right, as every proxy appends its peer: the first address that
+// is not a trusted proxy is the client, and anything left of it may be
+// forged. Requests from other peers keep their address.
+func (p Proxies) RealIP(next http.Handler) http.Handler {
+	if len(p) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if client, ok := p.client(r); ok {
+			r.RemoteAddr = client.String()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (p Proxies) client(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !p.trusted(peer.Unmap()) {
+		return netip.Addr{}, false
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	client, found := netip.Addr{}, false
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client, found = addr.Unmap(), true
+		if !p.trusted(client) {
+			break
+		}
+	}
+	return client, found
+}
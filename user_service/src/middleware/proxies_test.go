@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxiesFromEnv(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+	proxies, err := ProxiesFromEnv()
+	if err != nil || len(proxies) != 0 {
+		t.Fatalf("expected no proxies, got %v, %v", proxies, err)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.5,::1")
+	proxies, err = ProxiesFromEnv()
+	if err != nil || len(proxies) != 3 {
+		t.Fatalf("expected 3 proxies, got %v, %v", proxies, err)
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "proxy.local"} {
+		t.Setenv("TRUSTED_PROXIES", bad)
+		if _, err := ProxiesFromEnv(); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+	proxies, err := ProxiesFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		peer      string
+		forwarded []string
+		target    string
+	}{
+		{"direct", "203.0.113.7:5000", nil, "203.0.113.7:5000"},
+		{"untrusted peer", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7:5000"},
+		{"through proxy", "10.0.0.2:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"forged prefix", "10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"two proxies", "10.0.0.2:5000", []string{"198.51.100.1", "10.0.0.3"}, "198.51.100.1"},
+		{"garbage", "10.0.0.2:5000", []string{"unknown"}, "10.0.0.2:5000"},
+		{"no header", "10.0.0.2:5000", nil, "10.0.0.2:5000"},
+	}
+	for _, tt := range tests {
+		var got string
+		handler := proxies.RealIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.RemoteAddr
+		}))
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.RemoteAddr = tt.peer
+		for _, value := range tt.forwarded {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if got != tt.target {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.target, got)
+		}
+	}
+}
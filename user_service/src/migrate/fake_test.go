@@ -0,0 +1,156 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeDB is a database/sql driver that understands just the statements of
+// the migrator. It keeps schema_migrations in memory, runs scripts by
+// recording them and fails scripts that contain FAIL. Changes of a
+// transaction are kept only if it commits.
+type fakeDB struct {
+	mu      sync.Mutex
+	applied map[int64]time.Time
+	scripts []string
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{applied: make(map[int64]time.Time)}
+}
+
+var (
+	fakeDBsMu sync.Mutex
+	fakeDBs   = map[string]*fakeDB{}
+)
+
+func init() {
+	sql.Register("migratefake", fakeDriver{})
+}
+
+// open returns a *sql.DB backed by db. name has to be unique per test.
+func (db *fakeDB) open(name string) *sql.DB {
+	fakeDBsMu.Lock()
+	fakeDBs[name] = db
+	fakeDBsMu.Unlock()
+	sqlDB, _ := sql.Open("migratefake", name)
+	return sqlDB
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	db, ok := fakeDBs[name]
+	if !ok {
+		return nil, errors.New("unknown fake database " + name)
+	}
+	return &fakeConn{db: db}, nil
+}
+
+// change is a pending change of schema_migrations: an insert or, with
+// remove set, a delete.
+type change struct {
+	version int64
+	remove  bool
+}
+
+type fakeConn struct {
+	db      *fakeDB
+	tx      bool
+	scripts []string
+	changes []change
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.tx = true
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.scripts = append(c.db.scripts, c.scripts...)
+	for _, ch := range c.changes {
+		if ch.remove {
+			delete(c.db.applied, ch.version)
+		} else {
+			c.db.applied[ch.version] = time.Unix(ch.version, 0)
+		}
+	}
+	c.end()
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.end()
+	return nil
+}
+
+func (c *fakeConn) end() {
+	c.tx, c.scripts, c.changes = false, nil, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	switch {
+	case strings.Contains(query, "pg_advisory"), strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+		c.changes = append(c.changes, change{version: args[0].Value.(int64)})
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		c.changes = append(c.changes, change{version: args[0].Value.(int64), remove: true})
+	default:
+		// A failing script still leaves its effects in the transaction.
+		c.scripts = append(c.scripts, query)
+		if strings.Contains(query, "FAIL") {
+			return nil, errors.New("syntax error")
+		}
+	}
+	if !c.tx {
+		return nil, errors.New("expected statements in a transaction")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if query != "SELECT version, applied_at FROM schema_migrations" {
+		return nil, errors.New("unexpected query " + query)
+	}
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	rows := &fakeRows{}
+	for version, appliedAt := range c.db.applied {
+		rows.values = append(rows.values, []driver.Value{version, appliedAt})
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	values [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return []string{"version", "applied_at"} }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,137 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"userservice/src/oidc/oidctest"
+)
+
+const redirectURI = "http://localhost:8080/oidc/test/callback"
+
+// login runs the browser part of the flow against the stand-in provider
+// and returns the code and state it redirects back with.
+func login(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected redirect, got %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestLogin(t *testing.T) {
+	stand, err := oidctest.New("task-tracker", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stand.Close()
+	stand.SetUser(oidctest.User{Subject: "42", Email: "kek@corp.example", EmailVerified: true, Username: "kek"})
+
+	p := NewProvider(Config{Name: "test", Issuer: stand.Issuer(), ClientID: "task-tracker", ClientSecret: "secret"}, nil)
+	ctx := context.Background()
+
+	verifier, _ := RandomString()
+	authURL, err := p.AuthCodeURL(ctx, redirectURI, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	code, state := login(t, authURL)
+	if state != "state-1" {
+		t.Errorf("expected state-1, got %q", state)
+	}
+
+	identity, err := p.Exchange(ctx, redirectURI, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	target := Identity{Provider: "test", Subject: "42", Email: "kek@corp.example", EmailVerified: true, Username: "kek"}
+	if *identity != target {
+		t.Errorf("expected: %#v; got: %#v", target, *identity)
+	}
+
+	t.Run("Code reuse", func(t *testing.T) {
+		if _, err := p.Exchange(ctx, redirectURI, code, verifier, "nonce-1"); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("Wrong verifier", func(t *testing.T) {
+		authURL, _ := p.AuthCodeURL(ctx, redirectURI, "state-2", "nonce-2", verifier)
+		code, _ := login(t, authURL)
+		other, _ := RandomString()
+		if _, err := p.Exchange(ctx, redirectURI, code, other, "nonce-2"); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("Wrong nonce", func(t *testing.T) {
+		authURL, _ := p.AuthCodeURL(ctx, redirectURI, "state-3", "nonce-3", verifier)
+		code, _ := login(t, authURL)
+		if _, err := p.Exchange(ctx, redirectURI, code, verifier, "nonce-other"); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("Wrong audience", func(t *testing.T) {
+		other := NewProvider(Config{Name: "test", Issuer: stand.Issuer(), ClientID: "other", ClientSecret: "secret"}, nil)
+		authURL, _ := p.AuthCodeURL(ctx, redirectURI, "state-4", "nonce-4", verifier)
+		code, _ := login(t, authURL)
+		if _, err := other.Exchange(ctx, redirectURI, code, verifier, "nonce-4"); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	stand, err := oidctest.New("task-tracker", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stand.Close()
+
+	p := NewProvider(Config{Name: "test", Issuer: stand.Issuer() + "/", ClientID: "task-tracker"}, nil)
+	if _, err := p.AuthCodeURL(context.Background(), redirectURI, "s", "n", "v"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	// RFC 7636 appendix B.
+	if challenge := Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); challenge != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("unexpected challenge %s", challenge)
+	}
+}
+
+func TestConfigsFromEnv(t *testing.T) {
+	t.Setenv("OIDC_PROVIDERS", "corp, google")
+	t.Setenv("OIDC_CORP_ISSUER", "https://sso.corp.example")
+	t.Setenv("OIDC_CORP_CLIENT_ID", "task-tracker")
+	t.Setenv("OIDC_CORP_SCOPES", "openid,email")
+	t.Setenv("OIDC_GOOGLE_ISSUER", "https://accounts.google.com")
+	t.Setenv("OIDC_GOOGLE_CLIENT_ID", "")
+	if _, err := ConfigsFromEnv(); err == nil {
+		t.Error("expected error without client id")
+	}
+
+	t.Setenv("OIDC_GOOGLE_CLIENT_ID", "id")
+	configs, err := ConfigsFromEnv()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(configs) != 2 || configs[0].Name != "corp" || len(configs[0].Scopes) != 2 || configs[1].ClientID != "id" {
+		t.Errorf("unexpected configs %#v", configs)
+	}
+}
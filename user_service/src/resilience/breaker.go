@@ -0,0 +1,108 @@
+package resilience
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BreakerConfig says when a breaker opens: after Failures calls in a row
+// fail, calls are refused for Cooldown, then a single probe is let through.
+type BreakerConfig struct {
+	Failures int
+	Cooldown time.Duration
+}
+
+var DefaultBreakerConfig = BreakerConfig{
+	Failures: 5,
+	Cooldown: 10 * time.Second,
+}
+
+// Breaker guards one service. While it is open calls fail at once with
+// Unavailable instead of waiting for their deadlines, which spares both
+// the callers and the struggling service.
+type Breaker struct {
+	name   string
+	config BreakerConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time // zero while closed
+	probing  bool
+}
+
+func NewBreaker(name string, config BreakerConfig) *Breaker {
+	return &Breaker{name: name, config: config, now: time.Now}
+}
+
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.openedAt.IsZero()
+}
+
+// allow reports whether a call may go out and whether it is the probe of
+// a half-open breaker.
+func (b *Breaker) allow() (ok, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return true, false
+	}
+	if b.probing || b.now().Sub(b.openedAt) < b.config.Cooldown {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// record counts the result of a call. Only errors that show the service
+// is down or too slow count as failures. A call the caller gave up on says
+// nothing about the service: it may have been cancelled, or its deadline,
+// like a short one of a route, may have passed while the service was still
+// healthy. callerDone tells those apart from deadlines the service reports.
+func (b *Breaker) record(err error, probe, callerDone bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	if callerDone {
+		return
+	}
+
+	switch status.Code(err) {
+	case codes.Canceled:
+		return
+	case codes.Unavailable, codes.DeadlineExceeded:
+		b.failures++
+		if probe || (b.openedAt.IsZero() && b.failures >= b.config.Failures) {
+			if b.openedAt.IsZero() {
+				slog.Warn("Circuit opened.", slog.String("service", b.name), slog.String("error", err.Error()))
+			}
+			b.openedAt = b.now()
+		}
+	default:
+		if !b.openedAt.IsZero() {
+			slog.Info("Circuit closed.", slog.String("service", b.name))
+		}
+		b.failures = 0
+		b.openedAt = time.Time{}
+	}
+}
+
+func (b *Breaker) Interceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ok, probe := b.allow()
+	if !ok {
+		return status.Errorf(codes.Unavailable, "%s is unavailable, calls are paused", b.name)
+	}
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	b.record(err, probe, ctx.Err() != nil)
+	return err
+}
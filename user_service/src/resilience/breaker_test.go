@@ -0,0 +1,78 @@
+package resilience
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker("stats", BreakerConfig{Failures: 2, Cooldown: time.Minute})
+	b.now = func() time.Time { return now }
+
+	var code codes.Code
+	var calls int
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		return status.Error(code, "result")
+	}
+	call := func() codes.Code {
+		return status.Code(b.Interceptor(context.Background(), "/Service/Get", nil, nil, nil, invoker))
+	}
+
+	// Answers, even errors, and cancelled calls do not open the breaker.
+	code = codes.NotFound
+	call()
+	code = codes.Canceled
+	call()
+	code = codes.Unavailable
+	call()
+	if b.Open() {
+		t.Fatal("expected the breaker closed after one failure")
+	}
+
+	// Nor does a deadline of the caller's own.
+	expired, cancel := context.WithDeadline(context.Background(), now)
+	defer cancel()
+	code = codes.DeadlineExceeded
+	for i := 0; i < 3; i++ {
+		b.Interceptor(expired, "/Service/Get", nil, nil, nil, invoker)
+	}
+	if b.Open() {
+		t.Fatal("expected the breaker closed after deadlines of the caller")
+	}
+
+	// A deadline the service reports does.
+	call()
+	if !b.Open() {
+		t.Fatal("expected the breaker open after two failures in a row")
+	}
+
+	calls = 0
+	if got := call(); got != codes.Unavailable || calls != 0 {
+		t.Errorf("expected the call refused, got %v after %d calls", got, calls)
+	}
+
+	// After the cooldown a failed probe opens the breaker again.
+	now = now.Add(time.Minute)
+	code = codes.Unavailable
+	call()
+	if !b.Open() || calls != 1 {
+		t.Fatalf("expected a failed probe, got %d calls", calls)
+	}
+	if call(); calls != 1 {
+		t.Error("expected calls refused after the failed probe")
+	}
+
+	// A successful probe closes it.
+	now = now.Add(time.Minute)
+	code = codes.OK
+	if got := call(); got != codes.OK || b.Open() {
+		t.Errorf("expected the breaker closed, got %v", got)
+	}
+}
@@ -0,0 +1,138 @@
+// Package resilience keeps user service responsive when the services it
+// calls misbehave: it retries and hedges calls that are safe to repeat and
+// stops calling a service that keeps failing.
+package resilience
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// RetryPolicy says how often a failed call is repeated. Attempts counts
+// the first call too; the pause before a retry starts at Backoff and
+// doubles up to MaxBackoff.
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:   3,
+	Backoff:    50 * time.Millisecond,
+	MaxBackoff: 500 * time.Millisecond,
+}
+
+// retryable reports whether the call failed before the service could act
+// on it, like on a dropped connection or a restarting service.
+func retryable(err error) bool {
+	return status.Code(err) == codes.Unavailable
+}
+
+// Retry repeats calls of the listed methods while they fail with
+// Unavailable. A lost reply may hide a call that did succeed, so only
+// methods that are safe to repeat may be listed.
+func Retry(policy RetryPolicy, methods ...string) grpc.UnaryClientInterceptor {
+	idempotent := methodSet(methods)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if !idempotent[method] {
+			return err
+		}
+		backoff := policy.Backoff
+		for attempt := 1; attempt < policy.Attempts && retryable(err); attempt++ {
+			if !sleep(ctx, jitter(backoff)) {
+				return err
+			}
+			backoff = min(2*backoff, policy.MaxBackoff)
+			err = invoker(ctx, method, req, reply, cc, opts...)
+		}
+		return err
+	}
+}
+
+// jitter spreads retries of many clients over [d/2, d], so a service that
+// comes back is not hit by all of them at once.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// sleep waits for d unless ctx ends earlier, and reports whether the call
+// can still be retried.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Hedge sends a second copy of a call of the listed methods if the first
+// one has not answered in delay, and returns whichever succeeds first. It
+// trims the latency tail of cheap reads at the cost of some extra load.
+func Hedge(delay time.Duration, methods ...string) grpc.UnaryClientInterceptor {
+	hedged := methodSet(methods)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		message, ok := reply.(proto.Message)
+		if !hedged[method] || !ok || delay <= 0 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		// The loser is cancelled once a result is taken.
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type result struct {
+			reply proto.Message
+			err   error
+		}
+		results := make(chan result, 2)
+		call := func() {
+			reply := message.ProtoReflect().New().Interface()
+			results <- result{reply, invoker(ctx, method, req, reply, cc, opts...)}
+		}
+
+		go call()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		var res result
+		select {
+		case res = <-results:
+		case <-timer.C:
+			go call()
+			if res = <-results; res.err != nil {
+				res = <-results
+			}
+		}
+		if res.err != nil {
+			return res.err
+		}
+		proto.Reset(message)
+		proto.Merge(message, res.reply)
+		return nil
+	}
+}
+
+func methodSet(methods []string) map[string]bool {
+	set := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		set[method] = true
+	}
+	return set
+}
@@ -0,0 +1,91 @@
+package resilience
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var testPolicy = RetryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+// failing fails the first n calls with code and succeeds after that.
+func failing(n int32, code codes.Code, calls *atomic.Int32) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if calls.Add(1) <= n {
+			return status.Error(code, "down")
+		}
+		return nil
+	}
+}
+
+func TestRetry(t *testing.T) {
+	retry := Retry(testPolicy, "/Service/Get")
+	tests := []struct {
+		name     string
+		method   string
+		failures int32
+		code     codes.Code
+		calls    int32
+		result   codes.Code
+	}{
+		{"recovers", "/Service/Get", 2, codes.Unavailable, 3, codes.OK},
+		{"gives up", "/Service/Get", 5, codes.Unavailable, 3, codes.Unavailable},
+		{"not retryable", "/Service/Get", 1, codes.NotFound, 1, codes.NotFound},
+		{"not idempotent", "/Service/Create", 1, codes.Unavailable, 1, codes.Unavailable},
+	}
+
+	for _, test := range tests {
+		var calls atomic.Int32
+		err := retry(context.Background(), test.method, nil, nil, nil, failing(test.failures, test.code, &calls))
+		if status.Code(err) != test.result || calls.Load() != test.calls {
+			t.Errorf("%s: expected %v after %d calls, got %v after %d", test.name, test.result, test.calls, err, calls.Load())
+		}
+	}
+}
+
+func TestRetryStopsAtDeadline(t *testing.T) {
+	retry := Retry(RetryPolicy{Attempts: 3, Backoff: time.Hour, MaxBackoff: time.Hour}, "/Service/Get")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var calls atomic.Int32
+	err := retry(ctx, "/Service/Get", nil, nil, nil, failing(5, codes.Unavailable, &calls))
+	if status.Code(err) != codes.Unavailable || calls.Load() != 1 {
+		t.Errorf("expected no retry past the deadline, got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestHedge(t *testing.T) {
+	hedge := Hedge(10*time.Millisecond, "/Service/Get")
+
+	// The first copy hangs until cancelled, the second answers at once.
+	var calls atomic.Int32
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if calls.Add(1) == 1 {
+			<-ctx.Done()
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		proto.Merge(reply.(proto.Message), wrapperspb.String("second"))
+		return nil
+	}
+
+	reply := &wrapperspb.StringValue{}
+	if err := hedge(context.Background(), "/Service/Get", nil, reply, nil, invoker); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if reply.Value != "second" || calls.Load() != 2 {
+		t.Errorf("expected the hedged reply, got %q after %d calls", reply.Value, calls.Load())
+	}
+
+	calls.Store(0)
+	if err := hedge(context.Background(), "/Service/Create", nil, reply, nil, failing(0, codes.OK, &calls)); err != nil || calls.Load() != 1 {
+		t.Errorf("expected a single call of an unlisted method, got %v after %d calls", err, calls.Load())
+	}
+}
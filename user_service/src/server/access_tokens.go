@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+	"userservice/src/database"
+)
+
+const accessTokenNameMaxLen = 50
+
+type AccessTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// AccessTokenResponse carries the token itself, which is shown only once.
+type AccessTokenResponse struct {
+	database.AccessToken
+	Token string `json:"token"`
+}
+
+func (s *Server) createAccessToken(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	var req AccessTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Name == "" || len(req.Name) > accessTokenNameMaxLen {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Name must be 1 to %d characters long", accessTokenNameMaxLen)
+		return
+	}
+	if err := auth.ValidateScopes(req.Scopes); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "%v", err)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "expires_at must be in the future")
+		return
+	}
+
+	token, info, err := s.auth.CreateAccessToken(login, req.Name, req.Scopes, req.ExpiresAt)
+	if errors.Is(err, database.ErrAccessTokenExists) {
+		apierror.Write(w, http.StatusConflict, apierror.AlreadyExists, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not create access token: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(AccessTokenResponse{AccessToken: info, Token: token})
+}
+
+func (s *Server) getAccessTokens(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := s.auth.GetAccessTokens(login)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not get access tokens: %v", err)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(tokens)
+}
+
+func (s *Server) deleteAccessToken(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
+		return
+	}
+
+	err = s.auth.DeleteAccessToken(login, uint(id))
+	if errors.Is(err, database.ErrAccessTokenNotFound) {
+		apierror.Write(w, http.StatusNotFound, apierror.NotFound, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not delete access token: %v", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+	"userservice/src/broker"
+	"userservice/src/database"
+	"userservice/src/deletion"
+	"userservice/src/export"
+)
+
+// DeletionStatus reports the progress of an account deletion. Status is
+// "pending" until every service forgot the user, then "done".
+type DeletionStatus struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Step        string     `json:"step"`
+	Attempts    int        `json:"attempts,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+func deletionStatus(d *database.AccountDeletion) DeletionStatus {
+	status := "pending"
+	if d.Step == deletion.StepDone {
+		status = "done"
+	}
+	return DeletionStatus{
+		ID:          d.ID,
+		Status:      status,
+		Step:        d.Step,
+		Attempts:    d.Attempts,
+		LastError:   d.LastError,
+		CreatedAt:   d.CreatedAt,
+		CompletedAt: d.CompletedAt,
+	}
+}
+
+// deleteAccount locks the user out at once and leaves the rest to the
+// deletion worker. The body may be empty for users without a password.
+func (s *Server) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	var req Password
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	// Nothing would delete the statistics, so the deletion would never end.
+	if s.broker.Drops() {
+		apierror.Write(w, http.StatusServiceUnavailable, apierror.Unavailable, "Account deletion is unavailable: %v", broker.ErrNoConsumer)
+		return
+	}
+
+	now := time.Now()
+	if throttled(w, s.loginLimiter, loginKey(login), now) {
+		return
+	}
+
+	started, err := s.auth.DeleteAccount(login, req.Password)
+	if errors.Is(err, auth.ErrWrongPassword) {
+		s.failLogin(r, login, now)
+		apierror.Write(w, http.StatusForbidden, apierror.PermissionDenied, "Wrong password")
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not delete account: %v", err)
+		return
+	}
+	if err := s.loginLimiter.Reset(loginKey(login)); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not reset attempts: %v", err)
+		return
+	}
+	if s.deletions != nil {
+		s.deletions.Kick()
+	}
+
+	clearTokens(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(deletionStatus(started))
+}
+
+// getAccountDeletion needs no session: the user has none after deleting
+// the account. The random ID is enough.
+func (s *Server) getAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "id is required")
+		return
+	}
+
+	d, err := s.db.GetAccountDeletion(id)
+	if errors.Is(err, database.ErrDeletionNotFound) {
+		apierror.Write(w, http.StatusNotFound, apierror.NotFound, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not get account deletion: %v", err)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(deletionStatus(d))
+}
+
+// ExportStatus reports a personal data export. DownloadURL is set once the
+// archive is ready.
+type ExportStatus struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+func (s *Server) exportStatus(e *database.AccountExport) ExportStatus {
+	status := ExportStatus{
+		ID:        e.ID,
+		Status:    e.Status,
+		Attempts:  e.Attempts,
+		LastError: e.LastError,
+		CreatedAt: e.CreatedAt,
+		ExpiresAt: e.ExpiresAt,
+	}
+	if e.Status == database.ExportReady {
+		status.DownloadURL = s.publicURL + "/account/export/download?id=" + url.QueryEscape(e.ID)
+	}
+	return status
+}
+
+// startExport starts building an archive of the user's data in the
+// background. While one is pending, it is returned instead of a new one.
+func (s *Server) startExport(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := export.NewJob(login, time.Now())
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not start export: %v", err)
+		return
+	}
+	job, err = s.db.StartAccountExport(job)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not start export: %v", err)
+		return
+	}
+	if s.exports != nil {
+		s.exports.Kick()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(s.exportStatus(job))
+}
+
+// getExport returns the export with the id or the latest one.
+func (s *Server) getExport(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := s.db.GetAccountExport(login, r.URL.Query().Get("id"))
+	if errors.Is(err, database.ErrExportNotFound) {
+		apierror.Write(w, http.StatusNotFound, apierror.NotFound, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not get export: %v", err)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(s.exportStatus(job))
+}
+
+func (s *Server) downloadExport(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "id is required")
+		return
+	}
+	archive, err := s.db.GetExportArchive(login, id, time.Now())
+	if errors.Is(err, database.ErrExportNotFound) {
+		apierror.Write(w, http.StatusNotFound, apierror.NotFound, "Export is not ready, expired or does not exist")
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not get export: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", login+"-export.zip"))
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+	w.Write(archive)
+}
@@ -0,0 +1,394 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	pb "userservice/proto"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+	"userservice/src/database"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+type AdminUser struct {
+	Login string `json:"login"`
+}
+
+type AdminRole struct {
+	Login string `json:"login"`
+	Role  string `json:"role"`
+}
+
+type AdminTask struct {
+	ID uint `json:"id"`
+}
+
+type AdminUsers struct {
+	Users  []database.UserSummary `json:"users"`
+	Offset int                    `json:"offset"`
+}
+
+type LoginHistory struct {
+	Events []database.LoginEvent `json:"events"`
+	Offset int                   `json:"offset"`
+}
+
+type AuditLog struct {
+	Entries []database.AuditEntry `json:"entries"`
+	Offset  int                   `json:"offset"`
+}
+
+// checkAdmin is CheckAuth for the /admin API. Only sessions are accepted,
+// personal access tokens have no admin scope.
+func (s *Server) checkAdmin(w http.ResponseWriter, r *http.Request) (string, bool) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return "", false
+	}
+	role, err := s.db.GetRole(login)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not get role: %v", err)
+		return "", false
+	}
+	if role != database.RoleAdmin {
+		apierror.Write(w, http.StatusForbidden, apierror.PermissionDenied, "Administrator role required")
+		return "", false
+	}
+	return login, true
+}
+
+func auditEntry(r *http.Request, actor, action, target, details string) *database.AuditEntry {
+	return &database.AuditEntry{
+		Actor:   actor,
+		Action:  action,
+		Target:  target,
+		Details: details,
+		IP:      clientIP(r),
+	}
+}
+
+// audit records an action that is not stored in user_db together with its
+// entry. It answers 500 if the entry can not be saved.
+func (s *Server) audit(w http.ResponseWriter, r *http.Request, actor, action, target, details string) bool {
+	if err := s.db.AddAuditEntry(auditEntry(r, actor, action, target, details)); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not write audit log: %v", err)
+		return false
+	}
+	return true
+}
+
+// auditCall records an action done by another service: call runs only once
+// a pending entry is saved, and the outcome is recorded after it, so the
+// log does not miss an action even if user service stops in between. It
+// answers 500 if the entry can not be saved and returns the error of call.
+func (s *Server) auditCall(w http.ResponseWriter, r *http.Request, actor, action, target string, call func() error) (ok bool, err error) {
+	entry := auditEntry(r, actor, action, target, "")
+	entry.Outcome = database.AuditPending
+	if err := s.db.AddAuditEntry(entry); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not write audit log: %v", err)
+		return false, nil
+	}
+
+	err = call()
+	outcome, details := database.AuditDone, ""
+	if err != nil {
+		outcome, details = database.AuditFailed, err.Error()
+	}
+	if err := s.db.SetAuditOutcome(entry.ID, outcome, details); err != nil {
+		slog.Error("Can not record outcome of audit entry.", slog.Uint64("audit_id", uint64(entry.ID)), slog.String("error", err.Error()))
+	}
+	return true, err
+}
+
+// pageParams reads offset and batch_size, like /tasks does.
+func pageParams(query url.Values) (offset, limit int, err error) {
+	if raw := query.Get("offset"); raw != "" {
+		if offset, err = strconv.Atoi(raw); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("bad offset %q", raw)
+		}
+	}
+	limit = defaultPageSize
+	if raw := query.Get("batch_size"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 || limit > maxPageSize {
+			return 0, 0, fmt.Errorf("batch_size must be from 1 to %d", maxPageSize)
+		}
+	}
+	return offset, limit, nil
+}
+
+func (s *Server) getAdminUsers(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.checkAdmin(w, r)
+	if !ok {
+		return
+	}
+	offset, limit, err := pageParams(r.URL.Query())
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	users, err := s.db.SearchUsers(query, offset, limit)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not get users: %v", err)
+		return
+	}
+	if !s.audit(w, r, admin, "search_users", "", query) {
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(AdminUsers{Users: users, Offset: offset + len(users)})
+}
+
+// decodeAdminUser reads the target user of an action. Administrators can
+// not apply it to themselves, so they can not lock themselves out.
+func decodeAdminUser(w http.ResponseWriter, r *http.Request, admin string) (string, bool) {
+	var req AdminUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return "", false
+	}
+	defer r.Body.Close()
+	if req.Login == "" {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Login is required")
+		return "", false
+	}
+	if req.Login == admin {
+		apierror.Write(w, http.StatusConflict, apierror.FailedPrecondition, "Administrators can not do this to themselves")
+		return "", false
+	}
+	return req.Login, true
+}
+
+func writeUserError(w http.ResponseWriter, err error, what string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		apierror.Write(w, http.StatusNotFound, apierror.NotFound, "User not found")
+		return
+	}
+	apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "%s: %v", what, err)
+}
+
+func (s *Server) disableUser(w http.ResponseWriter, r *http.Request) {
+	s.setUserDisabled(w, r, true)
+}
+
+func (s *Server) enableUser(w http.ResponseWriter, r *http.Request) {
+	s.setUserDisabled(w, r, false)
+}
+
+func (s *Server) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	admin, ok := s.checkAdmin(w, r)
+	if !ok {
+		return
+	}
+	login, ok := decodeAdminUser(w, r, admin)
+	if !ok {
+		return
+	}
+
+	action := "enable_user"
+	if disabled {
+		action = "disable_user"
+	}
+	if err := s.db.SetUserDisabled(login, disabled, auditEntry(r, admin, action, login, "")); err != nil {
+		writeUserError(w, err, "Can not update user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) setUserRole(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.checkAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req AdminRole
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+	if req.Login == "" {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Login is required")
+		return
+	}
+	if !slices.Contains(database.Roles, req.Role) {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Unknown role %q, expected one of %v", req.Role, database.Roles)
+		return
+	}
+	if req.Login == admin {
+		apierror.Write(w, http.StatusConflict, apierror.FailedPrecondition, "Administrators can not change their own role")
+		return
+	}
+
+	if err := s.db.SetUserRole(req.Login, req.Role, auditEntry(r, admin, "set_role", req.Login, req.Role)); err != nil {
+		writeUserError(w, err, "Can not update user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resetUserPassword drops the password, ends all sessions and mails a
+// reset link. Users without a mail could not set a new password, so they
+// are refused.
+func (s *Server) resetUserPassword(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.checkAdmin(w, r)
+	if !ok {
+		return
+	}
+	login, ok := decodeAdminUser(w, r, admin)
+	if !ok {
+		return
+	}
+
+	_, mail, token, err := s.auth.ResetToken(login)
+	if errors.Is(err, auth.ErrNoMail) {
+		apierror.Write(w, http.StatusConflict, apierror.FailedPrecondition, "User has no mail to send a reset link to")
+		return
+	} else if err != nil {
+		writeUserError(w, err, "Can not reset password")
+		return
+	}
+	if err := s.db.ForcePasswordReset(login, auditEntry(r, admin, "reset_password", login, "")); err != nil {
+		writeUserError(w, err, "Can not reset password")
+		return
+	}
+
+	go s.sendResetMail(login, mail, token,
+		"Администратор сбросил пароль от вашего аккаунта, все сеансы завершены.",
+		"До смены пароля войти с прежним не получится.")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getLoginHistory(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.checkAdmin(w, r)
+	if !ok {
+		return
+	}
+	login := r.URL.Query().Get("login")
+	if login == "" {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Login is required")
+		return
+	}
+	offset, limit, err := pageParams(r.URL.Query())
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
+		return
+	}
+
+	events, err := s.db.GetLoginEvents(login, offset, limit)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not get login history: %v", err)
+		return
+	}
+	if !s.audit(w, r, admin, "view_logins", login, "") {
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(LoginHistory{Events: events, Offset: offset + len(events)})
+}
+
+func (s *Server) adminDeleteTask(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.checkAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
+		return
+	}
+
+	ok, err = s.auditCall(w, r, admin, "delete_task", strconv.Itoa(id), func() error {
+		_, err := s.taskMan.AdminDeleteTask(r.Context(), &pb.AdminTaskRequest{Id: uint32(id)})
+		return err
+	})
+	if !ok {
+		return
+	}
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not delete task")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) adminRestoreTask(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.checkAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req AdminTask
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	ok, err := s.auditCall(w, r, admin, "restore_task", strconv.Itoa(int(req.ID)), func() error {
+		_, err := s.taskMan.AdminRestoreTask(r.Context(), &pb.AdminTaskRequest{Id: uint32(req.ID)})
+		return err
+	})
+	if !ok {
+		return
+	}
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not restore task")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getAuditLog(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.checkAdmin(w, r)
+	if !ok {
+		return
+	}
+	offset, limit, err := pageParams(r.URL.Query())
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
+		return
+	}
+
+	query := r.URL.Query()
+	entries, err := s.db.GetAuditEntries(database.AuditFilter{
+		Actor:  query.Get("actor"),
+		Target: query.Get("target"),
+		Action: query.Get("action"),
+	}, offset, limit)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not get audit log: %v", err)
+		return
+	}
+	if !s.audit(w, r, admin, "view_audit", "", "") {
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(AuditLog{Entries: entries, Offset: offset + len(entries)})
+}
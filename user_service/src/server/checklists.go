@@ -0,0 +1,190 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	pb "userservice/proto"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+)
+
+type ChecklistItem struct {
+	ID       uint   `json:"id,omitempty"`
+	TaskID   uint   `json:"task_id,omitempty"`
+	Position *int   `json:"position,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Done     bool   `json:"done"`
+}
+
+type ChecklistProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+func protoToChecklistItem(item *pb.ChecklistItem) ChecklistItem {
+	position := int(item.Position)
+	return ChecklistItem{
+		ID:       uint(item.Id),
+		Position: &position,
+		Text:     item.Text,
+		Done:     item.Done,
+	}
+}
+
+func protoToChecklist(items []*pb.ChecklistItem) []ChecklistItem {
+	if len(items) == 0 {
+		return nil
+	}
+	checklist := make([]ChecklistItem, 0, len(items))
+	for _, item := range items {
+		checklist = append(checklist, protoToChecklistItem(item))
+	}
+	return checklist
+}
+
+func protoToChecklistProgress(task *pb.Task) *ChecklistProgress {
+	if task.ChecklistTotal == 0 {
+		return nil
+	}
+	return &ChecklistProgress{
+		Done:  int(task.ChecklistDone),
+		Total: int(task.ChecklistTotal),
+	}
+}
+
+// decodeChecklistItem reads a checklist item and makes sure it refers to a
+// task and, unless it is a new item, to an item.
+func decodeChecklistItem(w http.ResponseWriter, r *http.Request, isNew bool) (*ChecklistItem, bool) {
+	var item ChecklistItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return nil, false
+	}
+	defer r.Body.Close()
+
+	if item.TaskID == 0 {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "task_id is required")
+		return nil, false
+	}
+	if !isNew && item.ID == 0 {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "id is required")
+		return nil, false
+	}
+	return &item, true
+}
+
+func (s *Server) addChecklistItem(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	item, ok := decodeChecklistItem(w, r, true)
+	if !ok {
+		return
+	}
+
+	position := int32(-1)
+	if item.Position != nil {
+		position = int32(*item.Position)
+	}
+	resp, err := s.taskMan.AddChecklistItem(r.Context(), &pb.AddChecklistItemRequest{
+		TaskId:   uint32(item.TaskID),
+		Author:   login,
+		Text:     item.Text,
+		Position: position,
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not add checklist item")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(protoToChecklistItem(resp))
+}
+
+func (s *Server) checkChecklistItem(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	item, ok := decodeChecklistItem(w, r, false)
+	if !ok {
+		return
+	}
+
+	_, err := s.taskMan.CheckChecklistItem(r.Context(), &pb.CheckChecklistItemRequest{
+		TaskId: uint32(item.TaskID),
+		Id:     uint32(item.ID),
+		Author: login,
+		Done:   item.Done,
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not check checklist item")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) moveChecklistItem(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	item, ok := decodeChecklistItem(w, r, false)
+	if !ok {
+		return
+	}
+	if item.Position == nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "position is required")
+		return
+	}
+
+	_, err := s.taskMan.MoveChecklistItem(r.Context(), &pb.MoveChecklistItemRequest{
+		TaskId:   uint32(item.TaskID),
+		Id:       uint32(item.ID),
+		Author:   login,
+		Position: int32(*item.Position),
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not move checklist item")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	taskID, err := strconv.Atoi(r.URL.Query().Get("task_id"))
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
+		return
+	}
+
+	_, err = s.taskMan.DeleteChecklistItem(r.Context(), &pb.DeleteChecklistItemRequest{
+		TaskId: uint32(taskID),
+		Id:     uint32(id),
+		Author: login,
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not delete checklist item")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"time"
+	pb "userservice/proto"
+	statpb "userservice/proto/statistic"
+	"userservice/src/middleware"
+	"userservice/src/resilience"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// hedgeDelay is about the slowest answer of a healthy service to a point
+// read; a slower one is asked again.
+const hedgeDelay = 200 * time.Millisecond
+
+// Calls that change nothing or end in the same state when repeated. Only
+// these are retried.
+var (
+	idempotentTaskCalls = []string{
+		pb.TaskService_GetTask_FullMethodName,
+		pb.TaskService_GetTasks_FullMethodName,
+		pb.TaskService_CountTasks_FullMethodName,
+		pb.TaskService_GetCountCandidates_FullMethodName,
+		pb.TaskService_CountQueries_FullMethodName,
+		pb.TaskService_GetFieldDefinitions_FullMethodName,
+		pb.TaskService_GetSprints_FullMethodName,
+		pb.TaskService_GetVelocity_FullMethodName,
+		pb.TaskService_GetUserTaskIds_FullMethodName,
+		pb.TaskService_DeleteUserData_FullMethodName,
+	}
+	idempotentStatCalls = []string{
+		statpb.StatisticsService_GetTaskStats_FullMethodName,
+		statpb.StatisticsService_GetTopTasks_FullMethodName,
+		statpb.StatisticsService_GetTopUsers_FullMethodName,
+		statpb.StatisticsService_GetAllStats_FullMethodName,
+		statpb.StatisticsService_GetStatsForTasks_FullMethodName,
+		statpb.StatisticsService_GetUserDeletion_FullMethodName,
+		statpb.StatisticsService_GetUserActivity_FullMethodName,
+	}
+	hedgedCalls = []string{
+		pb.TaskService_GetTask_FullMethodName,
+		statpb.StatisticsService_GetTaskStats_FullMethodName,
+	}
+)
+
+// dialOptions sets up a connection to a service. The breaker is outermost,
+// so a call counts once however many times it was retried, and calls are
+// not retried while the breaker is open.
+func dialOptions(service string, idempotent []string) []grpc.DialOption {
+	breaker := resilience.NewBreaker(service, resilience.DefaultBreakerConfig)
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(
+			middleware.ForwardRequestID,
+			breaker.Interceptor,
+			resilience.Retry(resilience.DefaultRetryPolicy, idempotent...),
+			resilience.Hedge(hedgeDelay, hedgedCalls...),
+		),
+	}
+}
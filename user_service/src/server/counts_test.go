@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	pb "userservice/proto"
+	statpb "userservice/proto/statistic"
+	"userservice/src/database"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeTasks struct {
+	pb.TaskServiceClient
+	candidates []uint32
+	filters    bool
+	countCalls int
+	queries    []string
+}
+
+func (t *fakeTasks) GetCountCandidates(ctx context.Context, req *pb.GetTasksRequest, opts ...grpc.CallOption) (*pb.GetCountCandidatesResponse, error) {
+	if t.candidates == nil {
+		return &pb.GetCountCandidatesResponse{}, nil
+	}
+	return &pb.GetCountCandidatesResponse{UsesCounts: true, TaskIds: t.candidates, FiltersByCounts: t.filters}, nil
+}
+
+// CountQueries counts a query by its length. Queries starting with "likes"
+// filter by counts and need tasks 1 and 2, others need 2 and 3.
+func (t *fakeTasks) CountQueries(ctx context.Context, req *pb.CountQueriesRequest, opts ...grpc.CallOption) (*pb.CountQueriesResponse, error) {
+	t.countCalls++
+	resp := &pb.CountQueriesResponse{}
+	for _, q := range req.Queries {
+		switch {
+		case q == "bad":
+			resp.Counts = append(resp.Counts, &pb.QueryCount{Error: "unknown field"})
+		case strings.HasPrefix(q, "likes") && !req.WithCounts:
+			resp.Counts = append(resp.Counts, &pb.QueryCount{NeedsCounts: true, TaskIds: []uint32{1, 2}})
+		case strings.HasPrefix(q, "views") && !req.WithCounts:
+			resp.Counts = append(resp.Counts, &pb.QueryCount{NeedsCounts: true, TaskIds: []uint32{2, 3}})
+		default:
+			resp.Counts = append(resp.Counts, &pb.QueryCount{Count: uint32(len(q)) + uint32(len(req.Likes))})
+		}
+	}
+	return resp, nil
+}
+
+// GetTasks refuses the query "bad" and returns no tasks otherwise.
+func (t *fakeTasks) GetTasks(ctx context.Context, req *pb.GetTasksRequest, opts ...grpc.CallOption) (*pb.GetTasksReponse, error) {
+	t.queries = append(t.queries, req.Query)
+	if req.Query == "bad" {
+		return nil, status.Error(codes.InvalidArgument, "unknown field")
+	}
+	return &pb.GetTasksReponse{}, nil
+}
+
+type fakeStats struct {
+	statpb.StatisticsServiceClient
+	down  bool
+	calls [][]uint32
+}
+
+func (s *fakeStats) GetStatsForTasks(ctx context.Context, req *statpb.GetStatsForTasksRequest, opts ...grpc.CallOption) (*statpb.GetAllStatsResponse, error) {
+	s.calls = append(s.calls, req.TaskIds)
+	if s.down {
+		return nil, errors.New("statistics service is down")
+	}
+	likes := make(map[uint32]int32)
+	for _, id := range req.TaskIds {
+		likes[id] = int32(id)
+	}
+	return &statpb.GetAllStatsResponse{Likes: likes}, nil
+}
+
+func TestAddCounts(t *testing.T) {
+	tasks := &fakeTasks{}
+	stats := &fakeStats{}
+	s := &Server{taskMan: tasks, statMan: stats}
+
+	// A phrase or a field that only contains the word uses no counts.
+	req := &pb.GetTasksRequest{Query: `"likes" title:views`}
+	if complete, err := s.addCounts(context.Background(), req); !complete || err != nil || len(stats.calls) != 0 {
+		t.Fatalf("expected no stats call, got %v, %v after %d calls", complete, err, len(stats.calls))
+	}
+
+	tasks.candidates = []uint32{3, 7}
+	req = &pb.GetTasksRequest{Query: "author:kek likes>2"}
+	if complete, err := s.addCounts(context.Background(), req); !complete || err != nil {
+		t.Fatalf("expected counts, got %v, %v", complete, err)
+	}
+	if len(stats.calls) != 1 || !slices.Equal(stats.calls[0], tasks.candidates) {
+		t.Errorf("expected counts of the candidates only, got %v", stats.calls)
+	}
+	if len(req.Likes) != 2 || req.Likes[7] != 7 {
+		t.Errorf("expected likes of the candidates, got %v", req.Likes)
+	}
+
+	// Without stats tasks can still be sorted, but not filtered.
+	stats.down = true
+	req = &pb.GetTasksRequest{Query: "sort:-likes"}
+	if complete, err := s.addCounts(context.Background(), req); complete || err != nil {
+		t.Errorf("expected incomplete counts, got %v, %v", complete, err)
+	}
+	tasks.filters = true
+	req = &pb.GetTasksRequest{Query: "likes>2"}
+	if _, err := s.addCounts(context.Background(), req); !errors.Is(err, errCountsUnavailable) {
+		t.Errorf("expected counts unavailable, got %v", err)
+	}
+}
+
+func TestCountViews(t *testing.T) {
+	tasks := &fakeTasks{}
+	stats := &fakeStats{}
+	s := &Server{taskMan: tasks, statMan: stats}
+	views := []database.View{{Query: "ab"}, {Query: "likes>1"}, {Query: "views>1"}}
+
+	counts, err := s.countViews(context.Background(), views)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks.countCalls != 2 || len(stats.calls) != 1 || !slices.Equal(stats.calls[0], []uint32{1, 2, 3}) {
+		t.Fatalf("expected two count calls and one stats call for tasks 1-3, got %d and %v", tasks.countCalls, stats.calls)
+	}
+	// The fake adds the number of likes passed, so counted with stats
+	// means three more.
+	target := []uint32{2, 10, 10}
+	for i, count := range counts {
+		if count.Count == nil || *count.Count != target[i] {
+			t.Errorf("view %d: expected %d, got %v", i, target[i], count.Count)
+		}
+	}
+
+	// Without stats only the views that filter by counts have none.
+	stats.down = true
+	counts, err = s.countViews(context.Background(), views)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[0].Count == nil || counts[1].Count != nil || counts[2].Count != nil {
+		t.Errorf("expected counts of views without likes and views only, got %v", counts)
+	}
+
+	// A broken view gets its error and the others are still counted.
+	stats.down = false
+	counts, err = s.countViews(context.Background(), []database.View{{Name: "broken", Query: "bad"}, {Query: "ab"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[0].Count != nil || counts[0].Error == "" || counts[1].Count == nil || counts[1].Error != "" {
+		t.Errorf("expected the error of the broken view only, got %+v", counts)
+	}
+}
+
+func TestListTasksSkipsBrokenDefaultView(t *testing.T) {
+	tasks := &fakeTasks{}
+	s := &Server{taskMan: tasks, statMan: &fakeStats{}}
+
+	if _, _, err := s.listTasks(context.Background(), &pb.GetTasksRequest{Query: "bad"}, true); err != nil {
+		t.Fatalf("expected the default view skipped, got %v", err)
+	}
+	if !slices.Equal(tasks.queries, []string{"bad", ""}) {
+		t.Errorf("expected a retry without the query, got %q", tasks.queries)
+	}
+
+	// A view the user asked for is reported.
+	if _, _, err := s.listTasks(context.Background(), &pb.GetTasksRequest{Query: "bad"}, false); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected invalid argument, got %v", err)
+	}
+}
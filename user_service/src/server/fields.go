@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	pb "userservice/proto"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var fieldFilter = regexp.MustCompile(`^([a-z][a-z0-9_]*)(!=|<=|>=|=|<|>)(.*)$`)
+
+// FieldValues holds custom field values by field name. Values may be sent
+// as JSON strings or numbers; null clears the field.
+type FieldValues map[string]string
+
+func (f *FieldValues) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	values := make(FieldValues, len(raw))
+	for name, value := range raw {
+		var str string
+		var number json.Number
+		switch {
+		case bytes.Equal(value, []byte("null")):
+			values[name] = ""
+		case json.Unmarshal(value, &str) == nil:
+			values[name] = str
+		case json.Unmarshal(value, &number) == nil:
+			values[name] = number.String()
+		default:
+			return fmt.Errorf("field %s: value must be a string or a number", name)
+		}
+	}
+	*f = values
+	return nil
+}
+
+type FieldDefinition struct {
+	ID      uint     `json:"id,omitempty"`
+	Author  string   `json:"author,omitempty"`
+	Project string   `json:"project,omitempty"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Options []string `json:"options,omitempty"`
+}
+
+func protoToFieldDefinition(field *pb.FieldDefinition) FieldDefinition {
+	return FieldDefinition{
+		ID:      uint(field.Id),
+		Author:  field.Author,
+		Project: field.Project,
+		Name:    field.Name,
+		Type:    field.Type,
+		Options: field.Options,
+	}
+}
+
+// checkUserFields makes sure that values of user reference fields are logins
+// of existing users. Other values are validated by tasks manager.
+func (s *Server) checkUserFields(ctx context.Context, project string, fields FieldValues) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	resp, err := s.taskMan.GetFieldDefinitions(ctx, &pb.GetFieldDefinitionsRequest{
+		Project: project,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, field := range resp.Fields {
+		login := fields[field.Name]
+		if field.Type != "user" || login == "" {
+			continue
+		}
+		ok, err := s.db.UserExist(login)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if !ok {
+			return status.Errorf(codes.InvalidArgument, "field %s: user %q not found", field.Name, login)
+		}
+	}
+	return nil
+}
+
+func parseFieldFilters(raw []string) ([]*pb.FieldFilter, error) {
+	filters := make([]*pb.FieldFilter, 0, len(raw))
+	for _, filter := range raw {
+		match := fieldFilter.FindStringSubmatch(filter)
+		if match == nil {
+			return nil, fmt.Errorf("bad filter %q, expected <field><op><value>", filter)
+		}
+		filters = append(filters, &pb.FieldFilter{
+			Field: match[1],
+			Op:    match[2],
+			Value: match[3],
+		})
+	}
+	return filters, nil
+}
+
+func parseSort(sort string) (field string, desc bool) {
+	if strings.HasPrefix(sort, "-") {
+		return sort[1:], true
+	}
+	return sort, false
+}
+
+func (s *Server) createField(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	var field FieldDefinition
+	if err := json.NewDecoder(r.Body).Decode(&field); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	resp, err := s.taskMan.CreateFieldDefinition(r.Context(), &pb.CreateFieldDefinitionRequest{
+		Author:  login,
+		Project: field.Project,
+		Name:    field.Name,
+		Type:    field.Type,
+		Options: field.Options,
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not create field")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(protoToFieldDefinition(resp))
+}
+
+func (s *Server) getFields(w http.ResponseWriter, r *http.Request) {
+	_, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksRead)
+	if !ok {
+		return
+	}
+
+	resp, err := s.taskMan.GetFieldDefinitions(r.Context(), &pb.GetFieldDefinitionsRequest{
+		Project: r.URL.Query().Get("project"),
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not get fields")
+		return
+	}
+
+	fields := make([]FieldDefinition, 0, len(resp.Fields))
+	for _, field := range resp.Fields {
+		fields = append(fields, protoToFieldDefinition(field))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(fields)
+}
+
+func (s *Server) deleteField(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
+		return
+	}
+
+	_, err = s.taskMan.DeleteFieldDefinition(r.Context(), &pb.DeleteFieldDefinitionRequest{
+		Id:     uint32(id),
+		Author: login,
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not delete field")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	pb "userservice/proto"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+)
+
+type TaskLink struct {
+	ID       uint   `json:"id,omitempty"`
+	Type     string `json:"type"`
+	SourceID uint   `json:"source_id"`
+	TargetID uint   `json:"target_id"`
+}
+
+type MergeRequest struct {
+	DuplicateID uint `json:"duplicate_id"`
+	CanonicalID uint `json:"canonical_id"`
+}
+
+func protoToTaskLink(link *pb.TaskLink) TaskLink {
+	return TaskLink{
+		ID:       uint(link.Id),
+		Type:     link.Type,
+		SourceID: uint(link.SourceId),
+		TargetID: uint(link.TargetId),
+	}
+}
+
+func protoToTaskLinks(links []*pb.TaskLink) []TaskLink {
+	if len(links) == 0 {
+		return nil
+	}
+	result := make([]TaskLink, 0, len(links))
+	for _, link := range links {
+		result = append(result, protoToTaskLink(link))
+	}
+	return result
+}
+
+func (s *Server) linkTasks(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	var link TaskLink
+	if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	resp, err := s.taskMan.LinkTasks(r.Context(), &pb.LinkTasksRequest{
+		Author:   login,
+		Type:     link.Type,
+		SourceId: uint32(link.SourceID),
+		TargetId: uint32(link.TargetID),
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not link tasks")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(protoToTaskLink(resp))
+}
+
+func (s *Server) unlinkTasks(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
+		return
+	}
+
+	_, err = s.taskMan.UnlinkTasks(r.Context(), &pb.UnlinkTasksRequest{
+		Author: login,
+		Id:     uint32(id),
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not unlink tasks")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mergeTasks closes the duplicate in tasks manager and then moves its likes
+// and views in statistics service. Both steps are idempotent, so a client
+// may simply retry the request if the second step fails.
+func (s *Server) mergeTasks(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	var merge MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&merge); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	_, err := s.taskMan.MergeTasks(r.Context(), &pb.MergeTasksRequest{
+		Author:      login,
+		DuplicateId: uint32(merge.DuplicateID),
+		CanonicalId: uint32(merge.CanonicalID),
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not merge tasks")
+		return
+	}
+
+	// Statistics follow in the background if statistics service is down.
+	err = s.merges.Start(r.Context(), uint32(merge.DuplicateID), uint32(merge.CanonicalID), time.Now())
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Tasks merged, but can not move statistics, retry the request: %v", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+	"userservice/src/database"
+	"userservice/src/mailer"
+
+	"gorm.io/gorm"
+)
+
+type ForgotPasswordRequest struct {
+	// Login is the login or the mail of the user.
+	Login string `json:"login"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type VerifyMailRequest struct {
+	Token string `json:"token"`
+}
+
+func (s *Server) link(path, token string) string {
+	return s.publicURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func (s *Server) sendVerification(login string) error {
+	mail, token, err := s.auth.VerificationToken(login)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(mailer.Message{
+		To:      mail,
+		Subject: "Подтверждение почты",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы подтвердить почту, перейдите по ссылке:\n%s\n\nСсылка действует сутки.\n",
+			login, s.link("/verify-email", token)),
+	})
+}
+
+func (s *Server) sendVerificationMail(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.sendVerification(login)
+	if errors.Is(err, auth.ErrNoMail) {
+		apierror.Write(w, http.StatusBadRequest, apierror.FailedPrecondition, "Set mail with /update-info first")
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not send verification mail: %v", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verifyMail accepts the token from the link in the mail or from a JSON body.
+func (s *Server) verifyMail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" && r.ContentLength != 0 {
+		var req VerifyMailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+			return
+		}
+		defer r.Body.Close()
+		token = req.Token
+	}
+
+	err := s.auth.VerifyMail(token)
+	if isMailTokenError(err) {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not verify mail: %v", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// forgotPassword always answers 204, so it can not be used to find out
+// which logins and mails are registered.
+func (s *Server) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	login, mail, token, err := s.auth.ResetToken(req.Login)
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, auth.ErrNoMail) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not reset password: %v", err)
+		return
+	}
+
+	// Sending in the background keeps the response time the same for
+	// unknown users.
+	go s.sendResetMail(login, mail, token,
+		"Для входа в аккаунт запрошен сброс пароля.",
+		"Если вы не запрашивали сброс, просто проигнорируйте письмо.")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) sendResetMail(login, mail, token, reason, footer string) {
+	err := s.mailer.Send(mailer.Message{
+		To:      mail,
+		Subject: "Восстановление пароля",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\n%s Новый пароль можно задать запросом\n"+
+			"POST %s/password/reset с телом {\"token\": \"%s\", \"password\": \"...\"}\n\n"+
+			"Токен действует час. %s\n",
+			login, reason, s.publicURL, token, footer),
+	})
+	if err != nil {
+		slog.Error("Can not send password reset mail.", slog.String("login", login), slog.String("error", err.Error()))
+	}
+}
+
+func (s *Server) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := s.auth.ValidatePassword(req.Password); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Bad password: %v", err)
+		return
+	}
+
+	err := s.auth.ResetPassword(req.Token, req.Password)
+	if isMailTokenError(err) {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not reset password: %v", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// changePassword ends every session of the user, including the current
+// one, and answers with tokens of a new session. Wrong current passwords
+// count as failed logins.
+func (s *Server) changePassword(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	now := time.Now()
+	if throttled(w, s.loginLimiter, loginKey(login), now) {
+		return
+	}
+	if err := s.auth.ValidatePassword(req.NewPassword); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Bad password: %v", err)
+		return
+	}
+
+	err := s.auth.ChangePassword(login, req.CurrentPassword, req.NewPassword)
+	if errors.Is(err, auth.ErrWrongPassword) {
+		s.failLogin(r, login, now)
+		apierror.Write(w, http.StatusForbidden, apierror.PermissionDenied, "Wrong password")
+		return
+	} else if errors.Is(err, auth.ErrNoPassword) {
+		apierror.Write(w, http.StatusConflict, apierror.FailedPrecondition, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not change password: %v", err)
+		return
+	}
+	if err := s.loginLimiter.Reset(loginKey(login)); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not reset attempts: %v", err)
+		return
+	}
+
+	go s.sendPasswordChangedMail(login, now)
+
+	tokens, err := s.auth.IssueTokens(login, clientOf(r))
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not generate token: %v", err)
+		return
+	}
+	writeTokens(w, tokens)
+}
+
+// sendPasswordChangedMail warns the user, in case it was not them. Users
+// without a mail are not notified.
+func (s *Server) sendPasswordChangedMail(login string, changedAt time.Time) {
+	mail, _, err := s.db.GetMail(login)
+	if err != nil {
+		slog.Error("Can not get mail.", slog.String("login", login), slog.String("error", err.Error()))
+		return
+	}
+	if mail == "" {
+		return
+	}
+	err = s.mailer.Send(mailer.Message{
+		To:      mail,
+		Subject: "Пароль изменён",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nПароль от вашего аккаунта изменён %s, все сеансы на других устройствах завершены.\n\n"+
+			"Если вы не меняли пароль, восстановите доступ запросом POST %s/password/forgot с телом {\"login\": \"%s\"}.\n",
+			login, changedAt.UTC().Format("02.01.2006 15:04 MST"), s.publicURL, login),
+	})
+	if err != nil {
+		slog.Error("Can not send password change mail.", slog.String("login", login), slog.String("error", err.Error()))
+	}
+}
+
+func isMailTokenError(err error) bool {
+	return errors.Is(err, auth.ErrMailTokenInvalid) ||
+		errors.Is(err, database.ErrMailTokenUsed) ||
+		errors.Is(err, database.ErrMailChanged)
+}
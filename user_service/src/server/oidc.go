@@ -0,0 +1,151 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"userservice/src/apierror"
+	"userservice/src/database"
+	"userservice/src/oidc"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	oidcFlowCookie  = "oidc_flow"
+	oidcFlowPurpose = "oidc_flow"
+)
+
+var oidcFlowTTL = 10 * time.Minute
+
+// oidcFlow lives in a sealed cookie between the redirect to the provider
+// and the callback, so any replica can finish the login.
+type oidcFlow struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	LinkTo   string `json:"link_to,omitempty"`
+}
+
+func (s *Server) redirectURI(provider string) string {
+	return s.publicURL + "/oidc/" + provider + "/callback"
+}
+
+func (s *Server) getOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(names)
+}
+
+// oidcLogin sends the user to the provider. With ?link=1 a logged in user
+// links the external account to theirs instead.
+func (s *Server) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	provider, ok := s.providers[name]
+	if !ok {
+		apierror.Write(w, http.StatusNotFound, apierror.NotFound, "Unknown provider %q", name)
+		return
+	}
+
+	flow := oidcFlow{Provider: name}
+	if r.URL.Query().Get("link") != "" {
+		login, ok := s.auth.CheckAuth(w, r)
+		if !ok {
+			return
+		}
+		flow.LinkTo = login
+	}
+	for _, value := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		random, err := oidc.RandomString()
+		if err != nil {
+			apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not start login: %v", err)
+			return
+		}
+		*value = random
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), s.redirectURI(name), flow.State, flow.Nonce, flow.Verifier)
+	if err != nil {
+		apierror.Write(w, http.StatusBadGateway, apierror.Unavailable, "Provider %s is unavailable: %v", name, err)
+		return
+	}
+	sealed, err := s.auth.Seal(oidcFlowPurpose, flow, oidcFlowTTL)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not start login: %v", err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    sealed,
+		Path:     "/oidc/",
+		MaxAge:   int(oidcFlowTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(s.publicURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (s *Server) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	provider, ok := s.providers[name]
+	if !ok {
+		apierror.Write(w, http.StatusNotFound, apierror.NotFound, "Unknown provider %q", name)
+		return
+	}
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "Provider refused login: %s %s", errCode, query.Get("error_description"))
+		return
+	}
+
+	var flow oidcFlow
+	cookie, err := r.Cookie(oidcFlowCookie)
+	if err == nil {
+		err = s.auth.Open(oidcFlowPurpose, cookie.Value, &flow)
+	}
+	if err != nil || flow.Provider != name ||
+		subtle.ConstantTimeCompare([]byte(flow.State), []byte(query.Get("state"))) != 1 {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Login state is missing or does not match, start again")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcFlowCookie, Path: "/oidc/", MaxAge: -1, HttpOnly: true})
+
+	identity, err := provider.Exchange(r.Context(), s.redirectURI(name), query.Get("code"), flow.Verifier, flow.Nonce)
+	if err != nil {
+		apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "Can not log in with %s: %v", name, err)
+		return
+	}
+
+	login, err := s.auth.ExternalLogin(identity, flow.LinkTo)
+	if errors.Is(err, database.ErrIdentityLinked) {
+		apierror.Write(w, http.StatusConflict, apierror.AlreadyExists, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not log in with %s: %v", name, err)
+		return
+	}
+
+	s.issueTokens(w, r, login, "oidc:"+name)
+}
+
+func providersByName(providers []*oidc.Provider) map[string]*oidc.Provider {
+	result := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		result[provider.Name()] = provider
+	}
+	return result
+}
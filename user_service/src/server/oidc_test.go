@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"userservice/src/middleware"
+	"userservice/src/oidc"
+	"userservice/src/oidc/oidctest"
+)
+
+func TestOIDCLoginRedirect(t *testing.T) {
+	stand, err := oidctest.New("task-tracker", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stand.Close()
+	stand.SetUser(oidctest.User{Subject: "42", Username: "kek"})
+
+	provider := oidc.NewProvider(oidc.Config{Name: "corp", Issuer: stand.Issuer(), ClientID: "task-tracker", ClientSecret: "secret"}, nil)
+	s := New(nil, nil, nil, []byte("secret"), nil, nil, []*oidc.Provider{provider}, middleware.Timeouts{}, nil)
+	s.publicURL = "http://tracker.example"
+	s.Register()
+
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oidc/corp/login", nil))
+	if w.Code != http.StatusFound || !strings.HasPrefix(w.Header().Get("Location"), stand.Issuer()+"/authorize?") {
+		t.Fatalf("expected redirect to the provider, got %d %s", w.Code, w.Header().Get("Location"))
+	}
+	flowCookie := w.Result().Cookies()[0]
+	if flowCookie.Name != oidcFlowCookie || !flowCookie.HttpOnly {
+		t.Fatalf("unexpected cookie %#v", flowCookie)
+	}
+
+	// The stand-in logs the user in and sends them back to the callback.
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if callback.Host != "tracker.example" || callback.Path != "/oidc/corp/callback" || callback.Query().Get("code") == "" {
+		t.Fatalf("unexpected callback %s", callback)
+	}
+
+	t.Run("No flow cookie", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("Forged state", func(t *testing.T) {
+		query := callback.Query()
+		query.Set("state", "forged")
+		r := httptest.NewRequest(http.MethodGet, callback.Path+"?"+query.Encode(), nil)
+		r.AddCookie(flowCookie)
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oidc/other/login", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+	})
+
+}
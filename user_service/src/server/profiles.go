@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+	"userservice/src/database"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// UserInfo is the answer to /info: the user's own data with privacy
+// settings of the public profile.
+type UserInfo struct {
+	Login string `json:"login"`
+	database.UserData
+	Privacy database.Privacy `json:"privacy"`
+}
+
+type Profiles struct {
+	Users  []database.Profile `json:"users"`
+	Offset int                `json:"offset"`
+}
+
+func (s *Server) updatePrivacy(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	var update database.PrivacyUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := s.db.UpdatePrivacy(login, &update); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not update privacy: %v", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getProfile(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.auth.CheckAuth(w, r, auth.ScopeUsersRead); !ok {
+		return
+	}
+
+	profile, err := s.db.GetProfile(chi.URLParam(r, "login"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		apierror.Write(w, http.StatusNotFound, apierror.NotFound, "User not found")
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not get profile: %v", err)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(profile)
+}
+
+// searchUsers is the user directory, e.g. for picking an assignee.
+func (s *Server) searchUsers(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.auth.CheckAuth(w, r, auth.ScopeUsersRead); !ok {
+		return
+	}
+	offset, limit, err := pageParams(r.URL.Query())
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
+		return
+	}
+
+	profiles, err := s.db.SearchProfiles(r.URL.Query().Get("q"), offset, limit)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not search users: %v", err)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(Profiles{Users: profiles, Offset: offset + len(profiles)})
+}
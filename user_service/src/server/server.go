@@ -3,8 +3,9 @@ package server
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"strconv"
@@ -12,13 +13,23 @@ import (
 	"time"
 	pb "userservice/proto"
 	statpb "userservice/proto/statistic"
+	"userservice/src/apierror"
 	"userservice/src/auth"
 	"userservice/src/broker"
 	"userservice/src/database"
+	"userservice/src/deletion"
+	"userservice/src/export"
+	"userservice/src/mailer"
+	"userservice/src/merge"
+	"userservice/src/middleware"
+	"userservice/src/oidc"
+	"userservice/src/throttle"
 
 	"github.com/go-chi/chi/v5"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 type Server struct {
@@ -28,23 +39,103 @@ type Server struct {
 	taskMan pb.TaskServiceClient
 	statMan statpb.StatisticsServiceClient
 	broker  *broker.Broker
+	mailer  mailer.Mailer
+
+	// publicURL is where users reach the service, for links in mails.
+	publicURL string
+
+	loginLimiter    *throttle.Limiter
+	ipLimiter       *throttle.Limiter
+	registerLimiter *throttle.Limiter
+
+	providers map[string]*oidc.Provider
+
+	timeouts middleware.Timeouts
+	proxies  middleware.Proxies
+
+	// Workers are started by Listen, once the other services are dialed.
+	deletions *deletion.Worker
+	exports   *export.Worker
+	merges    *merge.Worker
 }
 
-func New(db *database.DataBase) (*Server, func()) {
-	broker, close := broker.New()
+func New(db *database.DataBase, broker *broker.Broker, keys *auth.KeySet, mailSecret []byte, mail mailer.Mailer, attempts throttle.Store, providers []*oidc.Provider, timeouts middleware.Timeouts, proxies middleware.Proxies) *Server {
+	publicURL := os.Getenv("PUBLIC_URL")
+	if publicURL == "" {
+		publicURL = "http://localhost:8080"
+	}
 	return &Server{
-		mux:    chi.NewRouter(),
-		db:     db,
-		auth:   auth.New(db),
-		broker: broker,
-	}, close
+		mux:       chi.NewRouter(),
+		db:        db,
+		auth:      auth.New(db, keys, mailSecret),
+		broker:    broker,
+		mailer:    mail,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+
+		loginLimiter:    throttle.New(attempts, throttle.DefaultConfig),
+		ipLimiter:       throttle.New(attempts, ipConfig),
+		registerLimiter: throttle.New(attempts, registerConfig),
+
+		providers: providersByName(providers),
+		timeouts:  timeouts,
+		proxies:   proxies,
+	}
 }
 
 func (s *Server) Register() {
+	s.mux.Use(
+		middleware.RequestID,
+		s.proxies.RealIP,
+		middleware.AccessLog(slog.Default()),
+		middleware.Recover(slog.Default()),
+		middleware.Deadline(s.mux, s.timeouts),
+	)
+
 	s.mux.Post("/register", s.register)
 	s.mux.Post("/login", s.login)
+	s.mux.Post("/login/2fa", s.loginTwoFactor)
+	s.mux.Get("/oidc/providers", s.getOIDCProviders)
+	s.mux.Get("/oidc/{provider}/login", s.oidcLogin)
+	s.mux.Get("/oidc/{provider}/callback", s.oidcCallback)
+	s.mux.Post("/refresh", s.refresh)
+	s.mux.Post("/logout", s.logout)
+	s.mux.Post("/logout-all", s.logoutAll)
+	s.mux.Post("/revoke", s.revoke)
+	s.mux.Get("/sessions", s.getSessions)
+	s.mux.Delete("/sessions", s.revokeSession)
+	s.mux.Post("/tokens", s.createAccessToken)
+	s.mux.Get("/tokens", s.getAccessTokens)
+	s.mux.Delete("/tokens", s.deleteAccessToken)
+	s.mux.Post("/password/forgot", s.forgotPassword)
+	s.mux.Post("/password/reset", s.resetPassword)
+	s.mux.Post("/password/change", s.changePassword)
+	s.mux.Get("/verify-email", s.verifyMail)
+	s.mux.Post("/verify-email", s.verifyMail)
+	s.mux.Post("/verify-email/send", s.sendVerificationMail)
+	s.mux.Post("/2fa/enroll", s.enrollTwoFactor)
+	s.mux.Post("/2fa/enable", s.enableTwoFactor)
+	s.mux.Post("/2fa/disable", s.disableTwoFactor)
+	s.mux.Post("/admin/unlock", s.unlock)
+	s.mux.Get("/admin/users", s.getAdminUsers)
+	s.mux.Post("/admin/users/disable", s.disableUser)
+	s.mux.Post("/admin/users/enable", s.enableUser)
+	s.mux.Put("/admin/users/role", s.setUserRole)
+	s.mux.Post("/admin/users/reset-password", s.resetUserPassword)
+	s.mux.Get("/admin/users/logins", s.getLoginHistory)
+	s.mux.Delete("/admin/tasks", s.adminDeleteTask)
+	s.mux.Post("/admin/tasks/restore", s.adminRestoreTask)
+	s.mux.Get("/admin/audit", s.getAuditLog)
+	s.mux.Delete("/account", s.deleteAccount)
+	s.mux.Get("/account/deletion", s.getAccountDeletion)
+	s.mux.Post("/account/export", s.startExport)
+	s.mux.Get("/account/export", s.getExport)
+	s.mux.Get("/account/export/download", s.downloadExport)
 	s.mux.Put("/update-info", s.updateInfo)
 	s.mux.Get("/info", s.getInfo)
+	s.mux.Put("/privacy", s.updatePrivacy)
+	s.mux.Get("/users", s.searchUsers)
+	s.mux.Get("/users/{login}", s.getProfile)
+	s.mux.Get("/.well-known/jwks.json", s.jwks)
 
 	s.mux.Post("/create-task", s.createTask)
 	s.mux.Get("/task", s.getTask)
@@ -52,6 +143,32 @@ func (s *Server) Register() {
 	s.mux.Delete("/task", s.deleteTask)
 	s.mux.Get("/tasks", s.getTasks)
 
+	s.mux.Post("/task/checklist", s.addChecklistItem)
+	s.mux.Put("/task/checklist/check", s.checkChecklistItem)
+	s.mux.Put("/task/checklist/move", s.moveChecklistItem)
+	s.mux.Delete("/task/checklist", s.deleteChecklistItem)
+
+	s.mux.Post("/task/links", s.linkTasks)
+	s.mux.Delete("/task/links", s.unlinkTasks)
+	s.mux.Post("/task/merge", s.mergeTasks)
+	s.mux.Put("/task/sprint", s.planTask)
+
+	s.mux.Post("/sprints", s.createSprint)
+	s.mux.Get("/sprints", s.getSprints)
+	s.mux.Post("/sprints/start", s.startSprint)
+	s.mux.Post("/sprints/close", s.closeSprint)
+	s.mux.Get("/velocity", s.getVelocity)
+
+	s.mux.Post("/fields", s.createField)
+	s.mux.Get("/fields", s.getFields)
+	s.mux.Delete("/fields", s.deleteField)
+
+	s.mux.Post("/views", s.createView)
+	s.mux.Get("/views", s.getViews)
+	s.mux.Put("/views", s.updateView)
+	s.mux.Delete("/views", s.deleteView)
+	s.mux.Put("/views/default", s.setDefaultView)
+
 	s.mux.Post("/like", s.addLike)
 	s.mux.Post("/view", s.addView)
 
@@ -65,7 +182,7 @@ func (s *Server) Listen(addr string) {
 	if tasksManAddr == "" {
 		tasksManAddr = "tasks_manager:8081"
 	}
-	taskManConn, err := grpc.Dial(tasksManAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	taskManConn, err := grpc.Dial(tasksManAddr, dialOptions("tasks_manager", idempotentTaskCalls)...)
 	if err != nil {
 		panic(err)
 	}
@@ -76,14 +193,24 @@ func (s *Server) Listen(addr string) {
 	if statisticsManAddr == "" {
 		statisticsManAddr = "statistics_service:8082"
 	}
-	statisticsManConn, err := grpc.Dial(statisticsManAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	statisticsManConn, err := grpc.Dial(statisticsManAddr, dialOptions("statistics_service", idempotentStatCalls)...)
 	if err != nil {
 		panic(err)
 	}
 	defer statisticsManConn.Close()
 	s.statMan = statpb.NewStatisticsServiceClient(statisticsManConn)
 
-	fmt.Println("Server started.")
+	go s.auth.PurgeExpired(time.Hour, nil)
+	go s.loginLimiter.PurgeEvery(time.Hour, nil)
+
+	s.deletions = deletion.NewWorker(s.db, s.taskMan, s.statMan, s.broker)
+	go s.deletions.Run(10*time.Second, nil)
+	s.exports = export.NewWorker(s.db, s.taskMan, s.statMan)
+	go s.exports.Run(10*time.Second, nil)
+	s.merges = merge.NewWorker(s.db, s.statMan)
+	go s.merges.Run(10*time.Second, nil)
+
+	slog.Info("Server started.", slog.String("addr", addr))
 	err = http.ListenAndServe(addr, s.mux)
 	log.Fatalf("Server stopped: %v", err)
 }
@@ -96,8 +223,7 @@ type LoginPassword struct {
 func (s *Server) register(w http.ResponseWriter, r *http.Request) {
 	var user LoginPassword
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse body: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
 		return
 	}
 	defer r.Body.Close()
@@ -105,21 +231,28 @@ func (s *Server) register(w http.ResponseWriter, r *http.Request) {
 	login := user.Login
 	password := user.Password
 
+	now := time.Now()
+	key := registerKey(clientIP(r))
+	if throttled(w, s.registerLimiter, key, now) {
+		return
+	}
+	if err := s.registerLimiter.Fail(now, key); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not record attempt: %v", err)
+		return
+	}
+
 	if err := s.auth.ValidateLogin(login); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Bad login: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Bad login: %v", err)
 		return
 	}
 
 	if err := s.auth.ValidatePassword(password); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Bad password: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Bad password: %v", err)
 		return
 	}
 
 	if err := s.auth.CreateUser(login, password); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "Can not create user: %v", err)
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not create user: %v", err)
 		return
 	}
 
@@ -129,8 +262,7 @@ func (s *Server) register(w http.ResponseWriter, r *http.Request) {
 func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 	var user LoginPassword
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse body: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
 		return
 	}
 	defer r.Body.Close()
@@ -138,24 +270,41 @@ func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 	login := user.Login
 	password := user.Password
 
-	if err := s.auth.CheckPassword(login, password); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Wrong login or password: %v", err)
+	now := time.Now()
+	if throttled(w, s.ipLimiter, ipKey(clientIP(r)), now) || throttled(w, s.loginLimiter, loginKey(login), now) {
 		return
 	}
 
-	token, err := s.auth.CreateToken(login)
+	if err := s.auth.CheckPassword(login, password); err != nil {
+		s.failLogin(r, login, now)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.recordLogin(r, login, "password", false)
+		}
+		apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "Wrong login or password: %v", err)
+		return
+	}
+	twoFactor, err := s.auth.TwoFactorEnabled(login)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "Can not generate token: %v", err)
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not check two-factor authentication: %v", err)
+		return
+	}
+	if twoFactor {
+		s.writePendingLogin(w, login)
+		return
+	}
+	if err := s.loginLimiter.Reset(loginKey(login)); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not reset attempts: %v", err)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:  "jwt",
-		Value: token,
-	})
-	w.WriteHeader(http.StatusOK)
+	s.issueTokens(w, r, login, "password")
+}
+
+// jwks publishes public keys, so other services can verify tokens.
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(s.auth.Keys().JWKS())
 }
 
 func (s *Server) updateInfo(w http.ResponseWriter, r *http.Request) {
@@ -166,24 +315,26 @@ func (s *Server) updateInfo(w http.ResponseWriter, r *http.Request) {
 
 	var userData database.UserData
 	if err := json.NewDecoder(r.Body).Decode(&userData); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse body: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
 		return
 	}
 	defer r.Body.Close()
 
 	if err := s.auth.ValidateUserData(&userData); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Bad user data: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Bad user data: %v", err)
 		return
 	}
 
-	err := s.db.UpdateUserData(login, &userData)
+	mailChanged, err := s.db.UpdateUserData(login, &userData)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "Can not update user data: %v", err)
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not update user data: %v", err)
 		return
 	}
+	if mailChanged {
+		if err := s.sendVerification(login); err != nil {
+			slog.Error("Can not send verification mail.", slog.String("login", login), slog.String("error", err.Error()))
+		}
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -195,129 +346,188 @@ func (s *Server) getInfo(w http.ResponseWriter, r *http.Request) {
 
 	data, err := s.db.GetUserData(login)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "Can not get user data: %v", err)
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not get user data: %v", err)
+		return
+	}
+
+	privacy, err := s.db.GetPrivacy(login)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not get user data: %v", err)
 		return
 	}
 
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "    ")
-	encoder.Encode(login)
-	encoder.Encode(data)
+	encoder.Encode(UserInfo{Login: login, UserData: *data, Privacy: privacy})
 }
 
 type TaskData struct {
-	ID           uint       `json:"id,omitempty"`
-	Author       string     `json:"author,omitempty"`
-	Title        string     `json:"title,omitempty"`
-	Content      string     `json:"content,omitempty"`
-	CreationTime *time.Time `json:"creation_time,omitempty"`
+	ID           uint        `json:"id,omitempty"`
+	Author       string      `json:"author,omitempty"`
+	Title        string      `json:"title,omitempty"`
+	Content      string      `json:"content,omitempty"`
+	CreationTime *time.Time  `json:"creation_time,omitempty"`
+	Project      string      `json:"project,omitempty"`
+	Status       string      `json:"status,omitempty"`
+	UpdateTime   *time.Time  `json:"update_time,omitempty"`
+	Fields       FieldValues `json:"fields,omitempty"`
+
+	Checklist         []ChecklistItem    `json:"checklist,omitempty"`
+	ChecklistProgress *ChecklistProgress `json:"checklist_progress,omitempty"`
+
+	Links      []TaskLink `json:"links,omitempty"`
+	MergedInto uint       `json:"merged_into,omitempty"`
+
+	// Estimate is omitted for tasks without an estimate. On update zero
+	// clears it.
+	Estimate     *float64 `json:"estimate,omitempty"`
+	EstimateUnit string   `json:"estimate_unit,omitempty"`
+	SprintID     uint     `json:"sprint_id,omitempty"`
 }
 
 func protoToTaskData(task *pb.Task) TaskData {
-	time := task.CreationTime.AsTime()
+	creationTime := task.CreationTime.AsTime()
+	updateTime := task.UpdateTime.AsTime()
+	var estimate *float64
+	if task.Estimate != 0 {
+		estimate = &task.Estimate
+	}
 	return TaskData{
 		ID:           uint(task.Id),
 		Author:       task.Author,
 		Title:        task.Title,
 		Content:      task.Content,
-		CreationTime: &time,
+		CreationTime: &creationTime,
+		UpdateTime:   &updateTime,
+		Project:      task.Project,
+		Status:       task.Status,
+		Fields:       task.Fields,
+
+		Checklist:         protoToChecklist(task.Checklist),
+		ChecklistProgress: protoToChecklistProgress(task),
+
+		Links:      protoToTaskLinks(task.Links),
+		MergedInto: uint(task.MergedInto),
+
+		Estimate:     estimate,
+		EstimateUnit: task.EstimateUnit,
+		SprintID:     uint(task.SprintId),
+	}
+}
+
+func (t *TaskData) estimate() float64 {
+	if t.Estimate == nil {
+		return 0
 	}
+	return *t.Estimate
 }
 
 func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
-	login, ok := s.auth.CheckAuth(w, r)
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
 	if !ok {
 		return
 	}
 
 	var taskData TaskData
 	if err := json.NewDecoder(r.Body).Decode(&taskData); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse body: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
 		return
 	}
 	defer r.Body.Close()
 
-	resp, err := s.taskMan.CreateTask(context.Background(), &pb.CreateTaskRequest{
-		Author:  login,
-		Title:   taskData.Title,
-		Content: taskData.Content,
+	if err := s.checkUserFields(r.Context(), taskData.Project, taskData.Fields); err != nil {
+		apierror.WriteGRPC(w, err, "Bad fields")
+		return
+	}
+
+	resp, err := s.taskMan.CreateTask(r.Context(), &pb.CreateTaskRequest{
+		Author:       login,
+		Title:        taskData.Title,
+		Content:      taskData.Content,
+		Project:      taskData.Project,
+		Fields:       taskData.Fields,
+		Estimate:     taskData.estimate(),
+		EstimateUnit: taskData.EstimateUnit,
 	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "Can not create new task: %v", err)
+		apierror.WriteGRPC(w, err, "Can not create new task")
 		return
 	}
 
 	json.NewEncoder(w).Encode(TaskData{ID: uint(resp.Id)})
-	w.WriteHeader(http.StatusOK)
 }
 
 func (s *Server) getTask(w http.ResponseWriter, r *http.Request) {
-	login, ok := s.auth.CheckAuth(w, r)
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksRead)
 	if !ok {
 		return
 	}
 
 	id, err := strconv.Atoi(r.URL.Query().Get("id"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse query: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
 		return
 	}
 
-	resp, err := s.taskMan.GetTask(context.Background(), &pb.GetTaskRequest{
+	resp, err := s.taskMan.GetTask(r.Context(), &pb.GetTaskRequest{
 		Id:     uint32(id),
 		Author: login,
 	})
 	if err != nil {
-		if strings.Contains(err.Error(), "permission denied") {
-			w.WriteHeader(http.StatusForbidden)
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "Can not get task: %v", err)
+		apierror.WriteGRPC(w, err, "Can not get task")
 		return
 	}
 
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "    ")
 	encoder.Encode(protoToTaskData(resp))
-	w.WriteHeader(http.StatusOK)
 }
 
 func (s *Server) updateTaskInfo(w http.ResponseWriter, r *http.Request) {
-	login, ok := s.auth.CheckAuth(w, r) // TODO: check login
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite) // TODO: check login
 	if !ok {
 		return
 	}
 
 	var taskData TaskData
 	if err := json.NewDecoder(r.Body).Decode(&taskData); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse body: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
 		return
 	}
 	defer r.Body.Close()
 
 	if taskData.ID == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "ID is required")
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "ID is required")
 		return
 	}
 
-	_, err := s.taskMan.UpdateTask(context.Background(), &pb.UpdateTaskRequest{
-		Author:  login,
-		Id:      uint32(taskData.ID),
-		Title:   taskData.Title,
-		Content: taskData.Content,
+	if len(taskData.Fields) > 0 {
+		task, err := s.taskMan.GetTask(r.Context(), &pb.GetTaskRequest{
+			Id:     uint32(taskData.ID),
+			Author: login,
+		})
+		if err != nil {
+			apierror.WriteGRPC(w, err, "Can not update task")
+			return
+		}
+		if err := s.checkUserFields(r.Context(), task.Project, taskData.Fields); err != nil {
+			apierror.WriteGRPC(w, err, "Bad fields")
+			return
+		}
+	}
+
+	_, err := s.taskMan.UpdateTask(r.Context(), &pb.UpdateTaskRequest{
+		Author:       login,
+		Id:           uint32(taskData.ID),
+		Title:        taskData.Title,
+		Content:      taskData.Content,
+		Status:       taskData.Status,
+		Fields:       taskData.Fields,
+		Estimate:     taskData.Estimate,
+		EstimateUnit: taskData.EstimateUnit,
 	})
 	if err != nil {
-		if strings.Contains(err.Error(), "permission denied") {
-			w.WriteHeader(http.StatusForbidden)
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "Can not update task: %v", err)
+		apierror.WriteGRPC(w, err, "Can not update task")
 		return
 	}
 
@@ -325,28 +535,23 @@ func (s *Server) updateTaskInfo(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request) {
-	login, ok := s.auth.CheckAuth(w, r)
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
 	if !ok {
 		return
 	}
 
 	id, err := strconv.Atoi(r.URL.Query().Get("id"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse query: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
 		return
 	}
 
-	_, err = s.taskMan.DeleteTask(context.Background(), &pb.DeleteTaskRequest{
+	_, err = s.taskMan.DeleteTask(r.Context(), &pb.DeleteTaskRequest{
 		Id:     uint32(id),
 		Author: login,
 	})
 	if err != nil {
-		if strings.Contains(err.Error(), "permission denied") {
-			w.WriteHeader(http.StatusForbidden)
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "Can not delete task: %v", err)
+		apierror.WriteGRPC(w, err, "Can not delete task")
 		return
 	}
 
@@ -354,7 +559,7 @@ func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) getTasks(w http.ResponseWriter, r *http.Request) {
-	_, ok := s.auth.CheckAuth(w, r)
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksRead)
 	if !ok {
 		return
 	}
@@ -365,40 +570,60 @@ func (s *Server) getTasks(w http.ResponseWriter, r *http.Request) {
 	}
 	batchSize, err := strconv.Atoi(batchSizeStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse query: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
 		return
 	}
 
 	offsetStr := r.URL.Query().Get("offset")
-	if batchSizeStr == "" {
-		batchSizeStr = "0"
+	if offsetStr == "" {
+		offsetStr = "0"
 	}
 	offset, err := strconv.Atoi(offsetStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse query: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
 		return
 	}
 
-	tasksResp, err := s.taskMan.GetTasks(context.Background(), &pb.GetTasksRequest{
+	filters, err := parseFieldFilters(r.URL.Query()["filter"])
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
+		return
+	}
+	sortField, sortDesc := parseSort(r.URL.Query().Get("sort"))
+	query, byDefault, err := s.taskQuery(login, r.URL.Query())
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not get view")
+		return
+	}
+
+	req := &pb.GetTasksRequest{
 		BatchSize: int32(batchSize),
 		Offset:    uint32(offset),
-	})
+		Author:    r.URL.Query().Get("author"),
+		Project:   r.URL.Query().Get("project"),
+		Filters:   filters,
+		SortField: sortField,
+		SortDesc:  sortDesc,
+		Query:     query,
+	}
+	tasksResp, complete, err := s.listTasks(r.Context(), req, byDefault)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "Can not delete task: %v", err)
+		apierror.WriteGRPC(w, err, "Can not get tasks")
 		return
 	}
 
 	type TasksOffset struct {
 		Tasks  []TaskData `json:"tasks"`
 		Offset uint32     `json:"offset"`
+		// StatsUnavailable means tasks were sorted with likes and views
+		// taken as zero.
+		StatsUnavailable bool `json:"stats_unavailable,omitempty"`
 	}
 
 	tasksOffset := TasksOffset{
-		Offset: tasksResp.Offset,
-		Tasks:  make([]TaskData, 0, len(tasksResp.Tasks)),
+		Offset:           tasksResp.Offset,
+		Tasks:            make([]TaskData, 0, len(tasksResp.Tasks)),
+		StatsUnavailable: !complete,
 	}
 
 	for _, task := range tasksResp.Tasks {
@@ -408,45 +633,109 @@ func (s *Server) getTasks(w http.ResponseWriter, r *http.Request) {
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "    ")
 	encoder.Encode(tasksOffset)
-	w.WriteHeader(http.StatusOK)
+}
+
+// listTasks gets tasks of req with likes and views added when needed. If
+// the query is the one of the default view and it broke, e.g. a custom
+// field it uses was deleted, the view is skipped: the user still gets all
+// tasks and can fix the view.
+func (s *Server) listTasks(ctx context.Context, req *pb.GetTasksRequest, byDefault bool) (resp *pb.GetTasksReponse, complete bool, err error) {
+	complete, err = s.addCounts(ctx, req)
+	if err == nil {
+		resp, err = s.taskMan.GetTasks(ctx, req)
+	}
+	if byDefault && status.Code(err) == codes.InvalidArgument {
+		slog.WarnContext(ctx, "Skipping broken default view.",
+			slog.String("request_id", middleware.GetRequestID(ctx)),
+			slog.String("error", err.Error()))
+		req.Query, req.Likes, req.Views = "", nil, nil
+		return s.listTasks(ctx, req, false)
+	}
+	return resp, complete, err
+}
+
+// errCountsUnavailable is returned when the query filters by likes or views
+// and statistics service does not answer. Taking them as zero would return
+// wrong tasks, not just fewer details.
+var errCountsUnavailable = status.Error(codes.Unavailable, "likes and views are unavailable, try the query without them later")
+
+// addCounts passes likes and views to tasks manager when the query filters
+// or sorts by them. Tasks manager tells that from the parsed query and names
+// the tasks the rest of the request matches, so only their counts are
+// fetched. If statistics service does not answer, a query that only sorts
+// by counts runs with them taken as zero and complete is false; one that
+// filters by them fails with errCountsUnavailable.
+func (s *Server) addCounts(ctx context.Context, req *pb.GetTasksRequest) (complete bool, err error) {
+	// Without these words the query can not use counts, which spares a call.
+	if !strings.Contains(req.Query, "likes") && !strings.Contains(req.Query, "views") {
+		return true, nil
+	}
+
+	candidates, err := s.taskMan.GetCountCandidates(ctx, req)
+	if err != nil {
+		return false, err
+	}
+	if !candidates.UsesCounts || len(candidates.TaskIds) == 0 {
+		return true, nil
+	}
+
+	stats, err := s.statMan.GetStatsForTasks(ctx, &statpb.GetStatsForTasksRequest{TaskIds: candidates.TaskIds})
+	if err != nil {
+		if candidates.FiltersByCounts {
+			return false, errCountsUnavailable
+		}
+		slog.WarnContext(ctx, "Sorting tasks without stats.",
+			slog.String("request_id", middleware.GetRequestID(ctx)),
+			slog.String("error", err.Error()))
+		return false, nil
+	}
+	req.Likes = stats.Likes
+	req.Views = stats.Views
+	return true, nil
 }
 
 func (s *Server) addLike(w http.ResponseWriter, r *http.Request) {
-	login, ok := s.auth.CheckAuth(w, r)
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
 	if !ok {
 		return
 	}
 
 	id, err := strconv.Atoi(r.URL.Query().Get("id"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse query: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
 		return
 	}
 
-	s.broker.SendLike(broker.Statistic{
+	err = s.broker.SendLike(broker.Statistic{
 		Login:  login,
 		TaskID: uint(id),
 	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not send like: %v", err)
+		return
+	}
 }
 
 func (s *Server) addView(w http.ResponseWriter, r *http.Request) {
-	login, ok := s.auth.CheckAuth(w, r)
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
 	if !ok {
 		return
 	}
 
 	id, err := strconv.Atoi(r.URL.Query().Get("id"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse query: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
 		return
 	}
 
-	s.broker.SendView(broker.Statistic{
+	err = s.broker.SendView(broker.Statistic{
 		Login:  login,
 		TaskID: uint(id),
 	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not send view: %v", err)
+		return
+	}
 }
 
 type taskStats struct {
@@ -456,19 +745,21 @@ type taskStats struct {
 }
 
 func (s *Server) taskStats(w http.ResponseWriter, r *http.Request) {
+	if !s.auth.CheckPublic(w, r, auth.ScopeStatsRead) {
+		return
+	}
+
 	id, err := strconv.Atoi(r.URL.Query().Get("id"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Can not parse query: %v", err)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
 		return
 	}
 
-	result, err := s.statMan.GetTaskStats(context.Background(), &statpb.GetTaskStatsRequest{
+	result, err := s.statMan.GetTaskStats(r.Context(), &statpb.GetTaskStatsRequest{
 		Id: uint32(id),
 	})
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		fmt.Println("error getting task stats: ", err)
+		apierror.WriteGRPC(w, err, "Can not get task stats")
 		return
 	}
 
@@ -481,11 +772,10 @@ func (s *Server) taskStats(w http.ResponseWriter, r *http.Request) {
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "    ")
 	encoder.Encode(stat)
-	w.WriteHeader(http.StatusOK)
 }
 
-func (s *Server) getAuthors() (map[uint32]string, error) {
-	response, err := s.taskMan.GetTasks(context.Background(), &pb.GetTasksRequest{
+func (s *Server) getAuthors(ctx context.Context) (map[uint32]string, error) {
+	response, err := s.taskMan.GetTasks(ctx, &pb.GetTasksRequest{
 		BatchSize: -1,
 		Offset:    0,
 	})
@@ -501,6 +791,10 @@ func (s *Server) getAuthors() (map[uint32]string, error) {
 }
 
 func (s *Server) topTasks(w http.ResponseWriter, r *http.Request) {
+	if !s.auth.CheckPublic(w, r, auth.ScopeStatsRead) {
+		return
+	}
+
 	var sort statpb.SortBy
 	sortBy := r.URL.Query().Get("sort_by")
 	if sortBy == "likes" {
@@ -508,23 +802,23 @@ func (s *Server) topTasks(w http.ResponseWriter, r *http.Request) {
 	} else if sortBy == "views" {
 		sort = statpb.SortBy_views
 	} else {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Undefind sort_by %s. Expected 'likes or 'views'", sortBy)
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Undefind sort_by %s. Expected 'likes or 'views'", sortBy)
 		return
 	}
 
-	taksksAuthors, err := s.getAuthors()
+	taksksAuthors, err := s.getAuthors(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		apierror.WriteGRPC(w, err, "Can not get tasks authors")
 		return
 	}
 
-	result, err := s.statMan.GetTopTasks(context.Background(), &statpb.GetTopTasksRequest{
+	result, err := s.statMan.GetTopTasks(r.Context(), &statpb.GetTopTasksRequest{
 		Sort:    sort,
 		Authors: taksksAuthors,
 	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		apierror.WriteGRPC(w, err, "Can not get top tasks")
+		return
 	}
 
 	encoder := json.NewEncoder(w)
@@ -532,21 +826,25 @@ func (s *Server) topTasks(w http.ResponseWriter, r *http.Request) {
 	for _, task := range result.Tasks {
 		encoder.Encode(task)
 	}
-	w.WriteHeader(http.StatusOK)
 }
 
 func (s *Server) topUsers(w http.ResponseWriter, r *http.Request) {
-	taksksAuthors, err := s.getAuthors()
+	if !s.auth.CheckPublic(w, r, auth.ScopeStatsRead) {
+		return
+	}
+
+	taksksAuthors, err := s.getAuthors(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		apierror.WriteGRPC(w, err, "Can not get tasks authors")
 		return
 	}
 
-	result, err := s.statMan.GetTopUsers(context.Background(), &statpb.GetTopUsersRequest{
+	result, err := s.statMan.GetTopUsers(r.Context(), &statpb.GetTopUsersRequest{
 		Authors: taksksAuthors,
 	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		apierror.WriteGRPC(w, err, "Can not get top users")
+		return
 	}
 
 	encoder := json.NewEncoder(w)
@@ -554,5 +852,4 @@ func (s *Server) topUsers(w http.ResponseWriter, r *http.Request) {
 	for _, author := range result.Authors {
 		encoder.Encode(author)
 	}
-	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	pb "userservice/proto"
+	"userservice/src/auth"
+	"userservice/src/database"
+	"userservice/src/throttle"
+)
+
+func TestFieldValuesUnmarshal(t *testing.T) {
+	var task TaskData
+	err := json.Unmarshal([]byte(`{"fields": {"story_points": 3, "customer": "ACME", "reviewer": null}}`), &task)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	target := FieldValues{"story_points": "3", "customer": "ACME", "reviewer": ""}
+	if len(task.Fields) != len(target) {
+		t.Fatalf("expected: %#v; got: %#v", target, task.Fields)
+	}
+	for name, value := range target {
+		if task.Fields[name] != value {
+			t.Errorf("field %s: expected %q, got %q", name, value, task.Fields[name])
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"fields": {"flag": true}}`), &task); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestParseFieldFilters(t *testing.T) {
+	filters, err := parseFieldFilters([]string{"severity=high", "story_points>=3", "customer!=ACME"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	target := [][3]string{
+		{"severity", "=", "high"},
+		{"story_points", ">=", "3"},
+		{"customer", "!=", "ACME"},
+	}
+	for i, filter := range filters {
+		if filter.Field != target[i][0] || filter.Op != target[i][1] || filter.Value != target[i][2] {
+			t.Errorf("expected: %v; got: %v", target[i], filter)
+		}
+	}
+
+	if _, err := parseFieldFilters([]string{"no operator"}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestHasTaskFilters(t *testing.T) {
+	tests := map[string]bool{
+		"batch_size=10&offset=20": false,
+		"batch_size=10&q=":        true,
+		"view=3":                  true,
+		"filter=severity=high":    true,
+		"sort=-story_points":      true,
+	}
+	for raw, target := range tests {
+		query, err := url.ParseQuery(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hasTaskFilters(query); got != target {
+			t.Errorf("%s: expected %v, got %v", raw, target, got)
+		}
+	}
+}
+
+func TestJoinQueries(t *testing.T) {
+	tests := [][3]string{
+		{"status:open", "", "status:open"},
+		{"", "author:kek", "author:kek"},
+		{"status:open sort:-likes ", " author:kek", "status:open sort:-likes author:kek"},
+	}
+	for _, test := range tests {
+		if got := joinQueries(test[0], test[1]); got != test[2] {
+			t.Errorf("expected %q, got %q", test[2], got)
+		}
+	}
+}
+
+func TestProtoToTaskDataChecklist(t *testing.T) {
+	task := protoToTaskData(&pb.Task{
+		Id: 1,
+		Checklist: []*pb.ChecklistItem{
+			{Id: 3, Position: 0, Text: "write", Done: true},
+			{Id: 5, Position: 1, Text: "review"},
+		},
+		ChecklistDone:  1,
+		ChecklistTotal: 2,
+	})
+
+	if task.ChecklistProgress == nil || *task.ChecklistProgress != (ChecklistProgress{Done: 1, Total: 2}) {
+		t.Errorf("expected progress 1/2, got %v", task.ChecklistProgress)
+	}
+	if len(task.Checklist) != 2 || task.Checklist[1].ID != 5 || *task.Checklist[1].Position != 1 {
+		t.Errorf("unexpected checklist: %v", task.Checklist)
+	}
+
+	if task := protoToTaskData(&pb.Task{Id: 2}); task.ChecklistProgress != nil {
+		t.Errorf("expected no progress without checklist, got %v", task.ChecklistProgress)
+	}
+}
+
+func TestProtoToTaskDataLinks(t *testing.T) {
+	task := protoToTaskData(&pb.Task{
+		Id:         5,
+		MergedInto: 3,
+		Links:      []*pb.TaskLink{{Id: 1, Type: "duplicates", SourceId: 5, TargetId: 3}},
+	})
+
+	target := TaskLink{ID: 1, Type: "duplicates", SourceID: 5, TargetID: 3}
+	if task.MergedInto != 3 || len(task.Links) != 1 || task.Links[0] != target {
+		t.Errorf("expected merged into 3 with link %v, got %v %v", target, task.MergedInto, task.Links)
+	}
+}
+
+func TestTaskDataEstimate(t *testing.T) {
+	var task TaskData
+	if err := json.Unmarshal([]byte(`{"estimate": 0}`), &task); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if task.Estimate == nil || *task.Estimate != 0 {
+		t.Errorf("expected explicit zero estimate to clear it, got %v", task.Estimate)
+	}
+
+	if data := protoToTaskData(&pb.Task{Id: 1}); data.Estimate != nil {
+		t.Errorf("expected no estimate, got %v", *data.Estimate)
+	}
+	data := protoToTaskData(&pb.Task{Id: 1, Estimate: 5, EstimateUnit: "hours", SprintId: 2})
+	if data.Estimate == nil || *data.Estimate != 5 || data.EstimateUnit != "hours" || data.SprintID != 2 {
+		t.Errorf("unexpected estimate: %v %q sprint %d", data.Estimate, data.EstimateUnit, data.SprintID)
+	}
+}
+
+func TestRefreshTokenSource(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(`{"refresh_token": "body"}`))
+	r.AddCookie(&http.Cookie{Name: auth.RefreshCookie, Value: "cookie"})
+	if token, err := refreshToken(r); err != nil || token != "body" {
+		t.Errorf("expected body token, got %q, %v", token, err)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/refresh", nil)
+	r.AddCookie(&http.Cookie{Name: auth.RefreshCookie, Value: "cookie"})
+	if token, err := refreshToken(r); err != nil || token != "cookie" {
+		t.Errorf("expected cookie token, got %q, %v", token, err)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(`{`))
+	if _, err := refreshToken(r); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestThrottled(t *testing.T) {
+	limiter := throttle.New(throttle.NewMemoryStore(), throttle.Config{
+		FreeFailures:    1,
+		BaseDelay:       1500 * time.Millisecond,
+		MaxDelay:        time.Minute,
+		LockoutFailures: 3,
+		LockoutDuration: time.Minute,
+		Window:          time.Hour,
+	})
+	now := time.Now()
+
+	w := httptest.NewRecorder()
+	if throttled(w, limiter, loginKey("kek"), now) {
+		t.Fatal("expected no throttling without failures")
+	}
+
+	limiter.Fail(now, loginKey("kek"), loginKey("kek"))
+	w = httptest.NewRecorder()
+	if !throttled(w, limiter, loginKey("kek"), now) {
+		t.Fatal("expected throttling")
+	}
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("expected 429 with Retry-After 2, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	limiter.Fail(now, loginKey("kek"))
+	w = httptest.NewRecorder()
+	throttled(w, limiter, loginKey("kek"), now)
+	if w.Header().Get("Retry-After") != "60" || !strings.Contains(w.Body.String(), "locked") {
+		t.Errorf("expected lockout, got %q %s", w.Header().Get("Retry-After"), w.Body.String())
+	}
+}
+
+func TestPageParams(t *testing.T) {
+	offset, limit, err := pageParams(url.Values{})
+	if err != nil || offset != 0 || limit != defaultPageSize {
+		t.Errorf("expected defaults, got %d %d %v", offset, limit, err)
+	}
+	offset, limit, err = pageParams(url.Values{"offset": {"20"}, "batch_size": {"10"}})
+	if err != nil || offset != 20 || limit != 10 {
+		t.Errorf("expected 20 10, got %d %d %v", offset, limit, err)
+	}
+	for _, raw := range []string{"offset=-1", "offset=x", "batch_size=0", "batch_size=501"} {
+		query, _ := url.ParseQuery(raw)
+		if _, _, err := pageParams(query); err == nil {
+			t.Errorf("%s: expected error", raw)
+		}
+	}
+}
+
+func TestDecodeAdminUser(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/admin/users/disable", strings.NewReader(`{"login": "kek"}`))
+	if login, ok := decodeAdminUser(w, r, "admin"); !ok || login != "kek" {
+		t.Errorf("expected kek, got %q", login)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/admin/users/disable", strings.NewReader(`{"login": "admin"}`))
+	if _, ok := decodeAdminUser(w, r, "admin"); ok || w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for the admin itself, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/admin/users/disable", strings.NewReader(`{}`))
+	if _, ok := decodeAdminUser(w, r, "admin"); ok || w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without login, got %d", w.Code)
+	}
+}
+
+func TestUserInfoJSON(t *testing.T) {
+	info := UserInfo{
+		Login:    "kek",
+		UserData: database.UserData{Name: "Kek", Mail: "kek@mail.ru"},
+		Privacy:  database.Privacy{Name: true},
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("expected a single JSON object, got %s: %v", data, err)
+	}
+	if decoded["login"] != "kek" || decoded["name"] != "Kek" || decoded["mail"] != "kek@mail.ru" {
+		t.Errorf("expected flat user data, got %s", data)
+	}
+	if privacy, _ := decoded["privacy"].(map[string]any); privacy["name"] != true || privacy["mail"] != false {
+		t.Errorf("unexpected privacy: %s", data)
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+	"userservice/src/database"
+)
+
+// SessionInfo marks the session the request came from.
+type SessionInfo struct {
+	database.Session
+	Current bool `json:"current"`
+}
+
+func clientOf(r *http.Request) auth.Client {
+	return auth.Client{IP: clientIP(r), UserAgent: r.UserAgent()}
+}
+
+// getSessions lists where the user is logged in. Like logout, it needs a
+// session token.
+func (s *Server) getSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.auth.CheckAuthClaims(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := s.db.GetSessions(claims.Login, time.Now())
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not get sessions: %v", err)
+		return
+	}
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		infos = append(infos, SessionInfo{Session: session, Current: session.ID == claims.SessionID})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(infos)
+}
+
+// revokeSession ends one session; its refresh and access tokens stop
+// working at once. Revoking the current session is a logout.
+func (s *Server) revokeSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.auth.CheckAuthClaims(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "id is required")
+		return
+	}
+	err := s.db.RevokeSession(claims.Login, id)
+	if errors.Is(err, database.ErrSessionNotFound) {
+		apierror.Write(w, http.StatusNotFound, apierror.NotFound, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not revoke session: %v", err)
+		return
+	}
+
+	if id == claims.SessionID {
+		clearTokens(w)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
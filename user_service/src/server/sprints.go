@@ -0,0 +1,239 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	pb "userservice/proto"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+)
+
+type Sprint struct {
+	ID              uint       `json:"id,omitempty"`
+	Author          string     `json:"author,omitempty"`
+	Project         string     `json:"project,omitempty"`
+	Name            string     `json:"name"`
+	State           string     `json:"state,omitempty"`
+	StartDate       string     `json:"start_date"`
+	EndDate         string     `json:"end_date,omitempty"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	ClosedAt        *time.Time `json:"closed_at,omitempty"`
+	CommittedPoints float64    `json:"committed_points"`
+	CommittedHours  float64    `json:"committed_hours"`
+	CompletedPoints float64    `json:"completed_points"`
+	CompletedHours  float64    `json:"completed_hours"`
+}
+
+type SprintRef struct {
+	ID           uint `json:"id"`
+	NextSprintID uint `json:"next_sprint_id,omitempty"`
+}
+
+type TaskSprint struct {
+	TaskID   uint `json:"task_id"`
+	SprintID uint `json:"sprint_id"`
+}
+
+type Velocity struct {
+	Sprints       []Sprint `json:"sprints"`
+	AveragePoints float64  `json:"average_points"`
+	AverageHours  float64  `json:"average_hours"`
+}
+
+func protoToSprint(sprint *pb.Sprint) Sprint {
+	result := Sprint{
+		ID:              uint(sprint.Id),
+		Author:          sprint.Author,
+		Project:         sprint.Project,
+		Name:            sprint.Name,
+		State:           sprint.State,
+		StartDate:       sprint.StartDate,
+		EndDate:         sprint.EndDate,
+		CommittedPoints: sprint.CommittedPoints,
+		CommittedHours:  sprint.CommittedHours,
+		CompletedPoints: sprint.CompletedPoints,
+		CompletedHours:  sprint.CompletedHours,
+	}
+	if sprint.StartedAt != nil {
+		startedAt := sprint.StartedAt.AsTime()
+		result.StartedAt = &startedAt
+	}
+	if sprint.ClosedAt != nil {
+		closedAt := sprint.ClosedAt.AsTime()
+		result.ClosedAt = &closedAt
+	}
+	return result
+}
+
+func protoToSprints(sprints []*pb.Sprint) []Sprint {
+	result := make([]Sprint, 0, len(sprints))
+	for _, sprint := range sprints {
+		result = append(result, protoToSprint(sprint))
+	}
+	return result
+}
+
+func (s *Server) createSprint(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	var sprint Sprint
+	if err := json.NewDecoder(r.Body).Decode(&sprint); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	resp, err := s.taskMan.CreateSprint(r.Context(), &pb.CreateSprintRequest{
+		Author:    login,
+		Project:   sprint.Project,
+		Name:      sprint.Name,
+		StartDate: sprint.StartDate,
+		EndDate:   sprint.EndDate,
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not create sprint")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(protoToSprint(resp))
+}
+
+func (s *Server) getSprints(w http.ResponseWriter, r *http.Request) {
+	_, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksRead)
+	if !ok {
+		return
+	}
+
+	resp, err := s.taskMan.GetSprints(r.Context(), &pb.GetSprintsRequest{
+		Project: r.URL.Query().Get("project"),
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not get sprints")
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(protoToSprints(resp.Sprints))
+}
+
+func (s *Server) startSprint(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	var ref SprintRef
+	if err := json.NewDecoder(r.Body).Decode(&ref); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	_, err := s.taskMan.StartSprint(r.Context(), &pb.StartSprintRequest{
+		Id:     uint32(ref.ID),
+		Author: login,
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not start sprint")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) closeSprint(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	var ref SprintRef
+	if err := json.NewDecoder(r.Body).Decode(&ref); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	resp, err := s.taskMan.CloseSprint(r.Context(), &pb.CloseSprintRequest{
+		Id:           uint32(ref.ID),
+		Author:       login,
+		NextSprintId: uint32(ref.NextSprintID),
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not close sprint")
+		return
+	}
+
+	type MovedTasks struct {
+		MovedTasks uint32 `json:"moved_tasks"`
+	}
+	json.NewEncoder(w).Encode(MovedTasks{MovedTasks: resp.MovedTasks})
+}
+
+func (s *Server) planTask(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	var plan TaskSprint
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	_, err := s.taskMan.PlanTask(r.Context(), &pb.PlanTaskRequest{
+		TaskId:   uint32(plan.TaskID),
+		Author:   login,
+		SprintId: uint32(plan.SprintID),
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not plan task")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getVelocity(w http.ResponseWriter, r *http.Request) {
+	_, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksRead)
+	if !ok {
+		return
+	}
+
+	var sprints int
+	if raw := r.URL.Query().Get("sprints"); raw != "" {
+		var err error
+		sprints, err = strconv.Atoi(raw)
+		if err != nil || sprints < 0 {
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: bad sprints %q", raw)
+			return
+		}
+	}
+
+	resp, err := s.taskMan.GetVelocity(r.Context(), &pb.GetVelocityRequest{
+		Project: r.URL.Query().Get("project"),
+		Sprints: uint32(sprints),
+	})
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not get velocity")
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(Velocity{
+		Sprints:       protoToSprints(resp.Sprints),
+		AveragePoints: resp.AveragePoints,
+		AverageHours:  resp.AverageHours,
+	})
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"time"
+	"userservice/src/apierror"
+	"userservice/src/throttle"
+)
+
+// ipConfig is looser than the per-login one: many users may share an IP
+// behind NAT.
+var ipConfig = throttle.Config{
+	FreeFailures:    20,
+	BaseDelay:       time.Second,
+	MaxDelay:        5 * time.Minute,
+	LockoutFailures: 100,
+	LockoutDuration: 15 * time.Minute,
+	Window:          time.Hour,
+}
+
+// registerConfig counts every registration, not only failed ones.
+var registerConfig = throttle.Config{
+	FreeFailures:    5,
+	BaseDelay:       10 * time.Second,
+	MaxDelay:        10 * time.Minute,
+	LockoutFailures: 20,
+	LockoutDuration: time.Hour,
+	Window:          time.Hour,
+}
+
+// maxCodeAttempts is how many wrong second factors one pending login
+// token takes before it is spent.
+const maxCodeAttempts = 5
+
+type Unlock struct {
+	Login string `json:"login"`
+	IP    string `json:"ip"`
+}
+
+func loginKey(login string) string {
+	return "login:" + login
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// pendingKey counts wrong codes against one pending login token.
+func pendingKey(id string) string {
+	return "pending:" + id
+}
+
+func registerKey(ip string) string {
+	return "register:" + ip
+}
+
+// clientIP is the address of the peer, or of the client when the request
+// came through a trusted proxy (see middleware.Proxies.RealIP).
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// throttled answers 429 with Retry-After if key has to wait.
+func throttled(w http.ResponseWriter, limiter *throttle.Limiter, key string, now time.Time) bool {
+	wait, err := limiter.Check(now, key)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not check attempts: %v", err)
+		return true
+	}
+	if wait <= 0 {
+		return false
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", fmt.Sprint(seconds))
+	if locked, _ := limiter.Locked(key, now); locked {
+		apierror.Write(w, http.StatusTooManyRequests, apierror.ResourceExhausted, "Too many failed attempts, locked for %d seconds", seconds)
+	} else {
+		apierror.Write(w, http.StatusTooManyRequests, apierror.ResourceExhausted, "Too many attempts, retry in %d seconds", seconds)
+	}
+	return true
+}
+
+// failLogin records a failed login for the login and the IP. An error
+// only gets logged: the request fails anyway.
+func (s *Server) failLogin(r *http.Request, login string, now time.Time) {
+	if err := s.loginLimiter.Fail(now, loginKey(login)); err != nil {
+		slog.Error("Can not record failed login.", slog.String("login", login), slog.String("error", err.Error()))
+	}
+	if err := s.ipLimiter.Fail(now, ipKey(clientIP(r))); err != nil {
+		slog.Error("Can not record failed login.", slog.String("ip", clientIP(r)), slog.String("error", err.Error()))
+	}
+}
+
+// failPendingLogin records a wrong second factor. After maxCodeAttempts of
+// them the pending token is spent, so guessing on needs the password again.
+func (s *Server) failPendingLogin(token, id string, now time.Time) (cancelled bool) {
+	failures, err := s.loginLimiter.FailCount(now, pendingKey(id))
+	if err != nil {
+		slog.Error("Can not record failed second factor.", slog.String("pending_login", id), slog.String("error", err.Error()))
+		return false
+	}
+	if failures < maxCodeAttempts {
+		return false
+	}
+	if err := s.auth.CancelPendingLogin(token); err != nil {
+		slog.Error("Can not cancel pending login.", slog.String("pending_login", id), slog.String("error", err.Error()))
+		return false
+	}
+	if err := s.loginLimiter.Reset(pendingKey(id)); err != nil {
+		slog.Error("Can not reset attempts.", slog.String("pending_login", id), slog.String("error", err.Error()))
+	}
+	return true
+}
+
+func (s *Server) unlock(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.checkAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req Unlock
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+	if req.Login == "" && req.IP == "" {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Login or ip is required")
+		return
+	}
+
+	if req.Login != "" {
+		if err := s.loginLimiter.Reset(loginKey(req.Login)); err != nil {
+			apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not unlock: %v", err)
+			return
+		}
+	}
+	if req.IP != "" {
+		if err := s.ipLimiter.Reset(ipKey(req.IP)); err != nil {
+			apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not unlock: %v", err)
+			return
+		}
+	}
+	var details string
+	if req.IP != "" {
+		details = "ip " + req.IP
+	}
+	if !s.audit(w, r, admin, "unlock", req.Login, details) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
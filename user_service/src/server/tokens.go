@@ -0,0 +1,181 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+	"userservice/src/database"
+)
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RevokeRequest struct {
+	Token string `json:"token"`
+}
+
+// writeTokens sets both tokens as cookies for browsers and returns them in
+// the body for other clients.
+func writeTokens(w http.ResponseWriter, tokens *auth.TokenPair) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.AccessCookie,
+		Value:    tokens.AccessToken,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.RefreshCookie,
+		Value:    tokens.RefreshToken,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// issueTokens starts a session after the user proved who they are by
+// method and records the login. Disabled users are refused.
+func (s *Server) issueTokens(w http.ResponseWriter, r *http.Request, login, method string) {
+	tokens, err := s.auth.IssueTokens(login, clientOf(r))
+	if errors.Is(err, database.ErrUserDisabled) {
+		s.recordLogin(r, login, method, false)
+		apierror.Write(w, http.StatusForbidden, apierror.PermissionDenied, "Account is disabled")
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not generate token: %v", err)
+		return
+	}
+	s.recordLogin(r, login, method, true)
+	writeTokens(w, tokens)
+}
+
+// recordLogin adds a login attempt to the user's login history. An error
+// only gets logged, like in failLogin.
+func (s *Server) recordLogin(r *http.Request, login, method string, success bool) {
+	err := s.db.AddLoginEvent(&database.LoginEvent{
+		Login:     login,
+		Method:    method,
+		Success:   success,
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		slog.Error("Can not record login.", slog.String("login", login), slog.String("error", err.Error()))
+	}
+}
+
+func clearTokens(w http.ResponseWriter) {
+	for _, name := range []string{auth.AccessCookie, auth.RefreshCookie} {
+		http.SetCookie(w, &http.Cookie{Name: name, Path: "/", MaxAge: -1, HttpOnly: true})
+	}
+}
+
+// refreshToken reads the refresh token from the body or, if the body is
+// empty, from the cookie.
+func refreshToken(r *http.Request) (string, error) {
+	var req RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return "", err
+		}
+		defer r.Body.Close()
+	}
+	if req.RefreshToken != "" {
+		return req.RefreshToken, nil
+	}
+	if cookie, err := r.Cookie(auth.RefreshCookie); err == nil {
+		return cookie.Value, nil
+	}
+	return "", nil
+}
+
+func (s *Server) refresh(w http.ResponseWriter, r *http.Request) {
+	refresh, err := refreshToken(r)
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	if refresh == "" {
+		apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "No refresh token")
+		return
+	}
+
+	tokens, err := s.auth.Refresh(refresh, clientOf(r))
+	if errors.Is(err, database.ErrTokenInvalid) || errors.Is(err, database.ErrTokenReused) {
+		clearTokens(w)
+		apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not refresh token: %v", err)
+		return
+	}
+
+	writeTokens(w, tokens)
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.auth.CheckAuthClaims(w, r)
+	if !ok {
+		return
+	}
+
+	refresh, err := refreshToken(r)
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+
+	if err := s.auth.Logout(claims, refresh); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not log out: %v", err)
+		return
+	}
+
+	clearTokens(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) logoutAll(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.auth.LogoutAll(login); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not log out: %v", err)
+		return
+	}
+
+	clearTokens(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) revoke(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	var req RevokeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	err := s.auth.RevokeToken(login, req.Token)
+	if errors.Is(err, auth.ErrForeignToken) {
+		apierror.Write(w, http.StatusForbidden, apierror.PermissionDenied, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not revoke token: %v", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
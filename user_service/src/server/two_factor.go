@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+	"userservice/src/database"
+)
+
+// PendingLogin is the answer to /login when the second factor is required.
+type PendingLogin struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	TwoFactorToken    string `json:"two_factor_token"`
+	ExpiresIn         int    `json:"expires_in"`
+}
+
+type TwoFactorLoginRequest struct {
+	Token        string `json:"two_factor_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TwoFactorCode struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type Password struct {
+	Password string `json:"password"`
+}
+
+func (s *Server) writePendingLogin(w http.ResponseWriter, login string) {
+	token, err := s.auth.PendingLoginToken(login)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not generate token: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PendingLogin{
+		TwoFactorRequired: true,
+		TwoFactorToken:    token,
+		ExpiresIn:         int(auth.PendingLoginTTL.Seconds()),
+	})
+}
+
+func (s *Server) loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	login, id, err := s.auth.PendingLogin(req.Token)
+	if errors.Is(err, auth.ErrMailTokenInvalid) || errors.Is(err, database.ErrMailTokenUsed) {
+		apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "Login expired, log in with password again")
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not check login token: %v", err)
+		return
+	}
+
+	now := time.Now()
+	if throttled(w, s.ipLimiter, ipKey(clientIP(r)), now) || throttled(w, s.loginLimiter, loginKey(login), now) {
+		return
+	}
+
+	_, err = s.auth.CompleteLogin(req.Token, req.Code, req.RecoveryCode)
+	if errors.Is(err, auth.ErrMailTokenInvalid) || errors.Is(err, database.ErrMailTokenUsed) {
+		apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "Login expired, log in with password again")
+		return
+	} else if errors.Is(err, auth.ErrTwoFactorCode) || errors.Is(err, database.ErrRecoveryCodeInvalid) ||
+		errors.Is(err, database.ErrTwoFactorNotEnrolled) {
+		s.failLogin(r, login, now)
+		s.recordLogin(r, login, "2fa", false)
+		if s.failPendingLogin(req.Token, id, now) {
+			apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "Too many wrong codes, log in with password again")
+			return
+		}
+		apierror.Write(w, http.StatusUnauthorized, apierror.Unauthenticated, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not check two-factor code: %v", err)
+		return
+	}
+	// The password alone does not reset the attempts of a login with two
+	// factors, or wrong codes would be forgiven by entering it again.
+	if err := s.loginLimiter.Reset(loginKey(login)); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not reset attempts: %v", err)
+		return
+	}
+
+	s.issueTokens(w, r, login, "2fa")
+}
+
+func (s *Server) enrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := s.auth.EnrollTOTP(login)
+	if errors.Is(err, database.ErrTwoFactorEnabled) {
+		apierror.Write(w, http.StatusConflict, apierror.FailedPrecondition, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not enroll two-factor authentication: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+func (s *Server) enableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCode
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	codes, err := s.auth.EnableTOTP(login, req.Code)
+	if errors.Is(err, auth.ErrTwoFactorCode) {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "%v", err)
+		return
+	} else if errors.Is(err, database.ErrTwoFactorEnabled) || errors.Is(err, database.ErrTwoFactorNotEnrolled) {
+		apierror.Write(w, http.StatusConflict, apierror.FailedPrecondition, "%v", err)
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not enable two-factor authentication: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodes{RecoveryCodes: codes})
+}
+
+func (s *Server) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r)
+	if !ok {
+		return
+	}
+
+	var req Password
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	now := time.Now()
+	if throttled(w, s.loginLimiter, loginKey(login), now) {
+		return
+	}
+
+	err := s.auth.DisableTOTP(login, req.Password)
+	if errors.Is(err, auth.ErrWrongPassword) {
+		s.failLogin(r, login, now)
+		apierror.Write(w, http.StatusForbidden, apierror.PermissionDenied, "Wrong password")
+		return
+	} else if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not disable two-factor authentication: %v", err)
+		return
+	}
+	if err := s.loginLimiter.Reset(loginKey(login)); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Can not reset attempts: %v", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,321 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	pb "userservice/proto"
+	statpb "userservice/proto/statistic"
+	"userservice/src/apierror"
+	"userservice/src/auth"
+	"userservice/src/database"
+	"userservice/src/middleware"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxViewNameLength = 100
+
+// ViewCount has no count when the query needs likes or views and
+// statistics service does not answer, or when the query is no longer
+// valid, e.g. a custom field it uses was deleted. Error tells the latter.
+type ViewCount struct {
+	database.View
+	Count *uint32 `json:"count,omitempty"`
+	Error string  `json:"error,omitempty"`
+}
+
+// viewStatus converts database errors of saved views to gRPC statuses, so
+// handlers can report them the same way as tasks manager errors.
+func viewStatus(err error) error {
+	switch err {
+	case database.ErrViewNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case database.ErrPermissionDenied:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case database.ErrViewExists:
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// hasTaskFilters reports whether the /tasks request selects tasks itself.
+// The default view is applied only when it does not.
+func hasTaskFilters(query url.Values) bool {
+	for _, param := range []string{"q", "view", "author", "project", "filter", "sort"} {
+		if query.Has(param) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinQueries combines a view query with extra terms from the request.
+// Terms are ANDed, so the request can only narrow the view.
+func joinQueries(view, extra string) string {
+	view = strings.TrimSpace(view)
+	extra = strings.TrimSpace(extra)
+	if view == "" || extra == "" {
+		return view + extra
+	}
+	return view + " " + extra
+}
+
+// taskQuery returns the query language string for /tasks taking the view
+// parameter and the default view of the user into account. byDefault tells
+// that the query is the one of the default view.
+func (s *Server) taskQuery(login string, query url.Values) (q string, byDefault bool, err error) {
+	if query.Has("view") {
+		id, err := strconv.Atoi(query.Get("view"))
+		if err != nil {
+			return "", false, status.Errorf(codes.InvalidArgument, "bad view id: %v", err)
+		}
+		view, err := s.db.GetView(uint(id), login)
+		if err != nil {
+			return "", false, viewStatus(err)
+		}
+		return joinQueries(view.Query, query.Get("q")), false, nil
+	}
+
+	if hasTaskFilters(query) {
+		return query.Get("q"), false, nil
+	}
+	view, err := s.db.GetDefaultView(login)
+	if err != nil {
+		return "", false, viewStatus(err)
+	}
+	if view == nil {
+		return "", false, nil
+	}
+	return view.Query, true, nil
+}
+
+// countViews counts tasks of each view. Stats are fetched at most once,
+// for the tasks the views that filter by likes or views may match; if
+// statistics service does not answer, the counts of those views are nil.
+// A view with an invalid query gets the error instead of a count, so one
+// broken view does not hide the others.
+func (s *Server) countViews(ctx context.Context, views []database.View) ([]ViewCount, error) {
+	queries := make([]string, 0, len(views))
+	for _, view := range views {
+		queries = append(queries, view.Query)
+	}
+	resp, err := s.taskMan.CountQueries(ctx, &pb.CountQueriesRequest{Queries: queries})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]ViewCount, len(views))
+	for i, view := range views {
+		counts[i].View = view
+	}
+	var pending []int
+	var taskIDs []uint32
+	seen := make(map[uint32]bool)
+	for i, result := range resp.Counts {
+		switch {
+		case result.Error != "":
+			counts[i].Error = result.Error
+		case result.NeedsCounts:
+			pending = append(pending, i)
+			for _, id := range result.TaskIds {
+				if !seen[id] {
+					seen[id] = true
+					taskIDs = append(taskIDs, id)
+				}
+			}
+		default:
+			counts[i].Count = &result.Count
+		}
+	}
+	if len(pending) == 0 {
+		return counts, nil
+	}
+
+	req := &pb.CountQueriesRequest{WithCounts: true}
+	for _, i := range pending {
+		req.Queries = append(req.Queries, queries[i])
+	}
+	if len(taskIDs) > 0 {
+		stats, err := s.statMan.GetStatsForTasks(ctx, &statpb.GetStatsForTasksRequest{TaskIds: taskIDs})
+		if err != nil {
+			slog.WarnContext(ctx, "Counting views without stats.",
+				slog.String("request_id", middleware.GetRequestID(ctx)),
+				slog.String("error", err.Error()))
+			return counts, nil
+		}
+		req.Likes = stats.Likes
+		req.Views = stats.Views
+	}
+	resp, err = s.taskMan.CountQueries(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for n, i := range pending {
+		counts[i].Count = &resp.Counts[n].Count
+	}
+	return counts, nil
+}
+
+// checkView validates a view before saving it. The query is checked by
+// counting matching tasks, so syntax errors are reported with positions.
+func (s *Server) checkView(ctx context.Context, view *database.View) error {
+	view.Name = strings.TrimSpace(view.Name)
+	if view.Name == "" {
+		return status.Error(codes.InvalidArgument, "name is required")
+	}
+	if len(view.Name) > maxViewNameLength {
+		return status.Errorf(codes.InvalidArgument, "name is longer than %d characters", maxViewNameLength)
+	}
+
+	for _, login := range view.SharedWith {
+		if login == view.Owner {
+			return status.Error(codes.InvalidArgument, "can not share a view with its owner")
+		}
+		ok, err := s.db.UserExist(login)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if !ok {
+			return status.Errorf(codes.InvalidArgument, "user %q not found", login)
+		}
+	}
+
+	// Likes and views do not matter for checking, so they are not fetched.
+	_, err := s.taskMan.CountTasks(ctx, &pb.GetTasksRequest{Query: view.Query})
+	return err
+}
+
+func (s *Server) createView(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	var view database.View
+	if err := json.NewDecoder(r.Body).Decode(&view); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	view.Owner = login
+	if err := s.checkView(r.Context(), &view); err != nil {
+		apierror.WriteGRPC(w, err, "Bad view")
+		return
+	}
+
+	id, err := s.db.CreateView(&view)
+	if err != nil {
+		apierror.WriteGRPC(w, viewStatus(err), "Can not create view")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(database.View{ID: id})
+}
+
+func (s *Server) getViews(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksRead)
+	if !ok {
+		return
+	}
+
+	views, err := s.db.GetViews(login)
+	if err != nil {
+		apierror.WriteGRPC(w, viewStatus(err), "Can not get views")
+		return
+	}
+
+	result, err := s.countViews(r.Context(), views)
+	if err != nil {
+		apierror.WriteGRPC(w, err, "Can not count tasks of views")
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(result)
+}
+
+func (s *Server) updateView(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	var view database.View
+	if err := json.NewDecoder(r.Body).Decode(&view); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	if view.ID == 0 {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "ID is required")
+		return
+	}
+
+	view.Owner = login
+	if err := s.checkView(r.Context(), &view); err != nil {
+		apierror.WriteGRPC(w, err, "Bad view")
+		return
+	}
+
+	if err := s.db.UpdateView(&view); err != nil {
+		apierror.WriteGRPC(w, viewStatus(err), "Can not update view")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteView(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse query: %v", err)
+		return
+	}
+
+	if err := s.db.DeleteView(uint(id), login); err != nil {
+		apierror.WriteGRPC(w, viewStatus(err), "Can not delete view")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) setDefaultView(w http.ResponseWriter, r *http.Request) {
+	login, ok := s.auth.CheckAuth(w, r, auth.ScopeTasksWrite)
+	if !ok {
+		return
+	}
+
+	var view database.View
+	if err := json.NewDecoder(r.Body).Decode(&view); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidArgument, "Can not parse body: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := s.db.SetDefaultView(login, view.ID); err != nil {
+		apierror.WriteGRPC(w, viewStatus(err), "Can not set default view")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,154 @@
+// Package throttle slows down guessing: every failed attempt for a key
+// (a login, an IP) makes the next attempt wait longer, and too many
+// failures lock the key for a while.
+package throttle
+
+import (
+	"log/slog"
+	"math"
+	"time"
+)
+
+// State of a key. Failures older than Config.Window are forgotten.
+type State struct {
+	Failures     int
+	LastFailure  time.Time
+	BlockedUntil time.Time
+}
+
+// Store keeps states. Update must apply fn atomically, so replicas sharing
+// a store do not lose failures.
+type Store interface {
+	Get(key string) (State, error)
+	Update(key string, fn func(*State)) (State, error)
+	Delete(key string) error
+	// Purge drops states with no failures since before.
+	Purge(before time.Time) error
+}
+
+type Config struct {
+	// FreeFailures are allowed without any delay.
+	FreeFailures int
+	// BaseDelay doubles with every further failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutFailures lock the key for LockoutDuration.
+	LockoutFailures int
+	LockoutDuration time.Duration
+	// Window must not be shorter than LockoutDuration and MaxDelay, so
+	// forgotten keys are never blocked.
+	Window time.Duration
+}
+
+var DefaultConfig = Config{
+	FreeFailures:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        5 * time.Minute,
+	LockoutFailures: 10,
+	LockoutDuration: 15 * time.Minute,
+	Window:          time.Hour,
+}
+
+type Limiter struct {
+	store  Store
+	config Config
+}
+
+func New(store Store, config Config) *Limiter {
+	return &Limiter{store: store, config: config}
+}
+
+// Check returns how long to wait before the next attempt for any of keys,
+// zero if it may go on now.
+func (l *Limiter) Check(now time.Time, keys ...string) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range keys {
+		state, err := l.store.Get(key)
+		if err != nil {
+			return 0, err
+		}
+		if d := state.BlockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+// Fail records a failed attempt for every key.
+func (l *Limiter) Fail(now time.Time, keys ...string) error {
+	for _, key := range keys {
+		_, err := l.store.Update(key, func(state *State) {
+			l.fail(state, now)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FailCount is Fail for one key that also returns its failures within
+// the window, for callers that give up after a number of them.
+func (l *Limiter) FailCount(now time.Time, key string) (int, error) {
+	state, err := l.store.Update(key, func(state *State) {
+		l.fail(state, now)
+	})
+	return state.Failures, err
+}
+
+func (l *Limiter) fail(state *State, now time.Time) {
+	if now.Sub(state.LastFailure) > l.config.Window {
+		state.Failures = 0
+	}
+	state.Failures++
+	state.LastFailure = now
+	if blockedUntil := now.Add(l.delay(state.Failures)); blockedUntil.After(state.BlockedUntil) {
+		state.BlockedUntil = blockedUntil
+	}
+}
+
+func (l *Limiter) delay(failures int) time.Duration {
+	if failures >= l.config.LockoutFailures {
+		return l.config.LockoutDuration
+	}
+	extra := failures - l.config.FreeFailures
+	if extra <= 0 {
+		return 0
+	}
+	delay := float64(l.config.BaseDelay) * math.Pow(2, float64(extra-1))
+	if delay > float64(l.config.MaxDelay) {
+		return l.config.MaxDelay
+	}
+	return time.Duration(delay)
+}
+
+// Locked reports whether key reached the lockout, as opposed to a backoff
+// delay.
+func (l *Limiter) Locked(key string, now time.Time) (bool, error) {
+	state, err := l.store.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return state.Failures >= l.config.LockoutFailures && state.BlockedUntil.After(now), nil
+}
+
+// Reset forgets the failures of key, after a success or an admin unlock.
+func (l *Limiter) Reset(key string) error {
+	return l.store.Delete(key)
+}
+
+// PurgeEvery drops forgotten states every interval until stop is closed.
+func (l *Limiter) PurgeEvery(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := l.store.Purge(now.Add(-l.config.Window)); err != nil {
+				slog.Error("Can not purge login attempts.", slog.String("error", err.Error()))
+			}
+		case <-stop:
+			return
+		}
+	}
+}
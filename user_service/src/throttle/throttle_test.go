@@ -0,0 +1,113 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+var testConfig = Config{
+	FreeFailures:    2,
+	BaseDelay:       time.Second,
+	MaxDelay:        4 * time.Second,
+	LockoutFailures: 6,
+	LockoutDuration: time.Minute,
+	Window:          time.Hour,
+}
+
+func TestBackoff(t *testing.T) {
+	l := New(NewMemoryStore(), testConfig)
+	now := time.Unix(1000, 0)
+
+	target := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, time.Minute}
+	for i, delay := range target {
+		if err := l.Fail(now, "login:kek"); err != nil {
+			t.Fatal(err)
+		}
+		wait, err := l.Check(now, "login:kek")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wait != delay {
+			t.Errorf("failure %d: expected %v, got %v", i+1, delay, wait)
+		}
+	}
+
+	if locked, _ := l.Locked("login:kek", now); !locked {
+		t.Error("expected lockout")
+	}
+	if locked, _ := l.Locked("login:kek", now.Add(time.Minute)); locked {
+		t.Error("expected lockout to end")
+	}
+
+	if err := l.Reset("login:kek"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := l.Check(now, "login:kek"); wait != 0 {
+		t.Errorf("expected no wait after reset, got %v", wait)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	l := New(NewMemoryStore(), testConfig)
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < 4; i++ {
+		l.Fail(now, "ip:1.2.3.4")
+	}
+	later := now.Add(2 * time.Hour)
+	l.Fail(later, "ip:1.2.3.4")
+	if wait, _ := l.Check(later, "ip:1.2.3.4"); wait != 0 {
+		t.Errorf("expected old failures to be forgotten, got %v", wait)
+	}
+}
+
+func TestCheckKeys(t *testing.T) {
+	l := New(NewMemoryStore(), testConfig)
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < 3; i++ {
+		l.Fail(now, "login:kek")
+	}
+	for i := 0; i < 4; i++ {
+		l.Fail(now, "ip:1.2.3.4")
+	}
+	if wait, _ := l.Check(now, "login:kek", "ip:1.2.3.4", "login:lol"); wait != 2*time.Second {
+		t.Errorf("expected the longest wait, got %v", wait)
+	}
+}
+
+func TestMemoryPurge(t *testing.T) {
+	store := NewMemoryStore()
+	l := New(store, testConfig)
+	now := time.Unix(1000, 0)
+
+	l.Fail(now, "old")
+	l.Fail(now.Add(time.Hour), "new")
+	if err := store.Purge(now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := store.Get("old"); state.Failures != 0 {
+		t.Error("expected old state to be purged")
+	}
+	if state, _ := store.Get("new"); state.Failures != 1 {
+		t.Error("expected new state to stay")
+	}
+}
+
+func TestFailCount(t *testing.T) {
+	l := New(NewMemoryStore(), testConfig)
+	now := time.Unix(1000, 0)
+
+	for i := 1; i <= 3; i++ {
+		failures, err := l.FailCount(now, "pending:abc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if failures != i {
+			t.Errorf("expected %d failures, got %d", i, failures)
+		}
+	}
+	if failures, _ := l.FailCount(now.Add(2*time.Hour), "pending:abc"); failures != 1 {
+		t.Errorf("expected old failures to be forgotten, got %d", failures)
+	}
+}
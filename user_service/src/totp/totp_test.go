@@ -0,0 +1,56 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B vectors for SHA1, truncated to 6 digits.
+func TestCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if code := Code(secret, Step(time.Unix(tt.unix, 0))); code != tt.code {
+			t.Errorf("time %d: expected %s, got %s", tt.unix, tt.code, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111109, 0)
+
+	if step, ok := Validate(secret, "081804", now); !ok || step != Step(now) {
+		t.Errorf("expected current code to match step %d, got %d, %v", Step(now), step, ok)
+	}
+	if _, ok := Validate(secret, "081804", now.Add(Period*time.Second)); !ok {
+		t.Error("expected previous code to match")
+	}
+	if _, ok := Validate(secret, "081804", now.Add(3*Period*time.Second)); ok {
+		t.Error("expected old code to be rejected")
+	}
+	if _, ok := Validate(secret, "81804", now); ok {
+		t.Error("expected short code to be rejected")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI([]byte("12345678901234567890"), "task-tracker", "kek")
+	if !strings.HasPrefix(uri, "otpauth://totp/task-tracker:kek?") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+	if !strings.Contains(uri, "secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ") {
+		t.Errorf("expected base32 secret in %s", uri)
+	}
+}